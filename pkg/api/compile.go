@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/generator/exec"
+	"kusionstack.io/kusion/pkg/generator/kcl"
+	"kusionstack.io/kusion/pkg/generator/kustomize"
+	"kusionstack.io/kusion/pkg/generator/yaml"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/resources/helm"
+)
+
+// Compile generates the Spec of the stack with the generator configured in project.yaml.
+// The KCL generator is used if no generator is configured.
+func Compile(ctx context.Context, project *projectstack.Project, stack *projectstack.Stack, o *generator.Options) (*models.Spec, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	g, err := newGenerator(project)
+	if err != nil {
+		return nil, err
+	}
+	if o.WorkDir == "" {
+		o.WorkDir = stack.GetPath()
+	}
+	sp, err := g.GenerateSpec(o, stack)
+	if err != nil {
+		return nil, err
+	}
+	// Helm charts are rendered into Kubernetes resources, which go through preview, diff and state as usual
+	if err = helm.Render(sp, stack.GetPath(), namespacedFunc(stack)); err != nil {
+		return nil, err
+	}
+	// IDs of resources deployed to other clusters carry their clusters
+	if err = engine.QualifyClusterIDs(sp); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// namespacedFunc resolves scopes of kinds by RESTMappers of clusters of the stack. It is only called for kinds which
+// are neither built-in nor defined by CRDs in charts, and clients of clusters are created on demand, so that charts
+// of built-in kinds and their own CRDs are rendered offline
+func namespacedFunc(stack *projectstack.Stack) helm.NamespacedFunc {
+	runtimes := map[string]*kubernetes.KubernetesRuntime{}
+	return func(cluster string, gvk schema.GroupVersionKind) (bool, error) {
+		rt, ok := runtimes[cluster]
+		if !ok {
+			var err error
+			if rt, err = kubernetes.NewClusterRuntime(stack, cluster); err != nil {
+				return false, err
+			}
+			runtimes[cluster] = rt
+		}
+		return rt.Namespaced(gvk)
+	}
+}
+
+func newGenerator(project *projectstack.Project) (generator.Generator, error) {
+	pg := project.Generator
+
+	// default Generator
+	if pg == nil {
+		return &kcl.Generator{}, nil
+	}
+
+	// we can add more generators here
+	switch pg.Type {
+	case projectstack.KCLGenerator:
+		return &kcl.Generator{}, nil
+	case projectstack.KustomizeGenerator:
+		return &kustomize.Generator{}, nil
+	case projectstack.YAMLGenerator:
+		return yaml.NewGenerator(pg.Configs)
+	case projectstack.ExecGenerator:
+		return exec.NewGenerator(pg.Configs)
+	default:
+		return nil, fmt.Errorf("unknow generator type:%s", pg.Type)
+	}
+}
@@ -0,0 +1,25 @@
+// Package api is the stable Go SDK of Kusion operations. It provides Compile, Preview, Apply, Destroy, Watch and Wait
+// functions which take all dependencies, such as the state storage, runtimes and the logger, explicitly, and have
+// nothing to do with terminal interactions. Both the Kusion CLI and the Kusion server are built on this package.
+//
+// A typical workflow looks like:
+//
+//	project, stack, err := projectstack.DetectProjectAndStack(workDir)
+//	if err != nil {
+//	    return err
+//	}
+//	sp, err := api.Compile(ctx, project, stack, &generator.Options{WorkDir: workDir})
+//	if err != nil {
+//	    return err
+//	}
+//	storage, err := backend.BackendFromConfig(project.Backend, backend.BackendOps{}, workDir)
+//	if err != nil {
+//	    return err
+//	}
+//	deps := api.Dependencies{StateStorage: storage}
+//	changes, err := api.Preview(ctx, project, stack, sp, &api.PreviewOptions{Dependencies: deps})
+//	if err != nil {
+//	    return err
+//	}
+//	state, err := api.Apply(ctx, sp, changes, &api.ApplyOptions{Dependencies: deps})
+package api
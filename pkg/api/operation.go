@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var ErrNoStateStorage = errors.New("no state storage is provided")
+
+// Preview computes the changes of the Spec against the latest state and live resources.
+// Cancelling ctx only takes effect before the preview starts.
+func Preview(
+	ctx context.Context,
+	project *projectstack.Project,
+	stack *projectstack.Stack,
+	sp *models.Spec,
+	o *PreviewOptions,
+) (*opsmodels.Changes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if o.StateStorage == nil {
+		return nil, ErrNoStateStorage
+	}
+	opType := o.OperationType
+	if opType == opsmodels.UndefinedOperation {
+		opType = opsmodels.ApplyPreview
+	}
+	// Validate secret stores
+	if opType == opsmodels.ApplyPreview && !project.SecretStores.IsValid() {
+		return nil, fmt.Errorf("no secret store is provided")
+	}
+
+	o.logger().Info("Start compute preview changes ...")
+	pc := &operation.PreviewOperation{
+		Operation: opsmodels.Operation{
+			OperationType:  opType,
+			Stack:          stack,
+			StateStorage:   o.StateStorage,
+			IgnoreFields:   o.IgnoreFields,
+			ChangeOrder:    &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			RuntimeMap:     o.Runtimes,
+			SecretStores:   project.SecretStores,
+			ForceConflicts: o.ForceConflicts,
+		},
+	}
+	rsp, s := pc.Preview(&operation.PreviewRequest{
+		Request: opsmodels.Request{
+			Tenant:   project.Tenant,
+			Project:  project,
+			Stack:    stack,
+			Operator: o.Operator,
+			Spec:     sp,
+			Cluster:  o.Cluster,
+		},
+	})
+	if status.IsErr(s) {
+		return nil, fmt.Errorf("preview failed.\n%s", s.String())
+	}
+
+	return opsmodels.NewChanges(project, stack, rsp.Order), nil
+}
+
+// Apply applies the Spec with the changes computed by Preview, and saves the result State in the state storage.
+// Cancelling ctx only takes effect before the apply starts. No State is returned in the dry run mode.
+func Apply(ctx context.Context, sp *models.Spec, changes *opsmodels.Changes, o *ApplyOptions) (*states.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if o.StateStorage == nil {
+		return nil, ErrNoStateStorage
+	}
+	// Validate secret stores
+	project := changes.Project()
+	if !project.SecretStores.IsValid() {
+		return nil, fmt.Errorf("no secret store is provided")
+	}
+
+	if o.DryRun {
+		for _, r := range sp.Resources {
+			report(o.Progress, opsmodels.Message{ResourceID: r.ResourceKey(), OpResult: opsmodels.Success})
+		}
+		return nil, nil
+	}
+
+	o.logger().Infof("Start applying %d resources ...", len(sp.Resources))
+	ac := &operation.ApplyOperation{
+		Operation: opsmodels.Operation{
+			Stack:          changes.Stack(),
+			StateStorage:   o.StateStorage,
+			MsgCh:          make(chan opsmodels.Message),
+			RuntimeMap:     o.Runtimes,
+			SecretStores:   project.SecretStores,
+			ForceConflicts: o.ForceConflicts,
+		},
+	}
+	done := drain(ac.MsgCh, o.Progress)
+	rsp, s := ac.Apply(&operation.ApplyRequest{
+		Request: opsmodels.Request{
+			Tenant:   project.Tenant,
+			Project:  project,
+			Stack:    changes.Stack(),
+			Cluster:  o.Cluster,
+			Operator: o.Operator,
+			Spec:     sp,
+		},
+	})
+	<-done
+	if status.IsErr(s) {
+		return nil, fmt.Errorf("apply failed, status:\n%v", s)
+	}
+	return rsp.State, nil
+}
+
+// Destroy deletes all resources in the Spec with the changes computed by Preview with opsmodels.DestroyPreview.
+// Cancelling ctx only takes effect before the destroy starts.
+func Destroy(ctx context.Context, sp *models.Spec, changes *opsmodels.Changes, o *DestroyOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if o.StateStorage == nil {
+		return ErrNoStateStorage
+	}
+	o.logger().Infof("Start destroying %d resources ...", len(sp.Resources))
+	do := &operation.DestroyOperation{
+		Operation: opsmodels.Operation{
+			Stack:        changes.Stack(),
+			StateStorage: o.StateStorage,
+			MsgCh:        make(chan opsmodels.Message),
+			RuntimeMap:   o.Runtimes,
+			SecretStores: changes.Project().SecretStores,
+		},
+	}
+	done := drain(do.MsgCh, o.Progress)
+	s := do.Destroy(&operation.DestroyRequest{
+		Request: opsmodels.Request{
+			Tenant:   changes.Project().Tenant,
+			Project:  changes.Project(),
+			Operator: o.Operator,
+			Stack:    changes.Stack(),
+			Spec:     sp,
+		},
+	})
+	<-done
+	if status.IsErr(s) {
+		return fmt.Errorf("destroy failed, status: %v", s)
+	}
+	return nil
+}
+
+// Watch watches resources in the Spec until all of them are ready or ctx is done. Unchanged resources are skipped
+// if changes is not nil, and resources whose runtime doesn't support watching are always skipped.
+func Watch(ctx context.Context, sp *models.Spec, changes *opsmodels.Changes, o *WatchOptions) error {
+	// runtimes initialized by the operation are closed once watching ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wo := &operation.WatchOperation{Operation: opsmodels.Operation{RuntimeMap: o.Runtimes}}
+	req := &operation.WatchRequest{Request: opsmodels.Request{Spec: &models.Spec{Resources: changedResources(sp, changes)}}}
+	if changes != nil {
+		req.Project = changes.Project()
+		req.Stack = changes.Stack()
+	}
+	watchers, err := wo.StartWatchers(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	o.logger().Infof("Start watching %d resources ...", len(watchers))
+	if o.OnStart != nil {
+		objects := make(map[string][]string, len(watchers))
+		for id, sw := range watchers {
+			objects[id] = sw.IDs
+		}
+		o.OnStart(objects)
+	}
+	return wo.ReceiveEvents(ctx, watchers, func(e *operation.WatchEvent) {
+		if o.OnEvent != nil {
+			o.OnEvent(e)
+		}
+	})
+}
+
+// Wait polls resources in the Spec until all of them are ready, any of them fails, the timeout passes or ctx is
+// done. Unchanged resources are skipped if changes is not nil. The latest health of all waited resources is
+// returned, and the error is not nil unless all of them are ready.
+func Wait(ctx context.Context, sp *models.Spec, changes *opsmodels.Changes, o *WaitOptions) ([]*operation.WaitResult, error) {
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	toBeWaited := changedResources(sp, changes)
+	o.logger().Infof("Start waiting for %d resources to be ready ...", len(toBeWaited))
+	wo := &operation.WaitOperation{
+		Operation: opsmodels.Operation{RuntimeMap: o.Runtimes},
+		Interval:  o.Interval,
+	}
+	req := &operation.WaitRequest{Request: opsmodels.Request{Spec: &models.Spec{Resources: toBeWaited}}}
+	if changes != nil {
+		req.Project = changes.Project()
+		req.Stack = changes.Stack()
+	}
+	return wo.Wait(ctx, req)
+}
+
+// changedResources filters out unchanged resources in sp if changes is not nil
+func changedResources(sp *models.Spec, changes *opsmodels.Changes) models.Resources {
+	resources := models.Resources{}
+	for _, res := range sp.Resources {
+		if changes != nil {
+			if step := changes.Get(res.ResourceKey()); step != nil && step.Action == opsmodels.UnChange {
+				continue
+			}
+		}
+		resources = append(resources, res)
+	}
+	return resources
+}
+
+// drain receives all messages in msgCh until it is closed, and reports them with the progress func
+func drain(msgCh <-chan opsmodels.Message, progress ProgressFunc) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range msgCh {
+			report(progress, msg)
+		}
+	}()
+	return done
+}
+
+func report(progress ProgressFunc, msg opsmodels.Message) {
+	if progress != nil {
+		progress(msg)
+	}
+}
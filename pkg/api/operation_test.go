@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/health"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/fake"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+var (
+	project = &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "fake-project"}}
+	stack   = &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "fake-stack"}}
+	sa      = models.Resource{
+		ID:   "v1:ServiceAccount:foo:bar",
+		Type: runtime.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   map[string]interface{}{"namespace": "foo", "name": "bar"},
+		},
+	}
+)
+
+func newDependencies(t *testing.T) (Dependencies, *fake.Runtime) {
+	rt := fake.NewRuntime()
+	return Dependencies{
+		StateStorage: &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)},
+		Runtimes:     map[models.Type]runtime.Runtime{runtime.Kubernetes: rt},
+	}, rt
+}
+
+func TestApplyAndDestroy(t *testing.T) {
+	ctx := context.Background()
+	deps, rt := newDependencies(t)
+	sp := &models.Spec{Resources: models.Resources{sa}}
+
+	changes, err := Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	assert.Equal(t, opsmodels.Create, changes.Get(sa.ID).Action)
+
+	var msgs []opsmodels.Message
+	state, err := Apply(ctx, sp, changes, &ApplyOptions{
+		Dependencies: deps,
+		Operator:     "foo",
+		Progress:     func(msg opsmodels.Message) { msgs = append(msgs, msg) },
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", state.Operator)
+	assert.NotNil(t, rt.Get(sa.ID))
+	assert.Equal(t, opsmodels.Success, msgs[len(msgs)-1].OpResult)
+
+	latest, err := deps.StateStorage.GetLatestState(&states.StateQuery{Project: project.Name, Stack: stack.Name})
+	assert.Nil(t, err)
+	assert.Equal(t, sa.ID, latest.Resources[0].ID)
+
+	changes, err = Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	assert.True(t, changes.AllUnChange())
+
+	changes, err = Preview(ctx, project, stack, sp, &PreviewOptions{
+		Dependencies:  deps,
+		OperationType: opsmodels.DestroyPreview,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, opsmodels.Delete, changes.Get(sa.ID).Action)
+
+	err = Destroy(ctx, sp, changes, &DestroyOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	assert.Nil(t, rt.Get(sa.ID))
+}
+
+func TestApplyDryRun(t *testing.T) {
+	ctx := context.Background()
+	deps, rt := newDependencies(t)
+	sp := &models.Spec{Resources: models.Resources{sa}}
+
+	changes, err := Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps})
+	assert.Nil(t, err)
+
+	var msgs []opsmodels.Message
+	state, err := Apply(ctx, sp, changes, &ApplyOptions{
+		Dependencies: deps,
+		DryRun:       true,
+		Progress:     func(msg opsmodels.Message) { msgs = append(msgs, msg) },
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, state)
+	assert.Empty(t, rt.Resources())
+	assert.Equal(t, []opsmodels.Message{{ResourceID: sa.ID, OpResult: opsmodels.Success}}, msgs)
+}
+
+func TestMissingDependencies(t *testing.T) {
+	ctx := context.Background()
+	sp := &models.Spec{Resources: models.Resources{sa}}
+
+	_, err := Preview(ctx, project, stack, sp, &PreviewOptions{})
+	assert.ErrorIs(t, err, ErrNoStateStorage)
+
+	changes := opsmodels.NewChanges(project, stack, &opsmodels.ChangeOrder{})
+	_, err = Apply(ctx, sp, changes, &ApplyOptions{})
+	assert.ErrorIs(t, err, ErrNoStateStorage)
+	assert.ErrorIs(t, Destroy(ctx, sp, changes, &DestroyOptions{}), ErrNoStateStorage)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = Preview(cancelled, project, stack, sp, &PreviewOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWatch(t *testing.T) {
+	deps, _ := newDependencies(t)
+	sp := &models.Spec{Resources: models.Resources{sa}}
+
+	var objects map[string][]string
+	var events []*operation.WatchEvent
+	err := Watch(context.Background(), sp, nil, &WatchOptions{
+		Dependencies: deps,
+		OnStart:      func(o map[string][]string) { objects = o },
+		OnEvent:      func(e *operation.WatchEvent) { events = append(events, e) },
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{sa.ID: {sa.ID}}, objects)
+	assert.Len(t, events, 1)
+	assert.Equal(t, sa.ID, events[0].ResourceID)
+	assert.Equal(t, "READY", string(events[0].Row.Type))
+}
+
+func TestWait(t *testing.T) {
+	ctx := context.Background()
+	deps, _ := newDependencies(t)
+	sp := &models.Spec{Resources: models.Resources{sa}}
+
+	results, err := Wait(ctx, sp, nil, &WaitOptions{Dependencies: deps, Timeout: 10 * time.Millisecond, Interval: time.Millisecond})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, health.NotFound, results[0].Status)
+
+	changes, err := Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	_, err = Apply(ctx, sp, changes, &ApplyOptions{Dependencies: deps})
+	assert.Nil(t, err)
+
+	results, err = Wait(ctx, sp, changes, &WaitOptions{Dependencies: deps, Timeout: time.Second, Interval: time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, health.Current, results[0].Status)
+}
+
+func TestApplyLocalResources(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "fake-stack"}, Path: dir}
+	deps := Dependencies{StateStorage: &local.FileSystemState{Path: filepath.Join(dir, local.KusionState)}}
+	sp := &models.Spec{Resources: models.Resources{
+		{
+			ID:         "local:file:greeting",
+			Type:       runtime.Local,
+			Attributes: map[string]interface{}{"kind": "File", "path": "greeting.txt", "content": "hello"},
+		},
+		{
+			ID:   "local:command:echo",
+			Type: runtime.Local,
+			Attributes: map[string]interface{}{
+				"kind":        "Command",
+				"create":      `echo "$GREETING world"`,
+				"environment": map[string]interface{}{"GREETING": "$kusion_path.local:file:greeting.content"},
+			},
+		},
+		{
+			ID:   "local:file:echo",
+			Type: runtime.Local,
+			Attributes: map[string]interface{}{
+				"kind":    "File",
+				"path":    "echo.txt",
+				"content": "$kusion_path.local:command:echo.output",
+			},
+		},
+	}}
+
+	changes, err := Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	_, err = Apply(ctx, sp, changes, &ApplyOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	content, err := os.ReadFile(filepath.Join(dir, "echo.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", string(content))
+
+	changes, err = Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps})
+	assert.Nil(t, err)
+	assert.True(t, changes.AllUnChange())
+
+	changes, err = Preview(ctx, project, stack, sp, &PreviewOptions{Dependencies: deps, OperationType: opsmodels.DestroyPreview})
+	assert.Nil(t, err)
+	assert.Nil(t, Destroy(ctx, sp, changes, &DestroyOptions{Dependencies: deps}))
+	assert.NoFileExists(t, filepath.Join(dir, "greeting.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "echo.txt"))
+}
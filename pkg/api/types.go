@@ -0,0 +1,110 @@
+package api
+
+import (
+	"time"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/log"
+)
+
+// Dependencies are the explicit dependencies of operations
+type Dependencies struct {
+	// StateStorage is where states are read from and saved to. It is required by Preview, Apply and Destroy
+	StateStorage states.StateStorage
+
+	// Runtimes are used to operate resources, and the key is the resource type.
+	// Runtimes are initialized by resource types with runtimeinit.Runtimes for each operation if it is nil, and
+	// closed when the operation ends
+	Runtimes map[models.Type]runtime.Runtime
+
+	// Logger logs the progress of operations. The default logger of pkg/log is used if it is nil
+	Logger log.Logger
+}
+
+func (d *Dependencies) logger() log.Logger {
+	if d.Logger == nil {
+		return log.GetLogger()
+	}
+	return d.Logger
+}
+
+// ProgressFunc is called with the result of each resource during Apply and Destroy. Calls are serialized
+type ProgressFunc func(msg opsmodels.Message)
+
+// PreviewOptions are options of Preview
+type PreviewOptions struct {
+	Dependencies
+
+	// OperationType is opsmodels.ApplyPreview or opsmodels.DestroyPreview. Default is opsmodels.ApplyPreview
+	OperationType opsmodels.OperationType
+
+	// Operator is the person or system who triggers this operation
+	Operator string
+
+	// Cluster is the target cluster of this operation
+	Cluster string
+
+	// IgnoreFields are fields ignored when computing diffs
+	IgnoreFields []string
+
+	// ForceConflicts takes over fields managed by others in the server-side apply dry run
+	ForceConflicts bool
+}
+
+// ApplyOptions are options of Apply
+type ApplyOptions struct {
+	Dependencies
+
+	// Operator is the person or system who triggers this operation
+	Operator string
+
+	// Cluster is the target cluster of this operation
+	Cluster string
+
+	// DryRun reports all resources as succeeded without applying them
+	DryRun bool
+
+	// ForceConflicts takes over fields managed by others in the server-side apply
+	ForceConflicts bool
+
+	// Progress is called with the result of each resource if not nil
+	Progress ProgressFunc
+}
+
+// DestroyOptions are options of Destroy
+type DestroyOptions struct {
+	Dependencies
+
+	// Operator is the person or system who triggers this operation
+	Operator string
+
+	// Progress is called with the result of each resource if not nil
+	Progress ProgressFunc
+}
+
+// WatchOptions are options of Watch
+type WatchOptions struct {
+	Dependencies
+
+	// OnStart is called once all watchers are started if not nil. It receives IDs of watched objects keyed by the
+	// resource keys, and resources whose runtime doesn't support watching are absent
+	OnStart func(objects map[string][]string)
+
+	// OnEvent is called with each event of watched objects if not nil. Calls are serialized
+	OnEvent func(e *operation.WatchEvent)
+}
+
+// WaitOptions are options of Wait
+type WaitOptions struct {
+	Dependencies
+
+	// Timeout is the max duration to wait. No timeout except the context if it is zero
+	Timeout time.Duration
+
+	// Interval is the polling interval. operation.DefaultWaitInterval is used if it is zero
+	Interval time.Duration
+}
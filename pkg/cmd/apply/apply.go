@@ -30,7 +30,13 @@ var (
 		kusion apply -Y settings.yaml
 
 		# Skip interactive approval of plan details before applying
-		kusion apply --yes`
+		kusion apply --yes
+
+		# Wait for all changed resources to be ready, and fail if they are not ready in 10 minutes
+		kusion apply --yes --wait --timeout 10m
+
+		# Apply and take over fields managed by others in server-side apply
+		kusion apply --force-conflicts`
 )
 
 func NewCmdApply() *cobra.Command {
@@ -60,6 +66,10 @@ func NewCmdApply() *cobra.Command {
 		i18n.T("dry-run to preview the execution effect (always successful) without actually applying the changes"))
 	cmd.Flags().BoolVarP(&o.Watch, "watch", "", false,
 		i18n.T("After creating/updating/deleting the requested object, watch for changes."))
+	cmd.Flags().BoolVarP(&o.Wait, "wait", "", false,
+		i18n.T("After applying, wait for all changed resources to be ready and exit with a non-zero code if any of them is not ready"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", DefaultWaitTimeout,
+		i18n.T("The max duration to wait for resources to be ready, only works with --wait"))
 
 	return cmd
 }
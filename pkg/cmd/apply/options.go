@@ -1,28 +1,34 @@
 package apply
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/howieyuen/uilive"
 	"github.com/pterm/pterm"
 
+	"kusionstack.io/kusion/pkg/api"
 	previewcmd "kusionstack.io/kusion/pkg/cmd/preview"
 	"kusionstack.io/kusion/pkg/cmd/spec"
 	"kusionstack.io/kusion/pkg/cmd/util"
 	"kusionstack.io/kusion/pkg/engine/backend"
 	_ "kusionstack.io/kusion/pkg/engine/backend/init"
+	"kusionstack.io/kusion/pkg/engine/health"
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/notification"
 	"kusionstack.io/kusion/pkg/engine/operation"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/printers"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/generator"
-	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
-	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
 
@@ -32,10 +38,15 @@ type ApplyOptions struct {
 	ApplyFlag
 }
 
+// DefaultWaitTimeout is the default timeout of waiting for resources to be ready
+const DefaultWaitTimeout = 5 * time.Minute
+
 type ApplyFlag struct {
-	Yes    bool
-	DryRun bool
-	Watch  bool
+	Yes     bool
+	DryRun  bool
+	Watch   bool
+	Wait    bool
+	Timeout time.Duration
 }
 
 // NewApplyOptions returns a new ApplyOptions instance
@@ -50,6 +61,9 @@ func (o *ApplyOptions) Complete(args []string) {
 }
 
 func (o *ApplyOptions) Validate() error {
+	if o.Wait && o.Timeout <= 0 {
+		return errors.New("invalid timeout, must be positive")
+	}
 	return o.CompileOptions.Validate()
 }
 
@@ -138,16 +152,34 @@ func (o *ApplyOptions) Run() error {
 	}
 
 	fmt.Println("Start applying diffs ...")
-	if err := Apply(o, stateStorage, sp, changes, os.Stdout); err != nil {
-		return err
-	}
-
-	// If dry run, print the hint
+	notifier := notification.NewNotifier(project.Notification)
+	defer notifier.Flush(notification.DefaultFlushTimeout)
 	if o.DryRun {
+		if err := Apply(o, stateStorage, sp, changes, os.Stdout); err != nil {
+			return err
+		}
+		// If dry run, print the hint
 		fmt.Printf("\nNOTE: Currently running in the --dry-run mode, the above configuration does not really take effect\n")
 		return nil
 	}
 
+	notifier.Send(util.NewNotificationEvent(notification.ApplyStarted, changes, o.Operator, nil))
+	// the result is notified after watching and waiting, so resources which fail to be ready fail the apply
+	err = applyAndWait(o, stateStorage, sp, changes)
+	if err != nil {
+		notifier.Send(util.NewNotificationEvent(notification.ApplyFailed, changes, o.Operator, err))
+		return err
+	}
+	notifier.Send(util.NewNotificationEvent(notification.ApplySucceeded, changes, o.Operator, nil))
+	return nil
+}
+
+// applyAndWait applies changes, and then watches and waits for resources if required
+func applyAndWait(o *ApplyOptions, stateStorage states.StateStorage, sp *models.Spec, changes *opsmodels.Changes) error {
+	if err := Apply(o, stateStorage, sp, changes, os.Stdout); err != nil {
+		return err
+	}
+
 	if o.Watch {
 		fmt.Println("\nStart watching changes ...")
 		if err := Watch(o, sp, changes); err != nil {
@@ -155,28 +187,28 @@ func (o *ApplyOptions) Run() error {
 		}
 	}
 
+	if o.Wait {
+		fmt.Printf("\nWaiting for resources to be ready, timeout: %s ...\n", o.Timeout)
+		if err := Wait(o, sp, changes, os.Stdout); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // The Apply function will apply the resources changes
 // through the execution Kusion Engine, and will save
-// the state to specified storage.
-//
-// You can customize the runtime of engine and the state
-// storage through `runtime` and `storage` parameters.
+// the state to specified storage. It is a thin wrapper
+// of api.Apply which prints the progress to out.
 //
 // Example:
 //
 //	o := NewApplyOptions()
-//	stateStorage := &states.FileSystemState{
-//	    Path: filepath.Join(o.WorkDir, states.KusionState)
-//	}
-//	kubernetesRuntime, err := runtime.NewKubernetesRuntime()
-//	if err != nil {
-//	    return err
+//	stateStorage := &local.FileSystemState{
+//	    Path: filepath.Join(o.WorkDir, local.KusionState)
 //	}
 //
-//	err = Apply(o, kubernetesRuntime, stateStorage, planResources, changes, os.Stdout)
+//	err = Apply(o, stateStorage, planResources, changes, os.Stdout)
 //	if err != nil {
 //	    return err
 //	}
@@ -187,22 +219,6 @@ func Apply(
 	changes *opsmodels.Changes,
 	out io.Writer,
 ) error {
-	// Validate secret stores
-	project := changes.Project()
-	if !project.SecretStores.IsValid() {
-		return fmt.Errorf("no secret store is provided")
-	}
-
-	// Construct the apply operation
-	ac := &operation.ApplyOperation{
-		Operation: opsmodels.Operation{
-			Stack:        changes.Stack(),
-			StateStorage: storage,
-			MsgCh:        make(chan opsmodels.Message),
-			SecretStores: project.SecretStores,
-		},
-	}
-
 	// Line summary
 	var ls lineSummary
 
@@ -215,94 +231,64 @@ func Apply(
 	if err != nil {
 		return err
 	}
-	// Wait msgCh close
-	var wg sync.WaitGroup
-	// Receive msg and print detail
-	go func() {
-		defer func() {
-			if p := recover(); p != nil {
-				log.Errorf("failed to receive msg and print detail as %v", p)
-			}
-		}()
-		wg.Add(1)
 
-		for {
-			select {
-			case msg, ok := <-ac.MsgCh:
-				if !ok {
-					wg.Done()
-					return
-				}
-				changeStep := changes.Get(msg.ResourceID)
-
-				switch msg.OpResult {
-				case opsmodels.Success, opsmodels.Skip:
-					var title string
-					if changeStep.Action == opsmodels.UnChange {
-						title = fmt.Sprintf("%s %s, %s",
-							changeStep.Action.String(),
-							pterm.Bold.Sprint(changeStep.ID),
-							strings.ToLower(string(opsmodels.Skip)),
-						)
-					} else {
-						title = fmt.Sprintf("%s %s %s",
-							changeStep.Action.String(),
-							pterm.Bold.Sprint(changeStep.ID),
-							strings.ToLower(string(msg.OpResult)),
-						)
-					}
-					pterm.Success.WithWriter(out).Println(title)
-					progressbar.UpdateTitle(title)
-					progressbar.Increment()
-					ls.Count(changeStep.Action)
-				case opsmodels.Failed:
-					title := fmt.Sprintf("%s %s %s",
-						changeStep.Action.String(),
-						pterm.Bold.Sprint(changeStep.ID),
-						strings.ToLower(string(msg.OpResult)),
-					)
-					pterm.Error.WithWriter(out).Printf("%s\n", title)
-				default:
-					title := fmt.Sprintf("%s %s %s",
-						changeStep.Action.Ing(),
-						pterm.Bold.Sprint(changeStep.ID),
-						strings.ToLower(string(msg.OpResult)),
-					)
-					progressbar.UpdateTitle(title)
-				}
-			}
+	// Receive msg and print detail
+	progress := func(msg opsmodels.Message) {
+		changeStep := changes.Get(msg.ResourceID)
+		if changeStep == nil {
+			return
 		}
-	}()
 
-	if o.DryRun {
-		for _, r := range planResources.Resources {
-			ac.MsgCh <- opsmodels.Message{
-				ResourceID: r.ResourceKey(),
-				OpResult:   opsmodels.Success,
-				OpErr:      nil,
+		switch msg.OpResult {
+		case opsmodels.Success, opsmodels.Skip:
+			var title string
+			if changeStep.Action == opsmodels.UnChange {
+				title = fmt.Sprintf("%s %s, %s",
+					changeStep.Action.String(),
+					pterm.Bold.Sprint(changeStep.ID),
+					strings.ToLower(string(opsmodels.Skip)),
+				)
+			} else {
+				title = fmt.Sprintf("%s %s %s",
+					changeStep.Action.String(),
+					pterm.Bold.Sprint(changeStep.ID),
+					strings.ToLower(string(msg.OpResult)),
+				)
 			}
+			pterm.Success.WithWriter(out).Println(title)
+			progressbar.UpdateTitle(title)
+			progressbar.Increment()
+			ls.Count(changeStep.Action)
+		case opsmodels.Failed:
+			title := fmt.Sprintf("%s %s %s",
+				changeStep.Action.String(),
+				pterm.Bold.Sprint(changeStep.ID),
+				strings.ToLower(string(msg.OpResult)),
+			)
+			pterm.Error.WithWriter(out).Printf("%s\n", title)
+		default:
+			title := fmt.Sprintf("%s %s %s",
+				changeStep.Action.Ing(),
+				pterm.Bold.Sprint(changeStep.ID),
+				strings.ToLower(string(msg.OpResult)),
+			)
+			progressbar.UpdateTitle(title)
 		}
-		close(ac.MsgCh)
-	} else {
+	}
+
+	_, err = api.Apply(context.Background(), planResources, changes, &api.ApplyOptions{
+		Dependencies: api.Dependencies{StateStorage: storage},
+		Operator:     o.Operator,
 		// parse cluster in arguments
-		cluster := util.ParseClusterArgument(o.Arguments)
-		_, st := ac.Apply(&operation.ApplyRequest{
-			Request: opsmodels.Request{
-				Tenant:   changes.Project().Tenant,
-				Project:  changes.Project(),
-				Stack:    changes.Stack(),
-				Cluster:  cluster,
-				Operator: o.Operator,
-				Spec:     planResources,
-			},
-		})
-		if status.IsErr(st) {
-			return fmt.Errorf("apply failed, status:\n%v", st)
-		}
+		Cluster:        util.ParseClusterArgument(o.Arguments),
+		DryRun:         o.DryRun,
+		ForceConflicts: o.ForceConflicts,
+		Progress:       progress,
+	})
+	if err != nil {
+		return err
 	}
 
-	// Wait for msgCh closed
-	wg.Wait()
 	// Print summary
 	pterm.Fprintln(out, fmt.Sprintf("Apply complete! Resources: %d created, %d updated, %d deleted.", ls.created, ls.updated, ls.deleted))
 	return nil
@@ -333,28 +319,108 @@ func Watch(
 		return nil
 	}
 
-	// Filter out unchanged resources
-	toBeWatched := models.Resources{}
+	if err := watch(planResources, changes); err != nil {
+		return err
+	}
+
+	fmt.Println("Watch Finish! All resources have been reconciled.")
+	return nil
+}
+
+// watch watches changed resources with api.Watch and renders their status in the terminal until all of them are
+// ready
+func watch(planResources *models.Spec, changes *opsmodels.Changes) error {
+	// Keep sorted, unchanged resources are not watched
+	var ids []string
 	for _, res := range planResources.Resources {
 		if changes.ChangeOrder.ChangeSteps[res.ResourceKey()].Action != opsmodels.UnChange {
-			toBeWatched = append(toBeWatched, res)
+			ids = append(ids, res.ResourceKey())
 		}
 	}
 
-	// Watch operation
-	wo := &operation.WatchOperation{}
-	if err := wo.Watch(&operation.WatchRequest{
-		Request: opsmodels.Request{
-			Project: changes.Project(),
-			Stack:   changes.Stack(),
-			Spec:    &models.Spec{Resources: toBeWatched},
-		},
-	}); err != nil {
-		return err
+	// Console writer
+	writer := uilive.New()
+	writer.RefreshInterval = time.Minute * 1
+	writer.Start()
+	defer writer.Stop()
+
+	// Receive events in background and render tables every 1s once watchers are started
+	var mu sync.Mutex
+	var tables map[string]*printers.Table
+	done := make(chan error, 1)
+	go func() {
+		done <- api.Watch(context.Background(), planResources, changes, &api.WatchOptions{
+			OnStart: func(objects map[string][]string) {
+				mu.Lock()
+				defer mu.Unlock()
+				tables = make(map[string]*printers.Table, len(objects))
+				for id, objectIDs := range objects {
+					tables[id] = printers.NewTable(objectIDs)
+				}
+			},
+			OnEvent: func(e *operation.WatchEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				tables[e.ResourceID].Update(e.ObjectID, e.Row)
+			},
+		})
+	}()
+
+	printTables := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if tables != nil {
+			operation.PrintTables(writer, ids, tables)
+		}
 	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			printTables()
+			return err
+		case <-ticker.C:
+			printTables()
+		}
+	}
+}
 
-	fmt.Println("Watch Finish! All resources have been reconciled.")
-	return nil
+// Wait function will wait for all changed resources to be ready
+// within the timeout, and print a report of resources which are not
+// ready if it fails.
+func Wait(
+	o *ApplyOptions,
+	planResources *models.Spec,
+	changes *opsmodels.Changes,
+	out io.Writer,
+) error {
+	if o.DryRun {
+		fmt.Fprintln(out, "NOTE: Wait doesn't work in DryRun mode")
+		return nil
+	}
+
+	results, err := api.Wait(context.Background(), planResources, changes, &api.WaitOptions{Timeout: o.Timeout})
+	if err == nil {
+		fmt.Fprintln(out, pretty.GreenBold("All resources are ready."))
+		return nil
+	}
+
+	// Report resources which are not ready
+	data := [][]string{{"ID", "Status", "Reason"}}
+	var notReady []string
+	for _, r := range results {
+		if r.Status != health.Current {
+			data = append(data, []string{r.ResourceID, string(r.Status), r.Message})
+			notReady = append(notReady, r.ResourceID)
+		}
+	}
+	fmt.Fprintln(out, pretty.RedBold("Some resources are not ready:"))
+	_ = pterm.DefaultTable.WithHasHeader().WithData(data).WithWriter(out).Render()
+	if len(notReady) == 0 {
+		return fmt.Errorf("wait failed: %w", err)
+	}
+	return fmt.Errorf("wait failed: %w. Not ready: %s", err, strings.Join(notReady, ", "))
 }
 
 type lineSummary struct {
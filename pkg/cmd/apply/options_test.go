@@ -7,13 +7,16 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"bou.ke/monkey"
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/stretchr/testify/assert"
 
+	"kusionstack.io/kusion/pkg/api"
 	"kusionstack.io/kusion/pkg/cmd/spec"
 	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/health"
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
@@ -310,3 +313,43 @@ func mockPromptOutput(res string) {
 		},
 	)
 }
+
+func TestWait(t *testing.T) {
+	planResources := &models.Spec{Resources: []models.Resource{sa1}}
+	changes := opsmodels.NewChanges(project, stack, &opsmodels.ChangeOrder{
+		StepKeys:    []string{sa1.ID},
+		ChangeSteps: map[string]*opsmodels.ChangeStep{sa1.ID: {ID: sa1.ID, Action: opsmodels.Create, From: &sa1}},
+	})
+
+	t.Run("all ready", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockWait(health.Current, nil)
+
+		o := NewApplyOptions()
+		o.Timeout = time.Second
+		assert.Nil(t, Wait(o, planResources, changes, os.Stdout))
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockWait(health.Failed, errors.New("resource failed"))
+
+		o := NewApplyOptions()
+		o.Timeout = time.Second
+		err := Wait(o, planResources, changes, os.Stdout)
+		assert.ErrorContains(t, err, sa1.ID)
+	})
+}
+
+func mockWait(s health.Status, err error) {
+	monkey.Patch(api.Wait,
+		func(ctx context.Context, sp *models.Spec, changes *opsmodels.Changes, o *api.WaitOptions) ([]*operation.WaitResult, error) {
+			return []*operation.WaitResult{{ResourceID: sa1.ID, Result: health.Result{Status: s, Message: "mock"}}}, err
+		})
+}
+
+func TestApplyOptions_Validate(t *testing.T) {
+	o := NewApplyOptions()
+	o.Wait = true
+	assert.NotNil(t, o.Validate())
+}
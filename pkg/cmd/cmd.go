@@ -25,7 +25,9 @@ import (
 	"kusionstack.io/kusion/pkg/cmd/env"
 	cmdinit "kusionstack.io/kusion/pkg/cmd/init"
 	"kusionstack.io/kusion/pkg/cmd/ls"
+	"kusionstack.io/kusion/pkg/cmd/orphans"
 	"kusionstack.io/kusion/pkg/cmd/preview"
+	"kusionstack.io/kusion/pkg/cmd/server"
 	"kusionstack.io/kusion/pkg/cmd/version"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/util/gitutil"
@@ -128,6 +130,7 @@ func NewKusionctlCmd(in io.Reader, out, err io.Writer) *cobra.Command {
 				preview.NewCmdPreview(),
 				apply.NewCmdApply(),
 				destroy.NewCmdDestroy(),
+				orphans.NewCmdOrphans(),
 			},
 		},
 	}
@@ -141,6 +144,7 @@ func NewKusionctlCmd(in io.Reader, out, err io.Writer) *cobra.Command {
 	// cmds.AddCommand(plugin.NewCmdPlugin(f, ioStreams))
 	cmds.AddCommand(version.NewCmdVersion())
 	cmds.AddCommand(env.NewCmdEnv())
+	cmds.AddCommand(server.NewCmdServer())
 
 	return cmds
 }
@@ -1,23 +1,24 @@
 package destroy
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
-	"sync"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/pterm/pterm"
 
+	"kusionstack.io/kusion/pkg/api"
 	compilecmd "kusionstack.io/kusion/pkg/cmd/compile"
+	"kusionstack.io/kusion/pkg/cmd/util"
 	"kusionstack.io/kusion/pkg/engine/backend"
 	"kusionstack.io/kusion/pkg/engine/models"
-	"kusionstack.io/kusion/pkg/engine/operation"
+	"kusionstack.io/kusion/pkg/engine/notification"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
-	"kusionstack.io/kusion/pkg/status"
 	jsonutil "kusionstack.io/kusion/pkg/util/json"
 	"kusionstack.io/kusion/pkg/util/signals"
 )
@@ -118,53 +119,28 @@ func (o *DestroyOptions) Run() error {
 
 	// Destroy
 	fmt.Println("Start destroying resources......")
+	notifier := notification.NewNotifier(project.Notification)
+	defer notifier.Flush(notification.DefaultFlushTimeout)
+	notifier.Send(util.NewNotificationEvent(notification.DestroyStarted, changes, o.Operator, nil))
 	if err := o.destroy(spec, changes, stateStorage); err != nil {
+		notifier.Send(util.NewNotificationEvent(notification.DestroyFailed, changes, o.Operator, err))
 		return err
 	}
+	notifier.Send(util.NewNotificationEvent(notification.DestroySucceeded, changes, o.Operator, nil))
 	return nil
 }
 
 func (o *DestroyOptions) preview(planResources *models.Spec, project *projectstack.Project,
 	stack *projectstack.Stack, stateStorage states.StateStorage,
 ) (*opsmodels.Changes, error) {
-	log.Info("Start compute preview changes ...")
-
-	pc := &operation.PreviewOperation{
-		Operation: opsmodels.Operation{
-			OperationType: opsmodels.DestroyPreview,
-			Stack:         stack,
-			StateStorage:  stateStorage,
-			ChangeOrder:   &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
-		},
-	}
-
-	log.Info("Start call pc.Preview() ...")
-
-	rsp, s := pc.Preview(&operation.PreviewRequest{
-		Request: opsmodels.Request{
-			Tenant:   project.Tenant,
-			Project:  project,
-			Operator: o.Operator,
-			Stack:    stack,
-			Spec:     planResources,
-		},
+	return api.Preview(context.Background(), project, stack, planResources, &api.PreviewOptions{
+		Dependencies:  api.Dependencies{StateStorage: stateStorage},
+		OperationType: opsmodels.DestroyPreview,
+		Operator:      o.Operator,
 	})
-	if status.IsErr(s) {
-		return nil, fmt.Errorf("preview failed, status: %v", s)
-	}
-
-	return opsmodels.NewChanges(project, stack, rsp.Order), nil
 }
 
 func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.Changes, stateStorage states.StateStorage) error {
-	do := &operation.DestroyOperation{
-		Operation: opsmodels.Operation{
-			Stack:        changes.Stack(),
-			StateStorage: stateStorage,
-			MsgCh:        make(chan opsmodels.Message),
-		},
-	}
-
 	// line summary
 	var deleted int
 
@@ -173,80 +149,59 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 	if err != nil {
 		return err
 	}
-	// wait msgCh close
-	var wg sync.WaitGroup
-	// receive msg and print detail
-	go func() {
-		defer func() {
-			if p := recover(); p != nil {
-				log.Errorf("failed to receive msg and print detail as %v", p)
-			}
-		}()
-		wg.Add(1)
 
-		for {
-			select {
-			case msg, ok := <-do.MsgCh:
-				if !ok {
-					wg.Done()
-					return
-				}
-				changeStep := changes.Get(msg.ResourceID)
+	// receive msg and print detail
+	progress := func(msg opsmodels.Message) {
+		changeStep := changes.Get(msg.ResourceID)
+		if changeStep == nil {
+			return
+		}
 
-				switch msg.OpResult {
-				case opsmodels.Success, opsmodels.Skip:
-					var title string
-					if changeStep.Action == opsmodels.UnChange {
-						title = fmt.Sprintf("%s %s, %s",
-							changeStep.Action.String(),
-							pterm.Bold.Sprint(changeStep.ID),
-							strings.ToLower(string(opsmodels.Skip)),
-						)
-					} else {
-						title = fmt.Sprintf("%s %s %s",
-							changeStep.Action.String(),
-							pterm.Bold.Sprint(changeStep.ID),
-							strings.ToLower(string(msg.OpResult)),
-						)
-					}
-					pterm.Success.Println(title)
-					progressbar.UpdateTitle(title)
-					progressbar.Increment()
-					deleted++
-				case opsmodels.Failed:
-					title := fmt.Sprintf("%s %s %s",
-						changeStep.Action.String(),
-						pterm.Bold.Sprint(changeStep.ID),
-						strings.ToLower(string(msg.OpResult)),
-					)
-					pterm.Error.Printf("%s\n", title)
-				default:
-					title := fmt.Sprintf("%s %s %s",
-						changeStep.Action.Ing(),
-						pterm.Bold.Sprint(changeStep.ID),
-						strings.ToLower(string(msg.OpResult)),
-					)
-					progressbar.UpdateTitle(title)
-				}
+		switch msg.OpResult {
+		case opsmodels.Success, opsmodels.Skip:
+			var title string
+			if changeStep.Action == opsmodels.UnChange {
+				title = fmt.Sprintf("%s %s, %s",
+					changeStep.Action.String(),
+					pterm.Bold.Sprint(changeStep.ID),
+					strings.ToLower(string(opsmodels.Skip)),
+				)
+			} else {
+				title = fmt.Sprintf("%s %s %s",
+					changeStep.Action.String(),
+					pterm.Bold.Sprint(changeStep.ID),
+					strings.ToLower(string(msg.OpResult)),
+				)
 			}
+			pterm.Success.Println(title)
+			progressbar.UpdateTitle(title)
+			progressbar.Increment()
+			deleted++
+		case opsmodels.Failed:
+			title := fmt.Sprintf("%s %s %s",
+				changeStep.Action.String(),
+				pterm.Bold.Sprint(changeStep.ID),
+				strings.ToLower(string(msg.OpResult)),
+			)
+			pterm.Error.Printf("%s\n", title)
+		default:
+			title := fmt.Sprintf("%s %s %s",
+				changeStep.Action.Ing(),
+				pterm.Bold.Sprint(changeStep.ID),
+				strings.ToLower(string(msg.OpResult)),
+			)
+			progressbar.UpdateTitle(title)
 		}
-	}()
+	}
 
-	st := do.Destroy(&operation.DestroyRequest{
-		Request: opsmodels.Request{
-			Tenant:   changes.Project().Tenant,
-			Project:  changes.Project(),
-			Operator: o.Operator,
-			Stack:    changes.Stack(),
-			Spec:     planResources,
-		},
-	})
-	if status.IsErr(st) {
-		return fmt.Errorf("destroy failed, status: %v", st)
+	if err = api.Destroy(context.Background(), planResources, changes, &api.DestroyOptions{
+		Dependencies: api.Dependencies{StateStorage: stateStorage},
+		Operator:     o.Operator,
+		Progress:     progress,
+	}); err != nil {
+		return err
 	}
 
-	// wait for msgCh closed
-	wg.Wait()
 	// Print summary
 	pterm.Println()
 	pterm.Printf("Destroy complete! Resources: %d deleted.\n", deleted)
@@ -0,0 +1,262 @@
+package orphans
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/pterm/pterm"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	compilecmd "kusionstack.io/kusion/pkg/cmd/compile"
+	"kusionstack.io/kusion/pkg/cmd/spec"
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/backend"
+	_ "kusionstack.io/kusion/pkg/engine/backend/init"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/util/pretty"
+)
+
+// OrphansOptions defines flags for the `orphans` command
+type OrphansOptions struct {
+	compilecmd.CompileOptions
+	Adopt bool
+	Prune bool
+	Yes   bool
+	backend.BackendOps
+}
+
+// orphan is a live object carrying the labels of the stack but missing from the state
+type orphan struct {
+	resource *models.Resource
+	// cluster is the name of the cluster where the object is found, empty for the cluster of the stack
+	cluster string
+	inSpec  bool
+}
+
+// NewOrphansOptions returns a new OrphansOptions instance
+func NewOrphansOptions() *OrphansOptions {
+	return &OrphansOptions{
+		CompileOptions: *compilecmd.NewCompileOptions(),
+	}
+}
+
+func (o *OrphansOptions) Complete(args []string) {
+	o.CompileOptions.Complete(args)
+}
+
+func (o *OrphansOptions) Validate() error {
+	if o.Adopt && o.Prune {
+		return errors.New("--adopt and --prune can not be specified at the same time")
+	}
+	return o.CompileOptions.Validate()
+}
+
+func (o *OrphansOptions) Run() error {
+	// Parse project and stack of work directory
+	project, stack, err := projectstack.DetectProjectAndStack(o.CompileOptions.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	// generate Spec
+	sp, err := spec.GenerateSpecWithSpinner(&generator.Options{
+		WorkDir:     o.WorkDir,
+		Filenames:   o.Filenames,
+		Settings:    o.Settings,
+		Arguments:   o.Arguments,
+		Overrides:   o.Overrides,
+		DisableNone: o.DisableNone,
+		OverrideAST: o.OverrideAST,
+	}, project, stack)
+	if err != nil {
+		return err
+	}
+	if sp == nil {
+		sp = &models.Spec{}
+	}
+
+	// Get state storage from backend config to manage state
+	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir)
+	if err != nil {
+		return err
+	}
+	query := &states.StateQuery{
+		Tenant:  project.Tenant,
+		Stack:   stack.Name,
+		Project: project.Name,
+	}
+	latestState, err := stateStorage.GetLatestState(query)
+	if err != nil {
+		return err
+	}
+	if latestState == nil {
+		latestState = states.NewState()
+		latestState.Tenant = project.Tenant
+		latestState.Project = project.Name
+		latestState.Stack = stack.Name
+		latestState.CreateTime = time.Now()
+	}
+
+	// List owned objects in all clusters of the stack
+	ctx := context.Background()
+	runtimes := map[string]*kubernetes.KubernetesRuntime{}
+	var orphans []*orphan
+	for _, cluster := range clustersOf(sp.Resources, latestState.Resources) {
+		rt, err := kubernetes.NewClusterRuntime(stack, cluster)
+		if err != nil {
+			return fmt.Errorf("init Kubernetes client of cluster %q failed: %v", cluster, err)
+		}
+		runtimes[cluster] = rt
+		owned, err := rt.ListOwned(ctx, project.Name, stack.Name)
+		if err != nil {
+			return fmt.Errorf("list objects of cluster %q failed: %v", cluster, err)
+		}
+		orphans = append(orphans, findOrphans(cluster, owned, latestState.Resources, sp.Resources)...)
+	}
+	orphans = dedupOrphans(orphans)
+
+	if len(orphans) == 0 {
+		fmt.Println(pretty.GreenBold("No orphaned objects found in this stack."))
+		return nil
+	}
+	printOrphans(orphans)
+
+	switch {
+	case o.Adopt:
+		if !o.Yes && !confirm("Do you want to adopt these objects into the state?") {
+			fmt.Println("Operation adopt canceled")
+			return nil
+		}
+		for _, orphan := range orphans {
+			latestState.Resources = append(latestState.Resources, *orphan.resource)
+		}
+		latestState.Serial += 1
+		latestState.ModifiedTime = time.Now()
+		if err = stateStorage.Apply(latestState); err != nil {
+			return err
+		}
+		fmt.Println(pretty.GreenBold(fmt.Sprintf("Adopted %d objects.", len(orphans))))
+	case o.Prune:
+		var pruned []*orphan
+		for _, orphan := range orphans {
+			if !orphan.inSpec {
+				pruned = append(pruned, orphan)
+			}
+		}
+		if len(pruned) == 0 {
+			fmt.Println(pretty.GreenBold("All orphaned objects are in the spec, nothing to prune."))
+			return nil
+		}
+		if !o.Yes && !confirm(fmt.Sprintf("Do you want to delete %d objects not in the spec?", len(pruned))) {
+			fmt.Println("Operation prune canceled")
+			return nil
+		}
+		for _, orphan := range pruned {
+			response := runtimes[orphan.cluster].Delete(ctx, &runtime.DeleteRequest{
+				Resource: orphan.resource,
+				Project:  project,
+				Stack:    stack,
+			})
+			if response.Status != nil {
+				return fmt.Errorf("delete %s failed: %s", orphan.resource.ID, response.Status.Message())
+			}
+			pterm.Success.Printf("Delete %s success\n", pterm.Bold.Sprint(orphan.resource.ID))
+		}
+	}
+	return nil
+}
+
+// clustersOf returns names of clusters of Kubernetes resources in the spec and state, and the cluster of the stack
+// which is named by an empty string
+func clustersOf(resources ...models.Resources) []string {
+	set := map[string]bool{"": true}
+	for _, rs := range resources {
+		for _, r := range rs {
+			if r.Type != runtime.Kubernetes {
+				continue
+			}
+			name, _ := r.Extensions[engine.ClusterExtension].(string)
+			set[name] = true
+		}
+	}
+	clusters := make([]string, 0, len(set))
+	for name := range set {
+		clusters = append(clusters, name)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// findOrphans returns owned objects in the cluster which are not in the state. Orphans in the spec take extensions of
+// the spec, and the others name the cluster they are found in, unless it is the cluster of the stack
+func findOrphans(cluster string, owned []unstructured.Unstructured, state, spec models.Resources) []*orphan {
+	stateIndex, specIndex := state.Index(), spec.Index()
+
+	var orphans []*orphan
+	for i := range owned {
+		res := kubernetes.AdoptedResource(&owned[i])
+		if _, ok := stateIndex[res.ID]; ok {
+			continue
+		}
+		specResource, inSpec := specIndex[res.ID]
+		switch {
+		case inSpec:
+			res.Extensions = specResource.Extensions
+		case cluster != "":
+			res.Extensions = map[string]interface{}{engine.ClusterExtension: cluster}
+		}
+		orphans = append(orphans, &orphan{resource: res, cluster: cluster, inSpec: inSpec})
+	}
+	return orphans
+}
+
+// dedupOrphans removes objects found more than once, which happens if the same cluster is named differently
+func dedupOrphans(orphans []*orphan) []*orphan {
+	seen := map[string]bool{}
+	var result []*orphan
+	for _, orphan := range orphans {
+		if seen[orphan.resource.ID] {
+			continue
+		}
+		seen[orphan.resource.ID] = true
+		result = append(result, orphan)
+	}
+	return result
+}
+
+func printOrphans(orphans []*orphan) {
+	data := [][]string{{"ID", "Cluster", "In Spec"}}
+	for _, orphan := range orphans {
+		cluster := orphan.cluster
+		if cluster == "" {
+			cluster = "-"
+		}
+		data = append(data, []string{orphan.resource.ID, cluster, fmt.Sprint(orphan.inSpec)})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(data).WithWriter(os.Stdout).Render()
+}
+
+func confirm(message string) bool {
+	prompt := &survey.Select{
+		Message: message,
+		Options: []string{"yes", "no"},
+		Default: "no",
+	}
+
+	var input string
+	if err := survey.AskOne(prompt, &input); err != nil {
+		fmt.Printf("Prompt failed %v\n", err)
+		return false
+	}
+	return input == "yes"
+}
@@ -0,0 +1,74 @@
+package orphans
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+)
+
+func newConfigMap(name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("foo")
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{kubernetes.ProjectLabel: "foo", kubernetes.StackLabel: "dev"})
+	return obj
+}
+
+func TestOrphansOptions_Validate(t *testing.T) {
+	o := NewOrphansOptions()
+	assert.Nil(t, o.Validate())
+
+	o.Adopt, o.Prune = true, true
+	assert.NotNil(t, o.Validate())
+}
+
+func TestFindOrphans(t *testing.T) {
+	owned := []unstructured.Unstructured{newConfigMap("a"), newConfigMap("b"), newConfigMap("c")}
+	state := models.Resources{{ID: "v1:ConfigMap:foo:a", Type: runtime.Kubernetes}}
+	spec := models.Resources{
+		{ID: "v1:ConfigMap:foo:a", Type: runtime.Kubernetes},
+		{ID: "v1:ConfigMap:foo:b", Type: runtime.Kubernetes},
+	}
+
+	orphans := findOrphans("", owned, state, spec)
+	assert.Len(t, orphans, 2)
+	assert.Equal(t, "v1:ConfigMap:foo:b", orphans[0].resource.ID)
+	assert.True(t, orphans[0].inSpec)
+	assert.Equal(t, "v1:ConfigMap:foo:c", orphans[1].resource.ID)
+	assert.False(t, orphans[1].inSpec)
+
+	assert.Len(t, dedupOrphans(append(orphans, orphans...)), 2)
+	assert.Nil(t, orphans[0].resource.Extensions)
+	assert.Nil(t, orphans[1].resource.Extensions)
+
+	t.Run("other cluster", func(t *testing.T) {
+		east := map[string]interface{}{engine.ClusterExtension: "east"}
+		spec := models.Resources{{ID: "v1:ConfigMap:foo:a", Type: runtime.Kubernetes, Extensions: east}}
+		orphans := findOrphans("east", owned[:2], nil, spec)
+		assert.Len(t, orphans, 2)
+		assert.Equal(t, east, orphans[0].resource.Extensions)
+		assert.True(t, orphans[0].inSpec)
+		assert.Equal(t, east, orphans[1].resource.Extensions)
+		assert.False(t, orphans[1].inSpec)
+	})
+}
+
+func TestClustersOf(t *testing.T) {
+	spec := models.Resources{
+		{ID: "a", Type: runtime.Kubernetes, Extensions: map[string]interface{}{engine.ClusterExtension: "prod"}},
+		{ID: "b", Type: runtime.Terraform, Extensions: map[string]interface{}{engine.ClusterExtension: "foo"}},
+	}
+	state := models.Resources{
+		{ID: "c", Type: runtime.Kubernetes},
+		{ID: "d", Type: runtime.Kubernetes, Extensions: map[string]interface{}{engine.ClusterExtension: "dev"}},
+	}
+	assert.Equal(t, []string{"", "dev", "prod"}, clustersOf(spec, state))
+}
@@ -0,0 +1,61 @@
+package orphans
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	orphansShort = "List Kubernetes objects of the stack which are missing from the state"
+
+	orphansLong = `
+		List Kubernetes objects labeled with the project and stack of the work directory, which are
+		missing from the state. These objects are usually left behind by interrupted operations or
+		lost states.
+
+		Orphaned objects can be adopted into the state with --adopt, or deleted with --prune.
+		Objects still in the spec are never pruned, and will be managed by the next apply once
+		they are adopted.`
+
+	orphansExample = `
+		# List orphaned objects of the current stack
+		kusion orphans
+
+		# Adopt orphaned objects into the state
+		kusion orphans --adopt
+
+		# Delete orphaned objects which are not in the spec without prompt
+		kusion orphans --prune --yes`
+)
+
+func NewCmdOrphans() *cobra.Command {
+	o := NewOrphansOptions()
+
+	cmd := &cobra.Command{
+		Use:     "orphans",
+		Short:   i18n.T(orphansShort),
+		Long:    templates.LongDesc(i18n.T(orphansLong)),
+		Example: templates.Examples(i18n.T(orphansExample)),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddCompileFlags(cmd)
+	cmd.Flags().BoolVarP(&o.Adopt, "adopt", "", false,
+		i18n.T("Adopt orphaned objects into the state"))
+	cmd.Flags().BoolVarP(&o.Prune, "prune", "", false,
+		i18n.T("Delete orphaned objects which are not in the spec"))
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", false,
+		i18n.T("Automatically approve adopting or pruning orphaned objects"))
+	o.AddBackendFlags(cmd)
+
+	return cmd
+}
@@ -1,6 +1,7 @@
 package preview
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,18 +9,16 @@ import (
 
 	"github.com/pterm/pterm"
 
+	"kusionstack.io/kusion/pkg/api"
 	compilecmd "kusionstack.io/kusion/pkg/cmd/compile"
 	"kusionstack.io/kusion/pkg/cmd/spec"
 	"kusionstack.io/kusion/pkg/cmd/util"
 	"kusionstack.io/kusion/pkg/engine/backend"
 	"kusionstack.io/kusion/pkg/engine/models"
-	"kusionstack.io/kusion/pkg/engine/operation"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/generator"
-	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
-	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
 
@@ -32,12 +31,13 @@ type PreviewOptions struct {
 }
 
 type PreviewFlags struct {
-	Operator     string
-	Detail       bool
-	All          bool
-	NoStyle      bool
-	Output       string
-	IgnoreFields []string
+	Operator       string
+	Detail         bool
+	All            bool
+	NoStyle        bool
+	Output         string
+	IgnoreFields   []string
+	ForceConflicts bool
 }
 
 func NewPreviewOptions() *PreviewOptions {
@@ -150,26 +150,8 @@ func (o *PreviewOptions) Run() error {
 }
 
 // The Preview function calculates the upcoming actions of each resource
-// through the execution Kusion Engine, and you can customize the
-// runtime of engine and the state storage through `runtime` and
-// `storage` parameters.
-//
-// Example:
-//
-//	o := NewPreviewOptions()
-//	stateStorage := &states.FileSystemState{
-//	    Path: filepath.Join(o.WorkDir, states.KusionState)
-//	}
-//	kubernetesRuntime, err := runtime.NewKubernetesRuntime()
-//	if err != nil {
-//	    return err
-//	}
-//
-//	changes, err := Preview(o, kubernetesRuntime, stateStorage,
-//	    planResources, project, stack, os.Stdout)
-//	if err != nil {
-//	    return err
-//	}
+// with flags of the `preview` command. It is a thin wrapper of api.Preview,
+// which should be used to preview changes programmatically.
 func Preview(
 	o *PreviewOptions,
 	storage states.StateStorage,
@@ -177,42 +159,11 @@ func Preview(
 	project *projectstack.Project,
 	stack *projectstack.Stack,
 ) (*opsmodels.Changes, error) {
-	log.Info("Start compute preview changes ...")
-
-	// Validate secret stores
-	if !project.SecretStores.IsValid() {
-		return nil, fmt.Errorf("no secret store is provided")
-	}
-
-	// Construct the preview operation
-	pc := &operation.PreviewOperation{
-		Operation: opsmodels.Operation{
-			OperationType: opsmodels.ApplyPreview,
-			Stack:         stack,
-			StateStorage:  storage,
-			IgnoreFields:  o.IgnoreFields,
-			ChangeOrder:   &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
-			SecretStores:  project.SecretStores,
-		},
-	}
-
-	log.Info("Start call pc.Preview() ...")
-
-	// parse cluster in arguments
-	cluster := util.ParseClusterArgument(o.Arguments)
-	rsp, s := pc.Preview(&operation.PreviewRequest{
-		Request: opsmodels.Request{
-			Tenant:   project.Tenant,
-			Project:  project,
-			Stack:    stack,
-			Operator: o.Operator,
-			Spec:     planResources,
-			Cluster:  cluster,
-		},
+	return api.Preview(context.Background(), project, stack, planResources, &api.PreviewOptions{
+		Dependencies:   api.Dependencies{StateStorage: storage},
+		Operator:       o.Operator,
+		Cluster:        util.ParseClusterArgument(o.Arguments),
+		IgnoreFields:   o.IgnoreFields,
+		ForceConflicts: o.ForceConflicts,
 	})
-	if status.IsErr(s) {
-		return nil, fmt.Errorf("preview failed.\n%s", s.String())
-	}
-
-	return opsmodels.NewChanges(project, stack, rsp.Order), nil
 }
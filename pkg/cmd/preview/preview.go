@@ -68,4 +68,6 @@ func (o *PreviewOptions) AddPreviewFlags(cmd *cobra.Command) {
 		i18n.T("Ignore differences of target fields"))
 	cmd.Flags().StringVarP(&o.Output, "output", "o", "",
 		i18n.T("Specify the output format"))
+	cmd.Flags().BoolVarP(&o.ForceConflicts, "force-conflicts", "", false,
+		i18n.T("Take over fields managed by others when conflicts occur in server-side apply"))
 }
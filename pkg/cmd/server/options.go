@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"kusionstack.io/kusion/pkg/server"
+)
+
+// TokenEnv is the environment variable to read the token from, so that the token is not exposed in process args
+const TokenEnv = "KUSION_SERVER_TOKEN"
+
+type ServerOptions struct {
+	server.Options
+}
+
+func NewServerOptions() *ServerOptions {
+	return &ServerOptions{}
+}
+
+func (o *ServerOptions) Complete(args []string) {
+	if len(args) > 0 {
+		o.WorkDir = args[0]
+	}
+
+	if o.WorkDir == "" {
+		o.WorkDir, _ = os.Getwd()
+	}
+
+	if o.Token == "" {
+		o.Token = os.Getenv(TokenEnv)
+	}
+}
+
+func (o *ServerOptions) Validate() error {
+	if _, err := os.Stat(o.WorkDir); err != nil {
+		return fmt.Errorf("invalid work dir: %s", err)
+	}
+	if o.MaxConcurrency < 0 {
+		return fmt.Errorf("invalid max concurrency: must not be negative")
+	}
+	if o.OperationTimeout < 0 {
+		return fmt.Errorf("invalid operation timeout: must not be negative")
+	}
+	if o.Token == "" && o.ClientCAFile == "" {
+		return fmt.Errorf("authentication is required: set a token by --token or $%s, or client certificates by --tls-client-ca", TokenEnv)
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		return fmt.Errorf("invalid TLS config: --tls-cert and --tls-key must be set together")
+	}
+	if o.ClientCAFile != "" && o.TLSCertFile == "" {
+		return fmt.Errorf("invalid TLS config: --tls-client-ca requires --tls-cert and --tls-key")
+	}
+	return nil
+}
+
+func (o *ServerOptions) Run() error {
+	// shutdown the server gracefully on interrupts or the SIGTERM signal
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return server.NewServer(&o.Options).Run(ctx)
+}
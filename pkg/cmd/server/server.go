@@ -0,0 +1,70 @@
+package server
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/server"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	serverShort = "Start a Kusion server exposing operations over HTTP"
+
+	serverLong = `
+		Start a Kusion server which exposes project and stack listing, compile, preview, apply,
+		destroy and state queries of all projects in the work directory as a versioned HTTP/JSON API.
+
+		Apply and destroy report the progress of each resource as a stream of newline-delimited JSON,
+		and only one of them can run on a stack at the same time.
+
+		The server listens on the loopback address by default. All API requests must be authenticated
+		by a bearer token, or by client certificates when serving HTTPS with a client CA.`
+
+	serverExample = `
+		# Start a server for all projects in the current directory
+		KUSION_SERVER_TOKEN=my-token kusion server
+
+		# Start a server for all projects in the specify directory, which verifies client certificates
+		kusion server ./path/to/projects --address :9090 --tls-cert server.crt --tls-key server.key --tls-client-ca ca.crt
+
+		# Preview a stack
+		curl -X POST localhost:8080/api/v1/projects/my-project/stacks/dev/preview -H "Authorization: Bearer my-token" -d '{}'`
+)
+
+func NewCmdServer() *cobra.Command {
+	o := NewServerOptions()
+
+	cmd := &cobra.Command{
+		Use:     "server [WORKDIR]",
+		Short:   i18n.T(serverShort),
+		Long:    templates.LongDesc(i18n.T(serverLong)),
+		Example: templates.Examples(i18n.T(serverExample)),
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Address, "address", server.DefaultAddress,
+		i18n.T("the TCP address the server listens on"))
+	cmd.Flags().StringVar(&o.Token, "token", "",
+		i18n.T("the bearer token clients must send, which can also be set by $"+TokenEnv))
+	cmd.Flags().StringVar(&o.TLSCertFile, "tls-cert", "",
+		i18n.T("the certificate file to serve HTTPS with"))
+	cmd.Flags().StringVar(&o.TLSKeyFile, "tls-key", "",
+		i18n.T("the key file to serve HTTPS with"))
+	cmd.Flags().StringVar(&o.ClientCAFile, "tls-client-ca", "",
+		i18n.T("the CA file to verify client certificates with, which requires clients to present certificates"))
+	cmd.Flags().IntVar(&o.MaxConcurrency, "max-concurrency", server.DefaultMaxConcurrency,
+		i18n.T("the max number of operations running at the same time"))
+	cmd.Flags().DurationVar(&o.OperationTimeout, "operation-timeout", 0,
+		i18n.T("the max duration of an apply or destroy, which keeps running when clients disconnect. 0 means no limit"))
+
+	return cmd
+}
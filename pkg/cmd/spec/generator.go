@@ -1,13 +1,14 @@
 package spec
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pterm/pterm"
 
+	"kusionstack.io/kusion/pkg/api"
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/generator"
-	"kusionstack.io/kusion/pkg/generator/kcl"
 	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
@@ -22,25 +23,7 @@ func GenerateSpecWithSpinner(o *generator.Options, project *projectstack.Project
 		sp, _ = sp.Start(fmt.Sprintf("Generating Spec in the Stack %s...", stack.Name))
 	}
 
-	// Choose the generator
-	var g generator.Generator
-	pg := project.Generator
-
-	// default Generator
-	if pg == nil {
-		g = &kcl.Generator{}
-	} else {
-		gt := pg.Type
-		// we can add more generators here
-		switch gt {
-		case projectstack.KCLGenerator:
-			g = &kcl.Generator{}
-		default:
-			return nil, fmt.Errorf("unknow generator type:%s", gt)
-		}
-	}
-
-	spec, err := g.GenerateSpec(o, stack)
+	spec, err := api.Compile(context.Background(), project, stack, o)
 	if err != nil {
 		if !o.NoPrompt && sp != nil {
 			sp.Fail()
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"kusionstack.io/kusion/pkg/engine/notification"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+// NewNotificationEvent builds a notification event with the change summary of changes and the operation error
+func NewNotificationEvent(t notification.EventType, changes *opsmodels.Changes, operator string, err error) *notification.Event {
+	event := &notification.Event{
+		Type:     t,
+		Operator: operator,
+		Summary:  map[string]int{},
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if changes == nil {
+		return event
+	}
+	if changes.Project() != nil {
+		event.Project = changes.Project().Name
+	}
+	if changes.Stack() != nil {
+		event.Stack = changes.Stack().Name
+	}
+	if changes.ChangeOrder != nil {
+		for _, step := range changes.Values() {
+			event.Changes = append(event.Changes, &notification.Change{ID: step.ID, Action: step.Action.String()})
+			event.Summary[step.Action.String()]++
+		}
+	}
+	return event
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/notification"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func TestNewNotificationEvent(t *testing.T) {
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "p"}}
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "s"}}
+	changes := opsmodels.NewChanges(project, stack, &opsmodels.ChangeOrder{
+		StepKeys: []string{"a", "b"},
+		ChangeSteps: map[string]*opsmodels.ChangeStep{
+			"a": {ID: "a", Action: opsmodels.Create},
+			"b": {ID: "b", Action: opsmodels.UnChange},
+		},
+	})
+
+	event := NewNotificationEvent(notification.ApplyFailed, changes, "op", errors.New("failed"))
+	assert.Equal(t, &notification.Event{
+		Type:     notification.ApplyFailed,
+		Project:  "p",
+		Stack:    "s",
+		Operator: "op",
+		Changes: []*notification.Change{
+			{ID: "a", Action: "Create"},
+			{ID: "b", Action: "UnChange"},
+		},
+		Summary: map[string]int{"Create": 1, "UnChange": 1},
+		Error:   "failed",
+	}, event)
+}
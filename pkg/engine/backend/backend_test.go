@@ -6,8 +6,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/zclconf/go-cty/cty"
 
-	_ "kusionstack.io/kusion/pkg/engine/backend/init"
+	backendInit "kusionstack.io/kusion/pkg/engine/backend/init"
 	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/fake"
 	"kusionstack.io/kusion/pkg/engine/states/local"
 )
 
@@ -94,6 +95,21 @@ func TestBackendFromConfig(t *testing.T) {
 	}
 }
 
+func TestBackendFromConfig_Registered(t *testing.T) {
+	fakeStorage := fake.NewStateStorage()
+	restore := backendInit.Register("fake", fakeStorage.BackendFn())
+
+	storage, err := BackendFromConfig(&Storage{Type: "fake"}, BackendOps{}, "./")
+	if err != nil || storage != fakeStorage {
+		t.Errorf("BackendFromConfig() = %v, %v, want the registered storage", storage, err)
+	}
+
+	restore()
+	if _, err = BackendFromConfig(&Storage{Type: "fake"}, BackendOps{}, "./"); err == nil {
+		t.Errorf("BackendFromConfig() succeeded after the backend is restored")
+	}
+}
+
 func TestValidBackendConfig(t *testing.T) {
 	type args struct {
 		config map[string]interface{}
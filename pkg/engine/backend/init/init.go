@@ -1,6 +1,8 @@
 package init
 
 import (
+	"sync"
+
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/engine/states/local"
 	"kusionstack.io/kusion/pkg/engine/states/remote/db"
@@ -10,7 +12,10 @@ import (
 )
 
 // backends store all available backend
-var backends map[string]func() states.Backend
+var (
+	backends   map[string]func() states.Backend
+	backendsMu sync.RWMutex
+)
 
 // init backends map with all support backend
 func init() {
@@ -25,5 +30,25 @@ func init() {
 
 // GetBackend return backend, or nil if not exists
 func GetBackend(name string) func() states.Backend {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
 	return backends[name]
 }
+
+// Register registers the backend func with the name, and replaces the registered one if any. The returned func
+// restores the previous registration, which helps tests registering fake backends
+func Register(name string, fn func() states.Backend) (restore func()) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	prev, ok := backends[name]
+	backends[name] = fn
+	return func() {
+		backendsMu.Lock()
+		defer backendsMu.Unlock()
+		if ok {
+			backends[name] = prev
+		} else {
+			delete(backends, name)
+		}
+	}
+}
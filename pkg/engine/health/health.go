@@ -0,0 +1,96 @@
+// Package health computes whether a live Kubernetes object is ready with kstatus-style rules.
+//
+// Built-in workloads like Deployment, StatefulSet, DaemonSet, Pod and Job are checked with rules of their own kinds,
+// and all other kinds, including CRDs, are checked with the generic `status.conditions`. An object is Current if it
+// is ready, InProgress if it is still reconciling, and Failed if it will never be ready without a new change, such
+// as a Deployment that exceeds its progress deadline.
+package health
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Status is the health status of an object
+type Status string
+
+const (
+	// InProgress means the object is not ready yet but is still reconciling
+	InProgress Status = "InProgress"
+	// Current means the object is ready
+	Current Status = "Current"
+	// Failed means the object is unlikely to be ready without a new change
+	Failed Status = "Failed"
+	// NotFound means the object doesn't exist
+	NotFound Status = "NotFound"
+	// Unknown means the health of the object can't be computed
+	Unknown Status = "Unknown"
+)
+
+// Result is the health result of an object
+type Result struct {
+	Status Status `json:"status" yaml:"status"`
+	// Message explains why the object is not Current
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+func newResult(s Status, format string, args ...interface{}) *Result {
+	return &Result{Status: s, Message: fmt.Sprintf(format, args...)}
+}
+
+// rule computes the health of objects of one kind
+type rule func(obj *unstructured.Unstructured) (*Result, error)
+
+// rules contains all kind-specific rules. The key is the GroupKind string, like `Deployment.apps`
+var rules = map[string]rule{
+	"Deployment.apps":       deploymentHealth,
+	"StatefulSet.apps":      statefulSetHealth,
+	"DaemonSet.apps":        daemonSetHealth,
+	"ReplicaSet.apps":       replicaSetHealth,
+	"Pod":                   podHealth,
+	"Job.batch":             jobHealth,
+	"PersistentVolumeClaim": pvcHealth,
+	"Service":               serviceHealth,
+	"Namespace":             namespaceHealth,
+	"CustomResourceDefinition.apiextensions.k8s.io": crdHealth,
+}
+
+// Compute computes the health of the live object. A nil object is NotFound
+func Compute(obj *unstructured.Unstructured) *Result {
+	if obj == nil {
+		return newResult(NotFound, "resource not found")
+	}
+
+	// Deleting objects are never ready
+	if obj.GetDeletionTimestamp() != nil {
+		return newResult(InProgress, "resource is being deleted")
+	}
+
+	// The controller hasn't observed the latest generation yet
+	if r := observedGenerationHealth(obj); r != nil {
+		return r
+	}
+
+	gk := obj.GroupVersionKind().GroupKind().String()
+	fn, ok := rules[gk]
+	if !ok {
+		fn = genericHealth
+	}
+	r, err := fn(obj)
+	if err != nil {
+		return newResult(Unknown, "%v", err)
+	}
+	return r
+}
+
+func observedGenerationHealth(obj *unstructured.Unstructured) *Result {
+	observed := int64Field(obj, -1, "status", "observedGeneration")
+	if observed < 0 {
+		return nil
+	}
+	if generation := int64Field(obj, 0, "metadata", "generation"); observed < generation {
+		return newResult(InProgress, "latest generation %d is not observed yet, observed generation: %d", generation, observed)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func mustObject(t *testing.T, s string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	assert.Nil(t, yaml.Unmarshal([]byte(s), &obj.Object))
+	return obj
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    string
+		status Status
+	}{
+		{
+			name: "deployment available",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+metadata: {generation: 2}
+spec: {replicas: 2}
+status: {observedGeneration: 2, replicas: 2, updatedReplicas: 2, readyReplicas: 2, availableReplicas: 2}`,
+			status: Current,
+		},
+		{
+			name: "deployment generation not observed",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+metadata: {generation: 3}
+spec: {replicas: 2}
+status: {observedGeneration: 2, replicas: 2, updatedReplicas: 2, readyReplicas: 2, availableReplicas: 2}`,
+			status: InProgress,
+		},
+		{
+			name: "deployment rolling",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+spec: {replicas: 2}
+status: {replicas: 3, updatedReplicas: 1, readyReplicas: 2, availableReplicas: 2}`,
+			status: InProgress,
+		},
+		{
+			name: "deployment progress deadline exceeded",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+spec: {replicas: 2}
+status:
+  replicas: 2
+  updatedReplicas: 1
+  conditions:
+  - {type: Progressing, status: "False", reason: ProgressDeadlineExceeded, message: timeout}`,
+			status: Failed,
+		},
+		{
+			name: "statefulset revision not updated",
+			obj: `
+apiVersion: apps/v1
+kind: StatefulSet
+spec: {replicas: 1}
+status: {readyReplicas: 1, updatedReplicas: 1, currentRevision: a, updateRevision: b}`,
+			status: InProgress,
+		},
+		{
+			name: "daemonset ready",
+			obj: `
+apiVersion: apps/v1
+kind: DaemonSet
+status: {desiredNumberScheduled: 3, updatedNumberScheduled: 3, numberAvailable: 3, numberReady: 3}`,
+			status: Current,
+		},
+		{
+			name: "pod crash loop",
+			obj: `
+apiVersion: v1
+kind: Pod
+status:
+  phase: Running
+  containerStatuses:
+  - {name: app, state: {waiting: {reason: CrashLoopBackOff}}}`,
+			status: Failed,
+		},
+		{
+			name: "pod ready",
+			obj: `
+apiVersion: v1
+kind: Pod
+status:
+  phase: Running
+  conditions:
+  - {type: Ready, status: "True"}`,
+			status: Current,
+		},
+		{
+			name: "job failed",
+			obj: `
+apiVersion: batch/v1
+kind: Job
+status:
+  conditions:
+  - {type: Failed, status: "True", reason: BackoffLimitExceeded}`,
+			status: Failed,
+		},
+		{
+			name: "pending load balancer",
+			obj: `
+apiVersion: v1
+kind: Service
+spec: {type: LoadBalancer}`,
+			status: InProgress,
+		},
+		{
+			name: "cluster ip service",
+			obj: `
+apiVersion: v1
+kind: Service
+spec: {type: ClusterIP}`,
+			status: Current,
+		},
+		{
+			name: "crd established",
+			obj: `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+status:
+  conditions:
+  - {type: Established, status: "True"}`,
+			status: Current,
+		},
+		{
+			name: "custom resource not ready",
+			obj: `
+apiVersion: example.com/v1
+kind: Foo
+status:
+  conditions:
+  - {type: Ready, status: "False", reason: Waiting}`,
+			status: InProgress,
+		},
+		{
+			name: "custom resource stalled",
+			obj: `
+apiVersion: example.com/v1
+kind: Foo
+status:
+  conditions:
+  - {type: Stalled, status: "True", reason: InvalidSpec}`,
+			status: Failed,
+		},
+		{
+			name: "configmap",
+			obj: `
+apiVersion: v1
+kind: ConfigMap`,
+			status: Current,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Compute(mustObject(t, tt.obj))
+			assert.Equal(t, tt.status, r.Status, r.Message)
+		})
+	}
+
+	assert.Equal(t, NotFound, Compute(nil).Status)
+}
@@ -0,0 +1,284 @@
+package health
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// waitingFailureReasons are reasons of waiting containers which are unlikely to recover by themselves
+var waitingFailureReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
+// condition is a generic status condition
+type condition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+func getConditions(obj *unstructured.Unstructured) ([]condition, error) {
+	items, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	var conditions []condition
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		c := condition{}
+		c.Type, _, _ = unstructured.NestedString(m, "type")
+		c.Status, _, _ = unstructured.NestedString(m, "status")
+		c.Reason, _, _ = unstructured.NestedString(m, "reason")
+		c.Message, _, _ = unstructured.NestedString(m, "message")
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+func findCondition(conditions []condition, t string) *condition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// int64Field returns the integer field, which may be decoded as float64 from JSON, or defaultValue if not found
+func int64Field(obj *unstructured.Unstructured, defaultValue int64, fields ...string) int64 {
+	v, found, err := unstructured.NestedFieldNoCopy(obj.Object, fields...)
+	if err != nil || !found {
+		return defaultValue
+	}
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int32:
+		return int64(n)
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	default:
+		return defaultValue
+	}
+}
+
+func stringField(obj *unstructured.Unstructured, fields ...string) string {
+	v, _, _ := unstructured.NestedString(obj.Object, fields...)
+	return v
+}
+
+// genericHealth checks the generic `status.conditions`: Stalled means Failed, Reconciling means InProgress, and
+// Ready decides the result if exists. Objects without these conditions are regarded as Current
+func genericHealth(obj *unstructured.Unstructured) (*Result, error) {
+	conditions, err := getConditions(obj)
+	if err != nil {
+		return nil, err
+	}
+	if c := findCondition(conditions, "Stalled"); c != nil && c.Status == "True" {
+		return newResult(Failed, "%s: %s", c.Reason, c.Message), nil
+	}
+	if c := findCondition(conditions, "Reconciling"); c != nil && c.Status == "True" {
+		return newResult(InProgress, "%s: %s", c.Reason, c.Message), nil
+	}
+	if c := findCondition(conditions, "Ready"); c != nil && c.Status != "True" {
+		return newResult(InProgress, "not ready, %s: %s", c.Reason, c.Message), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func deploymentHealth(obj *unstructured.Unstructured) (*Result, error) {
+	conditions, err := getConditions(obj)
+	if err != nil {
+		return nil, err
+	}
+	if c := findCondition(conditions, "Progressing"); c != nil && c.Reason == "ProgressDeadlineExceeded" {
+		return newResult(Failed, "progress deadline exceeded: %s", c.Message), nil
+	}
+
+	replicas := int64Field(obj, 1, "spec", "replicas")
+	statusReplicas := int64Field(obj, 0, "status", "replicas")
+	updated := int64Field(obj, 0, "status", "updatedReplicas")
+	ready := int64Field(obj, 0, "status", "readyReplicas")
+	available := int64Field(obj, 0, "status", "availableReplicas")
+
+	switch {
+	case updated < replicas:
+		return newResult(InProgress, "updated replicas: %d/%d", updated, replicas), nil
+	case statusReplicas > updated:
+		return newResult(InProgress, "pending termination: %d", statusReplicas-updated), nil
+	case available < updated:
+		return newResult(InProgress, "available replicas: %d/%d", available, updated), nil
+	case ready < updated:
+		return newResult(InProgress, "ready replicas: %d/%d", ready, updated), nil
+	}
+	if c := findCondition(conditions, "Available"); c != nil && c.Status == "False" {
+		return newResult(InProgress, "not available: %s", c.Message), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func statefulSetHealth(obj *unstructured.Unstructured) (*Result, error) {
+	replicas := int64Field(obj, 1, "spec", "replicas")
+	ready := int64Field(obj, 0, "status", "readyReplicas")
+	if ready < replicas {
+		return newResult(InProgress, "ready replicas: %d/%d", ready, replicas), nil
+	}
+
+	// Pods are only updated when deleted manually with the OnDelete strategy
+	if stringField(obj, "spec", "updateStrategy", "type") == "OnDelete" {
+		return newResult(Current, ""), nil
+	}
+
+	partition := int64Field(obj, 0, "spec", "updateStrategy", "rollingUpdate", "partition")
+	updated := int64Field(obj, 0, "status", "updatedReplicas")
+	if updated < replicas-partition {
+		return newResult(InProgress, "updated replicas: %d/%d", updated, replicas-partition), nil
+	}
+	if partition == 0 {
+		current, update := stringField(obj, "status", "currentRevision"), stringField(obj, "status", "updateRevision")
+		if current != update {
+			return newResult(InProgress, "waiting for revision %s to replace %s", update, current), nil
+		}
+	}
+	return newResult(Current, ""), nil
+}
+
+func daemonSetHealth(obj *unstructured.Unstructured) (*Result, error) {
+	desired := int64Field(obj, 0, "status", "desiredNumberScheduled")
+	updated := int64Field(obj, 0, "status", "updatedNumberScheduled")
+	available := int64Field(obj, 0, "status", "numberAvailable")
+	ready := int64Field(obj, 0, "status", "numberReady")
+
+	switch {
+	case updated < desired:
+		return newResult(InProgress, "updated pods: %d/%d", updated, desired), nil
+	case available < desired:
+		return newResult(InProgress, "available pods: %d/%d", available, desired), nil
+	case ready < desired:
+		return newResult(InProgress, "ready pods: %d/%d", ready, desired), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func replicaSetHealth(obj *unstructured.Unstructured) (*Result, error) {
+	conditions, err := getConditions(obj)
+	if err != nil {
+		return nil, err
+	}
+	if c := findCondition(conditions, "ReplicaFailure"); c != nil && c.Status == "True" {
+		return newResult(InProgress, "replica failure: %s", c.Message), nil
+	}
+
+	replicas := int64Field(obj, 1, "spec", "replicas")
+	ready := int64Field(obj, 0, "status", "readyReplicas")
+	available := int64Field(obj, 0, "status", "availableReplicas")
+	switch {
+	case available < replicas:
+		return newResult(InProgress, "available replicas: %d/%d", available, replicas), nil
+	case ready < replicas:
+		return newResult(InProgress, "ready replicas: %d/%d", ready, replicas), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func podHealth(obj *unstructured.Unstructured) (*Result, error) {
+	switch stringField(obj, "status", "phase") {
+	case "Succeeded":
+		return newResult(Current, ""), nil
+	case "Failed":
+		return newResult(Failed, "pod failed, %s: %s",
+			stringField(obj, "status", "reason"), stringField(obj, "status", "message")), nil
+	}
+
+	statuses, _, err := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		m, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(m, "state", "waiting", "reason")
+		if waitingFailureReasons[reason] {
+			name, _, _ := unstructured.NestedString(m, "name")
+			message, _, _ := unstructured.NestedString(m, "state", "waiting", "message")
+			return newResult(Failed, "container %s is waiting, %s: %s", name, reason, message), nil
+		}
+	}
+
+	conditions, err := getConditions(obj)
+	if err != nil {
+		return nil, err
+	}
+	if c := findCondition(conditions, "Ready"); c != nil && c.Status == "True" {
+		return newResult(Current, ""), nil
+	}
+	return newResult(InProgress, "pod is not ready, phase: %s", stringField(obj, "status", "phase")), nil
+}
+
+func jobHealth(obj *unstructured.Unstructured) (*Result, error) {
+	conditions, err := getConditions(obj)
+	if err != nil {
+		return nil, err
+	}
+	if c := findCondition(conditions, "Failed"); c != nil && c.Status == "True" {
+		return newResult(Failed, "job failed, %s: %s", c.Reason, c.Message), nil
+	}
+	if c := findCondition(conditions, "Complete"); c != nil && c.Status == "True" {
+		return newResult(Current, ""), nil
+	}
+	return newResult(InProgress, "job in progress, succeeded: %d, failed: %d",
+		int64Field(obj, 0, "status", "succeeded"), int64Field(obj, 0, "status", "failed")), nil
+}
+
+func pvcHealth(obj *unstructured.Unstructured) (*Result, error) {
+	if phase := stringField(obj, "status", "phase"); phase != "Bound" {
+		return newResult(InProgress, "pvc is not bound, phase: %s", phase), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func serviceHealth(obj *unstructured.Unstructured) (*Result, error) {
+	if stringField(obj, "spec", "type") != "LoadBalancer" {
+		return newResult(Current, ""), nil
+	}
+	ingress, _, err := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+	if err != nil {
+		return nil, err
+	}
+	if len(ingress) == 0 {
+		return newResult(InProgress, "external ip of the load balancer is pending"), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func namespaceHealth(obj *unstructured.Unstructured) (*Result, error) {
+	if phase := stringField(obj, "status", "phase"); phase == "Terminating" {
+		return newResult(InProgress, "namespace is terminating"), nil
+	}
+	return newResult(Current, ""), nil
+}
+
+func crdHealth(obj *unstructured.Unstructured) (*Result, error) {
+	conditions, err := getConditions(obj)
+	if err != nil {
+		return nil, err
+	}
+	if c := findCondition(conditions, "NamesAccepted"); c != nil && c.Status == "False" {
+		return newResult(Failed, "names not accepted, %s: %s", c.Reason, c.Message), nil
+	}
+	if c := findCondition(conditions, "Established"); c != nil && c.Status == "True" {
+		return newResult(Current, ""), nil
+	}
+	return newResult(InProgress, "crd is not established"), nil
+}
@@ -22,12 +22,25 @@ type Resource struct {
 
 	// Extensions specifies arbitrary metadata of this resource
 	Extensions map[string]interface{} `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+
+	// ResolvedExtensions are extensions whose secret and implicit references are resolved by the engine before
+	// execution. They are only kept in memory for runtimes, and never saved in states or printed in logs
+	ResolvedExtensions map[string]interface{} `json:"-" yaml:"-"`
 }
 
 func (r *Resource) ResourceKey() string {
 	return r.ID
 }
 
+// ResolvedExtension returns the extension with its references resolved if it is resolved, otherwise the extension
+// as it is
+func (r *Resource) ResolvedExtension(key string) interface{} {
+	if v, ok := r.ResolvedExtensions[key]; ok {
+		return v
+	}
+	return r.Extensions[key]
+}
+
 // DeepCopy return a copy of resource
 func (r *Resource) DeepCopy() *Resource {
 	var out Resource
@@ -0,0 +1,2 @@
+// Package notification contains code for sending operation events like apply started, succeeded or failed to external webhooks.
+package notification
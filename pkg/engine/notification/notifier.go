@@ -0,0 +1,274 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"kusionstack.io/kusion/pkg/log"
+)
+
+// queueSize is the number of events sent by Send which are waiting for delivery
+const queueSize = 16
+
+// Notifier delivers events to all configured webhooks. A failed delivery is only logged and never returned to the caller,
+// so notifications will not break the operation itself.
+type Notifier struct {
+	webhooks []*Webhook
+	client   *http.Client
+	backoff  time.Duration
+
+	// mu guards the queue of events sent asynchronously, which are delivered in order by one goroutine
+	mu     sync.Mutex
+	queue  chan *Event
+	closed bool
+	done   chan struct{}
+	// ctx cancels asynchronous deliveries which are not finished when flushing
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewNotifier creates a Notifier with the given config. A nil config returns a Notifier which sends nothing
+func NewNotifier(config *Config) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		client:  &http.Client{},
+		backoff: DefaultBackoff,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if config != nil {
+		n.webhooks = config.Webhooks
+	}
+	return n
+}
+
+// Send delivers the event in the background without blocking the caller. Events are delivered in the order they are
+// sent, and Flush must be called before exiting to wait for them. Events sent after Flush or when the queue is full
+// are dropped
+func (n *Notifier) Send(event *Event) {
+	if n == nil || event == nil || len(n.webhooks) == 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		log.Warnf("notifier is flushed, drop event %s", event.Type)
+		return
+	}
+	if n.queue == nil {
+		n.queue = make(chan *Event, queueSize)
+		n.done = make(chan struct{})
+		go func() {
+			defer close(n.done)
+			for e := range n.queue {
+				n.Notify(n.ctx, e)
+			}
+		}()
+	}
+	// the event is dropped instead of blocking while holding the lock, if deliveries are slow like retrying with
+	// backoff and the queue is full
+	select {
+	case n.queue <- event:
+	default:
+		log.Warnf("notification queue is full, drop event %s", event.Type)
+	}
+}
+
+// Flush waits until events sent by Send are delivered or the timeout expires, and cancels deliveries not finished by
+// then
+func (n *Notifier) Flush(timeout time.Duration) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	queue, done := n.queue, n.done
+	n.mu.Unlock()
+	defer n.cancel()
+	if queue == nil {
+		return
+	}
+
+	close(queue)
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Warnf("deliveries of notifications are not finished in %s, cancel them", timeout)
+		n.cancel()
+		<-done
+	}
+}
+
+// Notify sends the event to all webhooks subscribing it and waits until all deliveries are finished
+func (n *Notifier) Notify(ctx context.Context, event *Event) {
+	if n == nil || event == nil || len(n.webhooks) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Result == "" {
+		event.Result = resultOf(event.Type)
+	}
+
+	var wg sync.WaitGroup
+	for _, wh := range n.webhooks {
+		if wh == nil || !wh.matches(event) {
+			continue
+		}
+		wg.Add(1)
+		go func(wh *Webhook) {
+			defer wg.Done()
+			if err := n.deliver(ctx, wh, event); err != nil {
+				log.Errorf("deliver event %s to webhook %s failed: %v", event.Type, wh.displayName(), err)
+			}
+		}(wh)
+	}
+	wg.Wait()
+}
+
+func (n *Notifier) deliver(ctx context.Context, wh *Webhook, event *Event) error {
+	body, err := wh.render(event)
+	if err != nil {
+		return err
+	}
+	timeout, err := wh.timeout()
+	if err != nil {
+		return err
+	}
+
+	retries := DefaultRetries
+	if wh.Retries != nil {
+		retries = *wh.Retries
+	}
+	for i := 0; ; i++ {
+		if err = n.post(ctx, wh, body, timeout); err == nil {
+			log.Infof("deliver event %s to webhook %s success", event.Type, wh.displayName())
+			return nil
+		}
+		if i >= retries {
+			return err
+		}
+		log.Warnf("deliver event %s to webhook %s failed, retry %d: %v", event.Type, wh.displayName(), i+1, err)
+		select {
+		case <-time.After(n.backoff * time.Duration(i+1)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, wh *Webhook, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range wh.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
+	}
+	return nil
+}
+
+// matches returns true if this webhook subscribes the stack and the type of the event
+func (wh *Webhook) matches(event *Event) bool {
+	if len(wh.Stacks) != 0 && !contains(wh.Stacks, event.Stack) {
+		return false
+	}
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, t := range wh.Events {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// render builds the request body with the webhook template or the JSON format of the event
+func (wh *Webhook) render(event *Event) ([]byte, error) {
+	if wh.Template == "" {
+		return json.Marshal(event)
+	}
+
+	tmpl, err := template.New(wh.displayName()).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(wh.Template)
+	if err != nil {
+		return nil, fmt.Errorf("parse template failed: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err = tmpl.Execute(buf, event); err != nil {
+		return nil, fmt.Errorf("render template failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (wh *Webhook) timeout() (time.Duration, error) {
+	if wh.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	d, err := time.ParseDuration(wh.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %s: %w", wh.Timeout, err)
+	}
+	return d, nil
+}
+
+func (wh *Webhook) displayName() string {
+	if wh.Name != "" {
+		return wh.Name
+	}
+	return wh.URL
+}
+
+// resultOf returns the result part of the event type, such as Succeeded for apply.succeeded
+func resultOf(t EventType) string {
+	s := string(t)
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifier_Notify(t *testing.T) {
+	t.Run("post event in json", func(t *testing.T) {
+		var got Event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "token", r.Header.Get("Authorization"))
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &got)
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{URL: server.URL, Headers: map[string]string{"Authorization": "token"}}}})
+		n.Notify(context.Background(), &Event{
+			Type:    ApplySucceeded,
+			Project: "p",
+			Stack:   "s",
+			Changes: []*Change{{ID: "a", Action: "Create"}},
+		})
+		assert.Equal(t, ApplySucceeded, got.Type)
+		assert.Equal(t, "Succeeded", got.Result)
+		assert.Equal(t, []*Change{{ID: "a", Action: "Create"}}, got.Changes)
+	})
+
+	t.Run("render template", func(t *testing.T) {
+		var got string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got = string(body)
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{
+			URL:      server.URL,
+			Template: `{"text": "{{ .Project }}/{{ .Stack }} {{ .Result }}: {{ json .Summary }}"}`,
+		}}})
+		n.Notify(context.Background(), &Event{Type: DestroyFailed, Project: "p", Stack: "s", Summary: map[string]int{"Delete": 2}})
+		assert.Equal(t, `{"text": "p/s Failed: {"Delete":2}"}`, got)
+	})
+
+	t.Run("filter by stacks and events", func(t *testing.T) {
+		var count int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&count, 1)
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{
+			{URL: server.URL, Stacks: []string{"prod"}},
+			{URL: server.URL, Events: []EventType{ApplyFailed}},
+		}})
+		n.Notify(context.Background(), &Event{Type: ApplyStarted, Stack: "dev"})
+		assert.Equal(t, int32(0), atomic.LoadInt32(&count))
+		n.Notify(context.Background(), &Event{Type: ApplyFailed, Stack: "prod"})
+		assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	})
+
+	t.Run("retry failed delivery", func(t *testing.T) {
+		var count int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&count, 1) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{URL: server.URL}}})
+		n.backoff = 0
+		n.Notify(context.Background(), &Event{Type: ApplyStarted})
+		assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	})
+
+	t.Run("give up after retries", func(t *testing.T) {
+		var count int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&count, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		retries := 1
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{URL: server.URL, Retries: &retries}}})
+		n.backoff = 0
+		n.Notify(context.Background(), &Event{Type: ApplyStarted})
+		assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		NewNotifier(nil).Notify(context.Background(), &Event{Type: ApplyStarted})
+	})
+}
+
+func TestWebhook_timeout(t *testing.T) {
+	d, err := (&Webhook{}).timeout()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultTimeout, d)
+
+	_, err = (&Webhook{Timeout: "ten"}).timeout()
+	assert.NotNil(t, err)
+}
+
+func TestNotifier_Send(t *testing.T) {
+	t.Run("deliver in order", func(t *testing.T) {
+		var mu sync.Mutex
+		var got []EventType
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var e Event
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &e)
+			mu.Lock()
+			got = append(got, e.Type)
+			mu.Unlock()
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{URL: server.URL}}})
+		n.Send(&Event{Type: ApplyStarted})
+		n.Send(&Event{Type: ApplySucceeded})
+		n.Flush(DefaultFlushTimeout)
+		assert.Equal(t, []EventType{ApplyStarted, ApplySucceeded}, got)
+
+		// events sent after flushing are dropped
+		n.Send(&Event{Type: ApplyFailed})
+		n.Flush(DefaultFlushTimeout)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("cancel unfinished deliveries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{URL: server.URL}}})
+		n.backoff = time.Minute
+		start := time.Now()
+		n.Send(&Event{Type: ApplyStarted})
+		n.Flush(100 * time.Millisecond)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+
+	t.Run("drop events when the queue is full", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		n := NewNotifier(&Config{Webhooks: []*Webhook{{URL: server.URL}}})
+		n.backoff = time.Minute
+		start := time.Now()
+		for i := 0; i < 2*queueSize; i++ {
+			n.Send(&Event{Type: ApplyStarted})
+		}
+		assert.Less(t, time.Since(start), 10*time.Second)
+		n.Flush(100 * time.Millisecond)
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		n := NewNotifier(nil)
+		n.Send(&Event{Type: ApplyStarted})
+		n.Flush(DefaultFlushTimeout)
+	})
+}
@@ -0,0 +1,105 @@
+package notification
+
+import (
+	"time"
+)
+
+type EventType string
+
+// EventType values
+const (
+	ApplyStarted     EventType = "apply.started"
+	ApplySucceeded   EventType = "apply.succeeded"
+	ApplyFailed      EventType = "apply.failed"
+	DestroyStarted   EventType = "destroy.started"
+	DestroySucceeded EventType = "destroy.succeeded"
+	DestroyFailed    EventType = "destroy.failed"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 3
+	DefaultBackoff = time.Second
+	// DefaultFlushTimeout is how long Flush waits for pending deliveries
+	DefaultFlushTimeout = 5 * time.Second
+)
+
+// Config represents notification configs saved in project.yaml
+type Config struct {
+	// Webhooks contains all webhooks that events will be delivered to
+	Webhooks []*Webhook `json:"webhooks,omitempty" yaml:"webhooks,omitempty"`
+}
+
+// Webhook represents an HTTP endpoint that receives operation events.
+//
+// Example:
+//
+//	notification:
+//	  webhooks:
+//	    - name: slack
+//	      url: https://hooks.slack.com/services/xxx
+//	      stacks: [prod]
+//	      events: [apply.failed, destroy.started]
+//	      template: '{"text": "{{ .Type }} {{ .Project }}/{{ .Stack }}: {{ .Result }}"}'
+type Webhook struct {
+	// Name is used to identify this webhook in logs
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// URL is the endpoint events will be posted to
+	URL string `json:"url" yaml:"url"`
+
+	// Headers are extra HTTP headers set in each request
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// Stacks are names of protected stacks this webhook cares about. Empty means all stacks
+	Stacks []string `json:"stacks,omitempty" yaml:"stacks,omitempty"`
+
+	// Events are event types this webhook subscribes. Empty means all events
+	Events []EventType `json:"events,omitempty" yaml:"events,omitempty"`
+
+	// Template is a Go text/template to render the request body with an Event.
+	// The Event is posted in JSON format if no template is given
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
+
+	// Timeout of each delivery attempt, such as "10s". Default is 10s
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Retries is the max number of retries after a failed delivery. Default is 3
+	Retries *int `json:"retries,omitempty" yaml:"retries,omitempty"`
+}
+
+// Event is the payload delivered to webhooks
+type Event struct {
+	// Type of this event, such as apply.started
+	Type EventType `json:"type"`
+
+	// Project name
+	Project string `json:"project"`
+
+	// Stack name
+	Stack string `json:"stack"`
+
+	// Operator represents the person who triggered this operation
+	Operator string `json:"operator,omitempty"`
+
+	// Changes is the change summary of this operation
+	Changes []*Change `json:"changes,omitempty"`
+
+	// Summary is the count of changes grouped by action, such as {"Create": 1}
+	Summary map[string]int `json:"summary,omitempty"`
+
+	// Result of this operation, one of Started, Succeeded and Failed
+	Result string `json:"result"`
+
+	// Error message if this operation is failed
+	Error string `json:"error,omitempty"`
+
+	// Time is the time this event happened
+	Time time.Time `json:"time"`
+}
+
+// Change is the summary of one resource change
+type Change struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+}
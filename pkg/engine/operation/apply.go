@@ -12,6 +12,7 @@ import (
 	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/third_party/terraform/dag"
 	"kusionstack.io/kusion/third_party/terraform/tfdiags"
@@ -29,7 +30,7 @@ type ApplyResponse struct {
 	State *states.State
 }
 
-func NewApplyGraph(m *models.Spec, priorState *states.State) (*dag.AcyclicGraph, status.Status) {
+func NewApplyGraph(m *models.Spec, priorState *states.State, kindOrder [][]string) (*dag.AcyclicGraph, status.Status) {
 	specParser := parser.NewSpecParser(m)
 	g := &dag.AcyclicGraph{}
 	g.Add(&graph.RootNode{})
@@ -43,10 +44,29 @@ func NewApplyGraph(m *models.Spec, priorState *states.State) (*dag.AcyclicGraph,
 	if status.IsErr(s) {
 		return nil, s
 	}
+	s = parser.NewKindOrderParser(kindOrder).Parse(g)
+	if status.IsErr(s) {
+		return nil, s
+	}
 
 	return g, s
 }
 
+// KindOrder returns the order of kinds configured in the project, parser.DefaultKindOrder if it is not configured,
+// or nil if ordering by kinds is disabled
+func KindOrder(project *projectstack.Project) [][]string {
+	if project == nil || project.Kubernetes == nil {
+		return parser.DefaultKindOrder
+	}
+	if project.Kubernetes.DisableKindOrder {
+		return nil
+	}
+	if len(project.Kubernetes.KindOrder) > 0 {
+		return project.Kubernetes.KindOrder
+	}
+	return parser.DefaultKindOrder
+}
+
 // Apply means turn all actual infra resources into the desired state described in the request by invoking a specified Runtime.
 // Like other operations, Apply has 3 main steps during the whole process.
 //  1. parse resources and their relationship to build a DAG and should take care of those resources that will be deleted
@@ -88,14 +108,22 @@ func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st s
 
 	resources := request.Spec.Resources
 	resources = append(resources, priorState.Resources...)
-	runtimesMap, s := runtimeinit.Runtimes(resources)
-	if status.IsErr(s) {
-		return nil, s
+	// runtimes provided by the caller take precedence over the ones initialized by resource types
+	if o.RuntimeMap == nil {
+		runtimesMap, s := runtimeinit.Runtimes(request.Project, resources)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		o.RuntimeMap = runtimesMap
+		// close runtimes initialized by this operation, such as runtime plugins, when it ends
+		defer func() {
+			runtimeinit.Close(runtimesMap)
+			o.RuntimeMap = nil
+		}()
 	}
-	o.RuntimeMap = runtimesMap
 
 	// 2. build & walk DAG
-	applyGraph, s := NewApplyGraph(request.Spec, priorState)
+	applyGraph, s := NewApplyGraph(request.Spec, priorState, KindOrder(request.Project))
 	if status.IsErr(s) {
 		return nil, s
 	}
@@ -110,6 +138,8 @@ func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st s
 			StateResourceIndex:      stateResourceIndex,
 			RuntimeMap:              o.RuntimeMap,
 			Stack:                   o.Stack,
+			Project:                 request.Project,
+			ForceConflicts:          o.ForceConflicts,
 			MsgCh:                   o.MsgCh,
 			ResultState:             resultState,
 			Lock:                    &sync.Mutex{},
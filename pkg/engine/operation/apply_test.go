@@ -176,7 +176,7 @@ func TestOperation_Apply(t *testing.T) {
 				o.ResultState = rs
 				return nil
 			})
-			monkey.Patch(runtimeinit.Runtimes, func(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+			monkey.Patch(runtimeinit.Runtimes, func(_ *projectstack.Project, resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
 				return map[models.Type]runtime.Runtime{runtime.Kubernetes: &kubernetes.KubernetesRuntime{}}, nil
 			})
 
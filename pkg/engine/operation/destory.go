@@ -24,7 +24,7 @@ type DestroyRequest struct {
 	opsmodels.Request `json:",inline" yaml:",inline"`
 }
 
-func NewDestroyGraph(resource models.Resources) (*dag.AcyclicGraph, status.Status) {
+func NewDestroyGraph(resource models.Resources, kindOrder [][]string) (*dag.AcyclicGraph, status.Status) {
 	ag := &dag.AcyclicGraph{}
 	ag.Add(&graph.RootNode{})
 	deleteResourceParser := parser.NewDeleteResourceParser(resource)
@@ -32,6 +32,10 @@ func NewDestroyGraph(resource models.Resources) (*dag.AcyclicGraph, status.Statu
 	if status.IsErr(s) {
 		return nil, s
 	}
+	s = parser.NewKindOrderParser(kindOrder).Parse(ag)
+	if status.IsErr(s) {
+		return nil, s
+	}
 
 	return ag, s
 }
@@ -72,14 +76,22 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 
 	// only destroy resources we have recorded
 	resources := priorState.Resources
-	runtimesMap, s := runtimeinit.Runtimes(resources)
-	if status.IsErr(s) {
-		return s
+	// runtimes provided by the caller take precedence over the ones initialized by resource types
+	if o.RuntimeMap == nil {
+		runtimesMap, s := runtimeinit.Runtimes(request.Project, resources)
+		if status.IsErr(s) {
+			return s
+		}
+		o.RuntimeMap = runtimesMap
+		// close runtimes initialized by this operation, such as runtime plugins, when it ends
+		defer func() {
+			runtimeinit.Close(runtimesMap)
+			o.RuntimeMap = nil
+		}()
 	}
-	o.RuntimeMap = runtimesMap
 
 	// 2. build & walk DAG
-	destroyGraph, s := NewDestroyGraph(resources)
+	destroyGraph, s := NewDestroyGraph(resources, KindOrder(request.Project))
 	if status.IsErr(s) {
 		return s
 	}
@@ -93,7 +105,9 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 			StateResourceIndex:      stateResourceIndex,
 			RuntimeMap:              o.RuntimeMap,
 			Stack:                   o.Stack,
+			Project:                 request.Project,
 			MsgCh:                   o.MsgCh,
+			SecretStores:            o.SecretStores,
 			ResultState:             resultState,
 			Lock:                    &sync.Mutex{},
 		},
@@ -0,0 +1,123 @@
+package operation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime/fake"
+	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
+	"kusionstack.io/kusion/pkg/engine/states"
+	fakestate "kusionstack.io/kusion/pkg/engine/states/fake"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// Tests in this file run the real engine against the fake runtime and state storage without patching
+
+const fakeType models.Type = "Fake"
+
+var (
+	fakeProject = &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "fake-project"}}
+	fakeStack   = &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "fake-stack"}}
+)
+
+func fakeResource(id string, dependsOn ...string) models.Resource {
+	return models.Resource{ID: id, Type: fakeType, Attributes: map[string]interface{}{"name": id}, DependsOn: dependsOn}
+}
+
+// registerFakeRuntime registers a fake runtime of fakeType until the test ends
+func registerFakeRuntime(t *testing.T) *fake.Runtime {
+	rt := fake.NewRuntime()
+	t.Cleanup(runtimeinit.Register(fakeType, rt.InitFn()))
+	return rt
+}
+
+func request(resources ...models.Resource) opsmodels.Request {
+	return opsmodels.Request{Project: fakeProject, Stack: fakeStack, Spec: &models.Spec{Resources: resources}}
+}
+
+func apply(storage states.StateStorage, resources ...models.Resource) (*ApplyResponse, status.Status) {
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		Stack:        fakeStack,
+		StateStorage: storage,
+		MsgCh:        make(chan opsmodels.Message),
+	}}
+	go func() {
+		for range ao.MsgCh {
+		}
+	}()
+	return ao.Apply(&ApplyRequest{Request: request(resources...)})
+}
+
+func destroy(storage states.StateStorage, resources ...models.Resource) status.Status {
+	do := &DestroyOperation{Operation: opsmodels.Operation{
+		Stack:        fakeStack,
+		StateStorage: storage,
+		MsgCh:        make(chan opsmodels.Message),
+	}}
+	go func() {
+		for range do.MsgCh {
+		}
+	}()
+	return do.Destroy(&DestroyRequest{Request: request(resources...)})
+}
+
+func TestEngine_Order(t *testing.T) {
+	rt := registerFakeRuntime(t)
+	storage := fakestate.NewStateStorage()
+	resources := []models.Resource{fakeResource("c", "b"), fakeResource("b", "a"), fakeResource("a")}
+
+	_, s := apply(storage, resources...)
+	assert.Nil(t, s)
+	assert.Equal(t, []string{"a", "b", "c"}, rt.CallsOf(fake.Apply))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, rt.Resources())
+
+	assert.Nil(t, destroy(storage, resources...))
+	assert.Equal(t, []string{"c", "b", "a"}, rt.CallsOf(fake.Delete))
+	assert.Empty(t, rt.Resources())
+}
+
+func TestEngine_Failure(t *testing.T) {
+	rt := registerFakeRuntime(t)
+	rt.FailOn(fake.Apply, "b", "quota exceeded", 1)
+	storage := fakestate.NewStateStorage()
+	resources := []models.Resource{fakeResource("a"), fakeResource("b", "a"), fakeResource("c", "b")}
+
+	_, s := apply(storage, resources...)
+	assert.True(t, status.IsErr(s))
+	assert.Contains(t, s.Message(), "quota exceeded")
+	assert.Equal(t, []string{"a", "b"}, rt.CallsOf(fake.Apply))
+
+	// only the applied resource is recorded, so the next apply resumes from it
+	latest, err := storage.GetLatestState(&states.StateQuery{Project: fakeProject.Name, Stack: fakeStack.Name})
+	assert.Nil(t, err)
+	assert.Equal(t, "a", latest.Resources[0].ID)
+	assert.Len(t, latest.Resources, 1)
+
+	_, s = apply(storage, resources...)
+	assert.Nil(t, s)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, rt.Resources())
+}
+
+func TestEngine_Concurrency(t *testing.T) {
+	rt := registerFakeRuntime(t)
+	rt.SetLatency(fake.Apply, 20*time.Millisecond)
+
+	_, s := apply(fakestate.NewStateStorage(), fakeResource("a"), fakeResource("b"), fakeResource("c"))
+	assert.Nil(t, s)
+	assert.Equal(t, 3, rt.MaxConcurrency())
+}
+
+func TestEngine_StateConflict(t *testing.T) {
+	registerFakeRuntime(t)
+	storage := fakestate.NewStateStorage().InjectConflict(1)
+
+	_, s := apply(storage, fakeResource("a"))
+	assert.True(t, status.IsErr(s))
+	assert.Contains(t, s.Message(), fakestate.ErrConflict.Error())
+	assert.Empty(t, storage.History(nil))
+}
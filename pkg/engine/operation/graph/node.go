@@ -30,3 +30,17 @@ func (r *RootNode) Hashcode() interface{} {
 func (r *RootNode) Name() string {
 	return "root"
 }
+
+// BarrierNode is a node which executes nothing. Nodes linked to it are executed before all nodes it links to, so that
+// dependencies between two groups of n and m nodes take n+m edges instead of n*m
+type BarrierNode struct {
+	ID string
+}
+
+func (b *BarrierNode) Hashcode() interface{} {
+	return b.ID
+}
+
+func (b *BarrierNode) Name() string {
+	return b.ID
+}
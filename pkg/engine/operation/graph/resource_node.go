@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 
+	"kusionstack.io/kusion/pkg/engine"
 	"kusionstack.io/kusion/pkg/engine/models"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
@@ -45,6 +46,10 @@ func (rn *ResourceNode) PreExecute(o *opsmodels.Operation) status.Status {
 	case opsmodels.Apply:
 		// replace secret ref and implicit ref
 		_, replaced, s = ReplaceRef(value, o.CtxResourceIndex, ImplicitReplaceFun, o.SecretStores, vals.ParseSecretRef)
+	case opsmodels.Destroy, opsmodels.DestroyPreview:
+		// attributes are not needed to delete resources, but runtimes may need credentials in extensions.
+		// Resources referred to are deleted after this one, so they are still in the prior state
+		return rn.resolveExtensions(o.PriorStateResourceIndex, o.SecretStores)
 	default:
 		return nil
 	}
@@ -54,6 +59,38 @@ func (rn *ResourceNode) PreExecute(o *opsmodels.Operation) status.Status {
 	if !replaced.IsZero() {
 		rn.resource.Attributes = replaced.Interface().(map[string]interface{})
 	}
+
+	resourceIndex := o.CtxResourceIndex
+	if o.OperationType == opsmodels.ApplyPreview && len(o.PriorStateResourceIndex) == 0 {
+		resourceIndex = nil
+	}
+	return rn.resolveExtensions(resourceIndex, o.SecretStores)
+}
+
+// resolveExtensions resolves secret and implicit references in extensions returned by engine.RefExtensions, and
+// saves them in ResolvedExtensions of the resource. Extensions are kept as they are, so that resolved values are not
+// saved in states. Implicit references are not resolved if resourceIndex is nil
+func (rn *ResourceNode) resolveExtensions(resourceIndex map[string]*models.Resource, ss *vals.SecretStores) status.Status {
+	var replaceFun func(map[string]*models.Resource, string) (reflect.Value, status.Status)
+	if resourceIndex != nil {
+		replaceFun = ImplicitReplaceFun
+	}
+
+	resolved := map[string]interface{}{}
+	for _, key := range engine.RefExtensions(rn.resource) {
+		v := rn.resource.Extensions[key]
+		if v == nil {
+			continue
+		}
+		_, replaced, s := ReplaceRef(reflect.ValueOf(v), resourceIndex, replaceFun, ss, vals.ParseSecretRef)
+		if status.IsErr(s) {
+			return s
+		}
+		resolved[key] = replaced.Interface()
+	}
+	if len(resolved) > 0 {
+		rn.resource.ResolvedExtensions = resolved
+	}
 	return nil
 }
 
@@ -72,7 +109,7 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 	}
 
 	// compute action type
-	dryRunResource, s := rn.computeActionType(operation, planedResource, priorResource, liveResource)
+	dryRunResource, change, s := rn.computeActionType(operation, planedResource, priorResource, liveResource)
 	if status.IsErr(s) {
 		return s
 	}
@@ -85,7 +122,7 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 		if e := operation.RefreshResourceIndex(key, dryRunResource, rn.Action); e != nil {
 			return status.NewErrorStatus(e)
 		}
-		updateChangeOrder(operation, rn, liveResource, dryRunResource)
+		updateChangeOrder(operation, rn, liveResource, dryRunResource, change)
 	case opsmodels.Apply, opsmodels.Destroy:
 		if s = rn.applyResource(operation, priorResource, planedResource, liveResource); status.IsErr(s) {
 			return s
@@ -98,14 +135,16 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 }
 
 // computeActionType compute ActionType of current resource node according to  planResource, priorResource and liveResource.
-// dryRunResource is a middle result during the process of computing ActionType. We will use it to perform live diff latter
+// dryRunResource is a middle result during the process of computing ActionType. We will use it to perform live diff latter,
+// and the change planned by the runtime in the dry run, if there is one, will be shown in previews
 func (rn *ResourceNode) computeActionType(
 	operation *opsmodels.Operation,
 	planedResource *models.Resource,
 	priorResource *models.Resource,
 	liveResource *models.Resource,
-) (*models.Resource, status.Status) {
+) (*models.Resource, *runtime.ResourceChange, status.Status) {
 	dryRunResource := planedResource
+	var change *runtime.ResourceChange
 	switch operation.OperationType {
 	case opsmodels.Destroy, opsmodels.DestroyPreview:
 		rn.Action = opsmodels.Delete
@@ -117,24 +156,31 @@ func (rn *ResourceNode) computeActionType(
 		} else {
 			// Dry run to fetch predictable resource
 			dryRunResp := operation.RuntimeMap[rn.resource.Type].Apply(context.Background(), &runtime.ApplyRequest{
-				PriorResource: priorResource,
-				PlanResource:  planedResource,
-				Stack:         operation.Stack,
-				DryRun:        true,
+				PriorResource:  priorResource,
+				PlanResource:   planedResource,
+				Stack:          operation.Stack,
+				Project:        operation.Project,
+				DryRun:         true,
+				ForceConflicts: operation.ForceConflicts,
 			})
 			if status.IsErr(dryRunResp.Status) {
-				return nil, dryRunResp.Status
+				return nil, nil, dryRunResp.Status
 			}
 			dryRunResource = dryRunResp.Resource
+			change = dryRunResp.Change
 			// Ignore differences of target fields
 			for _, field := range operation.IgnoreFields {
 				splits := strings.Split(field, ".")
 				removeNestedField(liveResource.Attributes, splits...)
 				removeNestedField(dryRunResource.Attributes, splits...)
+				if change != nil {
+					// unknown attributes are shown in previews, and ignored ones should not be
+					removeNestedField(change.AfterUnknown, splits...)
+				}
 			}
 			report, err := diff.ToReport(liveResource, dryRunResource)
 			if err != nil {
-				return nil, status.NewErrorStatus(err)
+				return nil, nil, status.NewErrorStatus(err)
 			}
 			if len(report.Diffs) == 0 {
 				rn.Action = opsmodels.UnChange
@@ -143,9 +189,9 @@ func (rn *ResourceNode) computeActionType(
 			}
 		}
 	default:
-		return nil, status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
+		return nil, nil, status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
 	}
-	return dryRunResource, nil
+	return dryRunResource, change, nil
 }
 
 func (rn *ResourceNode) initThreeWayDiffData(operation *opsmodels.Operation) (*models.Resource, *models.Resource, *models.Resource, status.Status) {
@@ -160,12 +206,19 @@ func (rn *ResourceNode) initThreeWayDiffData(operation *opsmodels.Operation) (*m
 	// 2. get prior resource which is stored in kusion_state.json
 	key := rn.resource.ResourceKey()
 	priorResource := operation.PriorStateResourceIndex[key]
+	// runtimes delete resources with prior resources, which are not resolved by PreExecute
+	if rn.Action == opsmodels.Delete && priorResource != nil && priorResource != rn.resource {
+		resolved := *priorResource
+		resolved.ResolvedExtensions = rn.resource.ResolvedExtensions
+		priorResource = &resolved
+	}
 
 	// 3. get the live resource from runtime
 	readRequest := &runtime.ReadRequest{
 		PlanResource:  planedResource,
 		PriorResource: priorResource,
 		Stack:         operation.Stack,
+		Project:       operation.Project,
 	}
 	resourceType := rn.resource.Type
 	response := operation.RuntimeMap[resourceType].Read(context.Background(), readRequest)
@@ -207,12 +260,22 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, prior, pla
 	rt := operation.RuntimeMap[resourceType]
 	switch rn.Action {
 	case opsmodels.Create, opsmodels.Update:
-		response := rt.Apply(context.Background(), &runtime.ApplyRequest{PriorResource: prior, PlanResource: planed, Stack: operation.Stack})
+		response := rt.Apply(context.Background(), &runtime.ApplyRequest{
+			PriorResource:  prior,
+			PlanResource:   planed,
+			Stack:          operation.Stack,
+			Project:        operation.Project,
+			ForceConflicts: operation.ForceConflicts,
+		})
 		res = response.Resource
 		s = response.Status
 		log.Debugf("apply resource:%s, response: %v", planed.ID, jsonutil.Marshal2String(response))
 	case opsmodels.Delete:
-		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: prior, Stack: operation.Stack})
+		response := rt.Delete(context.Background(), &runtime.DeleteRequest{
+			Resource: prior,
+			Project:  operation.Project,
+			Stack:    operation.Stack,
+		})
 		s = response.Status
 		if s != nil {
 			log.Debugf("delete resource:%s, resource: %v", planed.ID, s.String())
@@ -221,7 +284,7 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, prior, pla
 		log.Infof("planed resource and live resource are equal")
 		// auto import resources exist in spec and live cluster but no recorded in kusion_state.json
 		if prior == nil {
-			response := rt.Import(context.Background(), &runtime.ImportRequest{PlanResource: planed})
+			response := rt.Import(context.Background(), &runtime.ImportRequest{PlanResource: planed, Stack: operation.Stack})
 			s = response.Status
 			log.Debugf("import resource:%s, resource:%v", planed.ID, jsonutil.Marshal2String(s))
 			res = response.Resource
@@ -259,7 +322,7 @@ func NewResourceNode(key string, state *models.Resource, action opsmodels.Action
 }
 
 // save change steps in DAG walking order so that we can preview a full applying list
-func updateChangeOrder(ops *opsmodels.Operation, rn *ResourceNode, plan, live interface{}) {
+func updateChangeOrder(ops *opsmodels.Operation, rn *ResourceNode, plan, live interface{}, change *runtime.ResourceChange) {
 	defer ops.Lock.Unlock()
 	ops.Lock.Lock()
 
@@ -274,7 +337,9 @@ func updateChangeOrder(ops *opsmodels.Operation, rn *ResourceNode, plan, live in
 		order.ChangeSteps = make(map[string]*opsmodels.ChangeStep)
 	}
 	order.StepKeys = append(order.StepKeys, rn.ID)
-	order.ChangeSteps[rn.ID] = opsmodels.NewChangeStep(rn.ID, rn.Action, plan, live)
+	step := opsmodels.NewChangeStep(rn.ID, rn.Action, plan, live)
+	step.Change = change
+	order.ChangeSteps[rn.ID] = step
 }
 
 func ReplaceSecretRef(v reflect.Value, ss *vals.SecretStores) ([]string, reflect.Value, status.Status) {
@@ -3,12 +3,14 @@ package graph
 import (
 	"context"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
 	"bou.ke/monkey"
 	"github.com/stretchr/testify/assert"
 
+	"kusionstack.io/kusion/pkg/engine"
 	"kusionstack.io/kusion/pkg/engine/models"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
@@ -16,6 +18,8 @@ import (
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/engine/states/local"
 	"kusionstack.io/kusion/pkg/status"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+	"kusionstack.io/kusion/pkg/vals"
 	"kusionstack.io/kusion/third_party/terraform/dag"
 )
 
@@ -260,3 +264,83 @@ func Test_removeNestedField(t *testing.T) {
 		assert.Len(t, ports[0], 2)
 	})
 }
+
+func TestResourceNode_PreExecute_Extensions(t *testing.T) {
+	monkey.Patch(vals.ParseSecretRef, func(prefix, src string, ss *vals.SecretStores) (string, error) {
+		return "secret-" + strings.TrimPrefix(src, prefix), nil
+	})
+	defer monkey.UnpatchAll()
+
+	jack := &models.Resource{ID: "jack", Attributes: map[string]interface{}{"region": "us-east-1"}}
+	newResource := func() *models.Resource {
+		return &models.Resource{
+			ID:         "pony",
+			Type:       runtime.Terraform,
+			Attributes: map[string]interface{}{"a": "b"},
+			Extensions: map[string]interface{}{
+				engine.ProviderMetaExtension: map[string]interface{}{
+					"token":  "ref+vault://tf#token",
+					"region": ImplicitRefPrefix + "jack.region",
+				},
+				engine.RefExtensionsExtension: []interface{}{"kubeConfig"},
+				"kubeConfig":                  "ref+vault://k8s#config",
+				"other":                       "ref+vault://other",
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		operation opsmodels.Operation
+		want      map[string]interface{}
+	}{
+		{
+			name: "apply",
+			operation: opsmodels.Operation{
+				OperationType:    opsmodels.Apply,
+				CtxResourceIndex: map[string]*models.Resource{"jack": jack},
+				SecretStores:     &vals.SecretStores{},
+			},
+			want: map[string]interface{}{
+				engine.ProviderMetaExtension: map[string]interface{}{"token": "secret-tf#token", "region": "us-east-1"},
+				"kubeConfig":                 "secret-k8s#config",
+			},
+		},
+		{
+			name: "first preview",
+			operation: opsmodels.Operation{
+				OperationType:    opsmodels.ApplyPreview,
+				CtxResourceIndex: map[string]*models.Resource{},
+				SecretStores:     &vals.SecretStores{},
+			},
+			want: map[string]interface{}{
+				engine.ProviderMetaExtension: map[string]interface{}{"token": "secret-tf#token", "region": ImplicitRefPrefix + "jack.region"},
+				"kubeConfig":                 "secret-k8s#config",
+			},
+		},
+		{
+			name: "destroy",
+			operation: opsmodels.Operation{
+				OperationType:           opsmodels.Destroy,
+				CtxResourceIndex:        map[string]*models.Resource{},
+				PriorStateResourceIndex: map[string]*models.Resource{"jack": jack},
+				SecretStores:            &vals.SecretStores{},
+			},
+			want: map[string]interface{}{
+				engine.ProviderMetaExtension: map[string]interface{}{"token": "secret-tf#token", "region": "us-east-1"},
+				"kubeConfig":                 "secret-k8s#config",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResource()
+			rn := &ResourceNode{resource: res}
+			assert.Nil(t, rn.PreExecute(&tt.operation))
+			assert.Equal(t, tt.want, res.ResolvedExtensions)
+			// extensions saved in states are not resolved
+			assert.Equal(t, newResource().Extensions, res.Extensions)
+			assert.NotContains(t, jsonutil.Marshal2String(res), "secret-")
+		})
+	}
+}
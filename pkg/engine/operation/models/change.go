@@ -2,14 +2,17 @@ package models
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/pterm/pterm"
 
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/util/diff"
@@ -25,13 +28,28 @@ type ChangeStep struct {
 	From interface{} `json:"from,omitempty" yaml:"from,omitempty"`
 	// new data
 	To interface{} `json:"to,omitempty" yaml:"to,omitempty"`
+	// the change planned by the runtime, which is optional
+	Change *runtime.ResourceChange `json:"change,omitempty" yaml:"change,omitempty"`
 }
 
+// Placeholders of attributes in diffs of change steps with changes planned by runtimes
+const (
+	UnknownValue          = "(known after apply)"
+	SensitiveValue        = "(sensitive)"
+	ChangedSensitiveValue = "(sensitive, changed)"
+)
+
 // Diff compares objects(from and to) which stores in ChangeStep,
-// and return a human-readable string report.
+// and return a human-readable string report. If the runtime plans the change, attributes unknown until apply and
+// sensitive attributes are replaced by placeholders, and planned replacements are shown
 func (cs *ChangeStep) Diff() (string, error) {
+	from, to := cs.From, cs.To
+	if cs.Change != nil {
+		from, to = cs.maskedData()
+	}
+
 	// Generate diff report
-	diffReport, err := diff.ToReport(cs.From, cs.To)
+	diffReport, err := diff.ToReport(from, to)
 	if err != nil {
 		log.Errorf("failed to compute diff with ChangeStep ID: %s", cs.ID)
 		return "", err
@@ -53,6 +71,12 @@ func (cs *ChangeStep) Diff() (string, error) {
 		buf.WriteString(pretty.GreenBold("Plan: "))
 		buf.WriteString(pterm.Sprintf("%s\n", cs.Action.PrettyString()))
 	}
+	if cs.Change != nil {
+		for _, r := range cs.Change.Replacements {
+			buf.WriteString(pretty.GreenBold("Replace: "))
+			buf.WriteString(pretty.Yellow("%s\n", replacementString(r)))
+		}
+	}
 	buf.WriteString(pretty.GreenBold("Diff: "))
 	if len(strings.TrimSpace(reportString)) == 0 && cs.Action == UnChange {
 		buf.WriteString(pretty.Gray("<EMPTY>"))
@@ -72,6 +96,165 @@ func NewChangeStep(id string, op ActionType, from, to interface{}) *ChangeStep {
 	}
 }
 
+// MarshalJSON masks from and to data like Diff if the runtime plans the change, so that sensitive attributes are not
+// shown in outputs like `kusion preview -o json`
+func (cs ChangeStep) MarshalJSON() ([]byte, error) {
+	// changeStep has no methods, which keeps json.Marshal from calling MarshalJSON again
+	type changeStep ChangeStep
+	masked := changeStep(cs)
+	if cs.Change != nil {
+		masked.From, masked.To = cs.maskedData()
+	}
+	return json.Marshal(masked)
+}
+
+// maskedData returns from and to data, whose sensitive attributes and attributes unknown until apply are replaced by
+// placeholders. Sensitive attributes changed by the step are replaced by ChangedSensitiveValue, so the changes are
+// still shown without their values. Data other than resources are returned as they are
+func (cs *ChangeStep) maskedData() (interface{}, interface{}) {
+	before := attributesOf(cs.From)
+	after := attributesOf(cs.To)
+
+	maskedBefore := maskValue(before, nil, cs.Change.BeforeSensitive, func(v, _ interface{}) interface{} {
+		if v == nil {
+			return nil
+		}
+		return SensitiveValue
+	})
+	maskedAfter := maskValue(after, before, cs.Change.AfterSensitive, func(v, old interface{}) interface{} {
+		if v == nil {
+			return nil
+		}
+		if reflect.DeepEqual(v, old) {
+			return SensitiveValue
+		}
+		return ChangedSensitiveValue
+	})
+	maskedAfter = maskValue(maskedAfter, nil, cs.Change.AfterUnknown, func(_, _ interface{}) interface{} {
+		return UnknownValue
+	})
+	return withAttributes(cs.From, maskedBefore), withAttributes(cs.To, maskedAfter)
+}
+
+func attributesOf(data interface{}) map[string]interface{} {
+	if r, ok := data.(*models.Resource); ok && r != nil {
+		return r.Attributes
+	}
+	return nil
+}
+
+// withAttributes returns a copy of the resource with the attributes, or the data itself if it is not a resource
+func withAttributes(data interface{}, attributes interface{}) interface{} {
+	r, ok := data.(*models.Resource)
+	if !ok || r == nil {
+		return data
+	}
+	masked := *r
+	masked.Attributes, _ = attributes.(map[string]interface{})
+	return &masked
+}
+
+// maskValue returns a copy of the value, in which values whose masks are true are replaced. The mask has the
+// structure of the value, and old is the value to compare with. Values are not copied if nothing is replaced in them,
+// and absent values are only replaced if the replacements are not nil
+func maskValue(value, old, mask interface{}, replace func(value, old interface{}) interface{}) interface{} {
+	switch m := mask.(type) {
+	case bool:
+		if m {
+			return replace(value, old)
+		}
+	case map[string]interface{}:
+		values, _ := value.(map[string]interface{})
+		olds, _ := old.(map[string]interface{})
+		var masked map[string]interface{}
+		for k, km := range m {
+			if !hasTrue(km) {
+				continue
+			}
+			v := maskValue(values[k], olds[k], km, replace)
+			if _, ok := values[k]; !ok && v == nil {
+				continue
+			}
+			if masked == nil {
+				masked = make(map[string]interface{}, len(values)+1)
+				for vk, vv := range values {
+					masked[vk] = vv
+				}
+			}
+			masked[k] = v
+		}
+		if masked != nil {
+			return masked
+		}
+	case []interface{}:
+		values, _ := value.([]interface{})
+		olds, _ := old.([]interface{})
+		var masked []interface{}
+		for i, im := range m {
+			if !hasTrue(im) {
+				continue
+			}
+			var v, o interface{}
+			if i < len(values) {
+				v = values[i]
+			}
+			if i < len(olds) {
+				o = olds[i]
+			}
+			mv := maskValue(v, o, im, replace)
+			if i >= len(values) && mv == nil {
+				continue
+			}
+			if masked == nil {
+				masked = append([]interface{}(nil), values...)
+			}
+			for len(masked) <= i {
+				masked = append(masked, nil)
+			}
+			masked[i] = mv
+		}
+		if masked != nil {
+			return masked
+		}
+	}
+	return value
+}
+
+// hasTrue returns true if any leaf of the mask is true
+func hasTrue(mask interface{}) bool {
+	switch m := mask.(type) {
+	case bool:
+		return m
+	case map[string]interface{}:
+		for _, v := range m {
+			if hasTrue(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range m {
+			if hasTrue(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func replacementString(r runtime.Replacement) string {
+	s := "replaced"
+	if r.Reason != "" {
+		s = r.Reason
+	}
+	if r.Path != "" {
+		s = r.Path + ": " + s
+	}
+	if len(r.ForcedBy) > 0 {
+		s += ", forced by " + strings.Join(r.ForcedBy, ", ")
+	}
+	return s
+}
+
 type ChangeStepFilterFunc func(*ChangeStep) bool
 
 var (
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"os"
 	"reflect"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
@@ -146,6 +148,78 @@ func TestChangeStep_Diff(t *testing.T) {
 	}
 }
 
+func TestChangeStep_Diff_Change(t *testing.T) {
+	from := &models.Resource{ID: "id", Attributes: map[string]interface{}{
+		"ami":      "ami-1",
+		"password": "foo",
+		"token":    "bar",
+		"tags":     map[string]interface{}{"env": "dev"},
+	}}
+	to := &models.Resource{ID: "id", Attributes: map[string]interface{}{
+		"ami":      "ami-2",
+		"password": "foo",
+		"token":    "baz",
+		"tags":     map[string]interface{}{"env": "dev"},
+	}}
+	cs := NewChangeStep("id", Update, from, to)
+	cs.Change = &runtime.ResourceChange{
+		Actions:         []string{"delete", "create"},
+		AfterUnknown:    map[string]interface{}{"arn": true, "tags": map[string]interface{}{"owner": true}, "ebs": []interface{}{false}},
+		BeforeSensitive: map[string]interface{}{"password": true, "token": true, "secret": true},
+		AfterSensitive:  map[string]interface{}{"password": true, "token": true, "secret": true},
+		Replacements:    []runtime.Replacement{{Reason: "attributes can't be updated in place", ForcedBy: []string{"ami"}}},
+	}
+
+	maskedFrom, maskedTo := cs.maskedData()
+	assert.Equal(t, map[string]interface{}{
+		"ami":      "ami-1",
+		"password": SensitiveValue,
+		"token":    SensitiveValue,
+		"tags":     map[string]interface{}{"env": "dev"},
+	}, maskedFrom.(*models.Resource).Attributes)
+	assert.Equal(t, map[string]interface{}{
+		"ami":      "ami-2",
+		"arn":      UnknownValue,
+		"password": SensitiveValue,
+		"token":    ChangedSensitiveValue,
+		"tags":     map[string]interface{}{"env": "dev", "owner": UnknownValue},
+	}, maskedTo.(*models.Resource).Attributes)
+	// the data of the step are not modified
+	assert.Equal(t, "baz", to.Attributes["token"])
+	assert.NotContains(t, to.Attributes["tags"], "owner")
+
+	got, err := cs.Diff()
+	assert.Nil(t, err)
+	assert.Contains(t, got, "attributes can't be updated in place, forced by ami")
+	assert.Contains(t, got, UnknownValue)
+	assert.Contains(t, got, ChangedSensitiveValue)
+	assert.NotContains(t, got, "baz")
+}
+
+func TestChangeStep_MarshalJSON(t *testing.T) {
+	from := &models.Resource{ID: "id", Attributes: map[string]interface{}{"ami": "ami-1", "password": "foo"}}
+	to := &models.Resource{ID: "id", Attributes: map[string]interface{}{"ami": "ami-2", "password": "bar"}}
+	cs := NewChangeStep("id", Update, from, to)
+	cs.Change = &runtime.ResourceChange{
+		BeforeSensitive: map[string]interface{}{"password": true},
+		AfterSensitive:  map[string]interface{}{"password": true},
+	}
+
+	data, err := json.Marshal(&ChangeOrder{StepKeys: []string{"id"}, ChangeSteps: map[string]*ChangeStep{"id": cs}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"ami":"ami-2"`)
+	assert.Contains(t, string(data), ChangedSensitiveValue)
+	assert.NotContains(t, string(data), "foo")
+	assert.NotContains(t, string(data), "bar")
+	// the step is not modified
+	assert.Equal(t, "bar", to.Attributes["password"])
+
+	// steps without changes planned by runtimes are marshaled as they are
+	data, err = json.Marshal(NewChangeStep("id", Update, from, to))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"password":"bar"`)
+}
+
 func TestChanges_Get(t *testing.T) {
 	type fields struct {
 		order   *ChangeOrder
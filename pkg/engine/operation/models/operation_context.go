@@ -45,6 +45,12 @@ type Operation struct {
 	// Stack contains info about where this command is invoked
 	Stack *projectstack.Stack
 
+	// Project contains configs of the project that resources belong to
+	Project *projectstack.Project
+
+	// ForceConflicts means taking over fields owned by others when applying resources
+	ForceConflicts bool
+
 	// MsgCh is used to send operation status like Success, Failed or Skip to Kusion CTl,
 	// and this message will be displayed in the terminal
 	MsgCh chan Message
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util"
+	"kusionstack.io/kusion/third_party/terraform/dag"
+)
+
+// DefaultKindOrder applies Namespaces and CRDs first, then RBAC, ServiceAccounts, ConfigMaps and Secrets,
+// and workloads and other kinds at last
+var DefaultKindOrder = [][]string{
+	{"Namespace", "CustomResourceDefinition"},
+	{"ServiceAccount", "Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding", "ConfigMap", "Secret"},
+}
+
+// KindOrderParser adds implicit dependencies among Kubernetes resources in the same cluster by their kinds.
+// Kinds in former groups are applied before kinds in latter groups, and kinds not in any group are applied at last.
+// The order is reversed for deleted resources. Dependencies conflicting with explicit ones are never added
+type KindOrderParser struct {
+	order [][]string
+}
+
+func NewKindOrderParser(order [][]string) *KindOrderParser {
+	return &KindOrderParser{order: order}
+}
+
+var _ Parser = (*KindOrderParser)(nil)
+
+func (k *KindOrderParser) Parse(g *dag.AcyclicGraph) (s status.Status) {
+	util.CheckNotNil(g, "dag is nil")
+	if len(k.order) == 0 {
+		return nil
+	}
+	ranks := make(map[string]int)
+	for i, kinds := range k.order {
+		for _, kind := range kinds {
+			ranks[kind] = i
+		}
+	}
+
+	// group nodes by cluster, action and rank
+	type group struct {
+		cluster string
+		delete  bool
+	}
+	tiers := make(map[group][][]*graph.ResourceNode)
+	for _, v := range g.Vertices() {
+		rn, ok := v.(*graph.ResourceNode)
+		if !ok || rn.State() == nil || rn.State().Type != runtime.Kubernetes {
+			continue
+		}
+		res := rn.State()
+		cluster, _ := res.Extensions[engine.ClusterExtension].(string)
+		key := group{cluster: cluster, delete: rn.Action == opsmodels.Delete}
+		if tiers[key] == nil {
+			tiers[key] = make([][]*graph.ResourceNode, len(k.order)+1)
+		}
+		rank, ok := ranks[(&unstructured.Unstructured{Object: res.Attributes}).GetKind()]
+		if !ok {
+			rank = len(k.order)
+		}
+		tiers[key][rank] = append(tiers[key][rank], rn)
+	}
+
+	// link each tier to the nearest former tier which is not empty through a barrier node
+	for key, ts := range tiers {
+		action := "apply"
+		if key.delete {
+			action = "delete"
+		}
+		var prev []*graph.ResourceNode
+		for i, cur := range ts {
+			if len(cur) == 0 {
+				continue
+			}
+			if len(prev) > 0 {
+				before, after := prev, cur
+				if key.delete {
+					before, after = cur, prev
+				}
+				id := fmt.Sprintf("kind-order:%s:%d", action, i)
+				if key.cluster != "" {
+					id += "@" + key.cluster
+				}
+				barrier := &graph.BarrierNode{ID: id}
+				if err := linkTiers(g, barrier, before, after); err != nil {
+					return status.NewErrorStatus(err)
+				}
+			}
+			prev = cur
+		}
+	}
+
+	if err := g.Validate(); err != nil {
+		return status.NewErrorStatusWithMsg(status.IllegalManifest, "Found circle dependency in models:"+err.Error())
+	}
+	g.TransitiveReduction()
+	return nil
+}
+
+// linkTiers links nodes in before to the barrier, and the barrier to nodes in after, so that nodes in after are
+// executed after all nodes in before. Nodes in before which are reachable from nodes in after already have explicit
+// dependencies conflicting with the order, so they are linked to nodes in after one by one, skipping edges making
+// cycles. Reachability is walked once for the whole tier
+func linkTiers(g *dag.AcyclicGraph, barrier dag.Vertex, before, after []*graph.ResourceNode) error {
+	start := make(dag.Set)
+	for _, a := range after {
+		start.Add(a)
+	}
+	reachable := make(dag.Set)
+	if err := g.DepthFirstWalk(start, func(v dag.Vertex, _ int) error {
+		reachable.Add(v)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("walk dag failed: %v", err)
+	}
+
+	var linked []*graph.ResourceNode
+	for _, b := range before {
+		if !reachable.Include(b) {
+			linked = append(linked, b)
+			continue
+		}
+		for _, a := range after {
+			if err := linkIfAcyclic(g, b, a); err != nil {
+				return err
+			}
+		}
+	}
+	// a barrier without nodes linked to it would be another root of the dag
+	if len(linked) == 0 {
+		return nil
+	}
+	g.Add(barrier)
+	for _, b := range linked {
+		g.Connect(dag.BasicEdge(b, barrier))
+	}
+	for _, a := range after {
+		g.Connect(dag.BasicEdge(barrier, a))
+	}
+	return nil
+}
+
+// linkIfAcyclic adds an edge from the node to another unless the edge would make a cycle with existing edges
+func linkIfAcyclic(g *dag.AcyclicGraph, from, to dag.Vertex) error {
+	reachable, err := g.Ancestors(to)
+	if err != nil {
+		return fmt.Errorf("walk dag failed: %v", err)
+	}
+	if reachable.Include(from) {
+		return nil
+	}
+	g.Connect(dag.BasicEdge(from, to))
+	return nil
+}
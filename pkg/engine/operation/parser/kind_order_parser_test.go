@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/third_party/terraform/dag"
+)
+
+func newKubernetesResource(id, kind string, dependsOn ...string) models.Resource {
+	return models.Resource{
+		ID:         id,
+		Type:       runtime.Kubernetes,
+		Attributes: map[string]interface{}{"kind": kind},
+		DependsOn:  dependsOn,
+	}
+}
+
+func TestKindOrderParser_Parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []models.Resource
+		delete    bool
+		expected  string
+	}{
+		{
+			name: "apply",
+			resources: []models.Resource{
+				newKubernetesResource("deploy", "Deployment"),
+				newKubernetesResource("cm", "ConfigMap"),
+				newKubernetesResource("ns", "Namespace"),
+				newKubernetesResource("cr", "Foo"),
+				newKubernetesResource("crd", "CustomResourceDefinition"),
+				{ID: "tf", Type: runtime.Terraform, Attributes: map[string]interface{}{"kind": "Namespace"}},
+			},
+			expected: testKindOrderApply,
+		},
+		{
+			name: "delete",
+			resources: []models.Resource{
+				newKubernetesResource("deploy", "Deployment"),
+				newKubernetesResource("ns", "Namespace"),
+			},
+			delete:   true,
+			expected: testKindOrderDelete,
+		},
+		{
+			name: "explicit dependencies",
+			resources: []models.Resource{
+				newKubernetesResource("deploy", "Deployment"),
+				newKubernetesResource("ns", "Namespace", "deploy"),
+			},
+			expected: testKindOrderExplicit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag := &dag.AcyclicGraph{}
+			ag.Add(&graph.RootNode{})
+			if tt.delete {
+				_ = NewDeleteResourceParser(tt.resources).Parse(ag)
+			} else {
+				_ = NewSpecParser(&models.Spec{Resources: tt.resources}).Parse(ag)
+			}
+
+			_ = NewKindOrderParser(DefaultKindOrder).Parse(ag)
+			actual := strings.TrimSpace(ag.String())
+			expected := strings.TrimSpace(tt.expected)
+			if actual != expected {
+				t.Errorf("wrong result\ngot:\n%s\n\nwant:\n%s", actual, expected)
+			}
+		})
+	}
+}
+
+const testKindOrderApply = `
+cm
+  kind-order:apply:2
+cr
+crd
+  kind-order:apply:1
+deploy
+kind-order:apply:1
+  cm
+kind-order:apply:2
+  cr
+  deploy
+ns
+  kind-order:apply:1
+root
+  crd
+  ns
+  tf
+tf
+`
+
+const testKindOrderDelete = `
+deploy
+  kind-order:delete:2
+kind-order:delete:2
+  ns
+ns
+root
+  deploy
+`
+
+const testKindOrderExplicit = `
+deploy
+  ns
+ns
+root
+  deploy
+`
+
+func TestKindOrderParser_ParseLinear(t *testing.T) {
+	// tiers are linked through barriers, so edges grow linearly with resources instead of quadratically
+	var resources []models.Resource
+	for i := 0; i < 200; i++ {
+		resources = append(resources,
+			newKubernetesResource(fmt.Sprintf("cm-%d", i), "ConfigMap"),
+			newKubernetesResource(fmt.Sprintf("deploy-%d", i), "Deployment"),
+		)
+	}
+	// the explicit dependency conflicts with the order, so cm-0 is not linked to the barrier
+	resources[0].DependsOn = []string{"deploy-0"}
+
+	ag := &dag.AcyclicGraph{}
+	ag.Add(&graph.RootNode{})
+	_ = NewSpecParser(&models.Spec{Resources: resources}).Parse(ag)
+	if s := NewKindOrderParser(DefaultKindOrder).Parse(ag); s != nil {
+		t.Fatalf("parse failed: %v", s)
+	}
+	if n := len(ag.Edges()); n > 1000 {
+		t.Errorf("too many edges: %d", n)
+	}
+
+	barrier := GetVertex(ag, &graph.BarrierNode{ID: "kind-order:apply:2"})
+	cm0, _ := graph.NewResourceNode("cm-0", nil, opsmodels.Create)
+	cm1, _ := graph.NewResourceNode("cm-1", nil, opsmodels.Create)
+	deploy0, _ := graph.NewResourceNode("deploy-0", nil, opsmodels.Create)
+	if !ag.HasEdge(dag.BasicEdge(GetVertex(ag, cm1), barrier)) || !ag.HasEdge(dag.BasicEdge(barrier, GetVertex(ag, deploy0))) {
+		t.Errorf("cm-1 is not linked to deploy-0 through the barrier")
+	}
+	if ag.HasEdge(dag.BasicEdge(GetVertex(ag, cm0), barrier)) {
+		t.Errorf("cm-0 is linked to the barrier")
+	}
+}
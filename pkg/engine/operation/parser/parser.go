@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"kusionstack.io/kusion/pkg/engine"
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation/graph"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
@@ -19,15 +20,23 @@ func updateDependencies(resource *models.Resource) ([]string, status.Status) {
 	// handle explicate dependency
 	refNodeKeys := resource.DependsOn
 
-	// handle implicit dependency
-	v := reflect.ValueOf(resource.Attributes)
-	implicitRefKeys, _, s := graph.ReplaceImplicitRef(v, nil, func(map[string]*models.Resource, string) (reflect.Value, status.Status) {
-		return v, nil
-	})
-	if status.IsErr(s) {
-		return nil, s
+	// handle implicit dependency in attributes and extensions whose references are resolved
+	values := []interface{}{resource.Attributes}
+	for _, key := range engine.RefExtensions(resource) {
+		if ext := resource.Extensions[key]; ext != nil {
+			values = append(values, ext)
+		}
+	}
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		implicitRefKeys, _, s := graph.ReplaceImplicitRef(v, nil, func(map[string]*models.Resource, string) (reflect.Value, status.Status) {
+			return v, nil
+		})
+		if status.IsErr(s) {
+			return nil, s
+		}
+		refNodeKeys = append(refNodeKeys, implicitRefKeys...)
 	}
-	refNodeKeys = append(refNodeKeys, implicitRefKeys...)
 
 	// Deduplicate
 	refNodeKeys = Deduplicate(refNodeKeys)
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+)
+
+func Test_updateDependencies(t *testing.T) {
+	res := &models.Resource{
+		ID:         "eric",
+		Attributes: map[string]interface{}{"a": graph.ImplicitRefPrefix + "jack.a"},
+		DependsOn:  []string{"pony"},
+		Extensions: map[string]interface{}{
+			engine.ProviderMetaExtension:  map[string]interface{}{"region": graph.ImplicitRefPrefix + "vpc.region"},
+			engine.RefExtensionsExtension: []interface{}{"kubeConfig"},
+			"kubeConfig":                  graph.ImplicitRefPrefix + "cluster.kubeConfig",
+			"other":                       graph.ImplicitRefPrefix + "other.a",
+		},
+	}
+	keys, s := updateDependencies(res)
+	assert.Nil(t, s)
+	assert.ElementsMatch(t, []string{"pony", "jack", "vpc", "cluster"}, keys)
+	assert.Equal(t, keys, res.DependsOn)
+}
@@ -64,20 +64,28 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 	// Kusion is a multi-runtime system. We initialize runtimes dynamically by resource types
 	resources := request.Spec.Resources
 	resources = append(resources, priorState.Resources...)
-	runtimesMap, s := runtimeinit.Runtimes(resources)
-	if status.IsErr(s) {
-		return nil, s
+	// runtimes provided by the caller take precedence over the ones initialized by resource types
+	if o.RuntimeMap == nil {
+		runtimesMap, s := runtimeinit.Runtimes(request.Project, resources)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		o.RuntimeMap = runtimesMap
+		// close runtimes initialized by this operation, such as runtime plugins, when it ends
+		defer func() {
+			runtimeinit.Close(runtimesMap)
+			o.RuntimeMap = nil
+		}()
 	}
-	o.RuntimeMap = runtimesMap
 
 	switch o.OperationType {
 	case opsmodels.ApplyPreview:
 		priorStateResourceIndex = priorState.Resources.Index()
-		ag, s = NewApplyGraph(request.Spec, priorState)
+		ag, s = NewApplyGraph(request.Spec, priorState, KindOrder(request.Project))
 	case opsmodels.DestroyPreview:
 		resources := request.Request.Spec.Resources
 		priorStateResourceIndex = resources.Index()
-		ag, s = NewDestroyGraph(resources)
+		ag, s = NewDestroyGraph(resources, KindOrder(request.Project))
 	}
 	if status.IsErr(s) {
 		return nil, s
@@ -102,6 +110,8 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 			ChangeOrder:             o.ChangeOrder,
 			RuntimeMap:              o.RuntimeMap,
 			Stack:                   o.Stack,
+			Project:                 request.Project,
+			ForceConflicts:          o.ForceConflicts,
 			ResultState:             resultState,
 			Lock:                    &sync.Mutex{},
 			SecretStores:            o.SecretStores,
@@ -264,7 +264,7 @@ func TestOperation_Preview(t *testing.T) {
 				},
 			}
 
-			monkey.Patch(runtimeinit.Runtimes, func(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+			monkey.Patch(runtimeinit.Runtimes, func(_ *projectstack.Project, resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
 				return map[models.Type]runtime.Runtime{runtime.Kubernetes: &fakePreviewRuntime{}}, nil
 			})
 			gotRsp, gotS := o.Preview(tt.args.request)
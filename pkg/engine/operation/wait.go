@@ -0,0 +1,122 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine/health"
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const DefaultWaitInterval = 2 * time.Second
+
+// WaitOperation polls live resources until all of them are ready. Kubernetes resources are ready when their health
+// is Current, and resources of other runtimes are ready as soon as they exist
+type WaitOperation struct {
+	opsmodels.Operation
+
+	// Interval is the polling interval. DefaultWaitInterval is used if it is zero
+	Interval time.Duration
+}
+
+type WaitRequest struct {
+	opsmodels.Request `json:",inline" yaml:",inline"`
+}
+
+// WaitResult is the health of one resource
+type WaitResult struct {
+	ResourceID    string `json:"resourceID" yaml:"resourceID"`
+	health.Result `json:",inline" yaml:",inline"`
+}
+
+// Wait polls resources in the request until all of them are Current, any of them is Failed, or ctx is done.
+// The latest results of all resources are returned in the order of the Spec, and the error is not nil unless all
+// resources are Current
+func (wo *WaitOperation) Wait(ctx context.Context, req *WaitRequest) ([]*WaitResult, error) {
+	resources := req.Spec.Resources
+	if wo.RuntimeMap == nil {
+		runtimes, s := runtimeinit.Runtimes(req.Project, resources)
+		if status.IsErr(s) {
+			return nil, errors.New(s.Message())
+		}
+		wo.RuntimeMap = runtimes
+		// close runtimes initialized by this operation, such as runtime plugins, when it ends
+		defer func() {
+			runtimeinit.Close(runtimes)
+			wo.RuntimeMap = nil
+		}()
+	}
+	interval := wo.Interval
+	if interval <= 0 {
+		interval = DefaultWaitInterval
+	}
+
+	results := make([]*WaitResult, len(resources))
+	for i := range resources {
+		results[i] = &WaitResult{
+			ResourceID: resources[i].ResourceKey(),
+			Result:     health.Result{Status: health.Unknown, Message: "not checked yet"},
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ready := true
+		for i := range resources {
+			if results[i].Status == health.Current {
+				continue
+			}
+			results[i].Result = *wo.check(ctx, &resources[i], &req.Request)
+			log.Debugf("health of %s: %s %s", results[i].ResourceID, results[i].Status, results[i].Message)
+
+			switch results[i].Status {
+			case health.Current:
+			case health.Failed:
+				return results, fmt.Errorf("resource %s failed: %s", results[i].ResourceID, results[i].Message)
+			default:
+				ready = false
+			}
+		}
+		if ready {
+			return results, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return results, fmt.Errorf("resources are not ready: %w", ctx.Err())
+		}
+	}
+}
+
+// check reads the live resource and computes its health
+func (wo *WaitOperation) check(ctx context.Context, res *models.Resource, req *opsmodels.Request) *health.Result {
+	rt, ok := wo.RuntimeMap[res.Type]
+	if !ok {
+		return &health.Result{Status: health.Unknown, Message: fmt.Sprintf("no runtime found for resource type: %s", res.Type)}
+	}
+	resp := rt.Read(ctx, &runtime.ReadRequest{PlanResource: res, Stack: req.Stack, Project: req.Project})
+	if resp == nil {
+		return &health.Result{Status: health.Unknown, Message: "empty read response"}
+	}
+	if status.IsErr(resp.Status) {
+		return &health.Result{Status: health.Unknown, Message: resp.Status.Message()}
+	}
+	if resp.Resource == nil {
+		return health.Compute(nil)
+	}
+	if res.Type != runtime.Kubernetes {
+		return &health.Result{Status: health.Current}
+	}
+	return health.Compute(&unstructured.Unstructured{Object: resp.Resource.Attributes})
+}
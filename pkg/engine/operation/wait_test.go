@@ -0,0 +1,91 @@
+package operation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/health"
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+// readRuntime returns the live objects in order for each Read
+type readRuntime struct {
+	fooWatchRuntime
+	objects []map[string]interface{}
+}
+
+func (r *readRuntime) Read(_ context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	obj := r.objects[0]
+	if len(r.objects) > 1 {
+		r.objects = r.objects[1:]
+	}
+	if obj == nil {
+		return &runtime.ReadResponse{}
+	}
+	return &runtime.ReadResponse{Resource: &models.Resource{ID: request.PlanResource.ID, Attributes: obj}}
+}
+
+func deploymentWithStatus(s map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"namespace": "foo", "name": "bar"},
+		"spec":       map[string]interface{}{"replicas": int64(1)},
+		"status":     s,
+	}
+}
+
+func TestWaitOperation_Wait(t *testing.T) {
+	req := &WaitRequest{Request: opsmodels.Request{Spec: &models.Spec{Resources: models.Resources{
+		{ID: "apps/v1:Deployment:foo:bar", Type: runtime.Kubernetes, Attributes: barDeployment},
+	}}}}
+	ready := deploymentWithStatus(map[string]interface{}{
+		"replicas": int64(1), "updatedReplicas": int64(1), "readyReplicas": int64(1), "availableReplicas": int64(1),
+	})
+	progressing := deploymentWithStatus(map[string]interface{}{"replicas": int64(1)})
+	failed := deploymentWithStatus(map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{
+			"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded",
+		}},
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		rt := &readRuntime{objects: []map[string]interface{}{nil, progressing, ready}}
+		wo := &WaitOperation{
+			Operation: opsmodels.Operation{RuntimeMap: map[models.Type]runtime.Runtime{runtime.Kubernetes: rt}},
+			Interval:  time.Millisecond,
+		}
+		results, err := wo.Wait(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Equal(t, health.Current, results[0].Status)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		rt := &readRuntime{objects: []map[string]interface{}{progressing, failed}}
+		wo := &WaitOperation{
+			Operation: opsmodels.Operation{RuntimeMap: map[models.Type]runtime.Runtime{runtime.Kubernetes: rt}},
+			Interval:  time.Millisecond,
+		}
+		results, err := wo.Wait(context.Background(), req)
+		assert.NotNil(t, err)
+		assert.Equal(t, health.Failed, results[0].Status)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		rt := &readRuntime{objects: []map[string]interface{}{progressing}}
+		wo := &WaitOperation{
+			Operation: opsmodels.Operation{RuntimeMap: map[models.Type]runtime.Runtime{runtime.Kubernetes: rt}},
+			Interval:  time.Millisecond,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		results, err := wo.Wait(ctx, req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, health.InProgress, results[0].Status)
+	})
+}
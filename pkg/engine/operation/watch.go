@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"reflect"
+	"sync"
 	"time"
 
 	"github.com/howieyuen/uilive"
@@ -30,42 +30,39 @@ type WatchRequest struct {
 	opsmodels.Request `json:",inline" yaml:",inline"`
 }
 
+// WatchEvent is an event of one object watched for a resource
+type WatchEvent struct {
+	// ResourceID is the key of the watched resource in the Spec
+	ResourceID string
+
+	// ObjectID is the ID of the object this event belongs to. It is the resource itself or one of its dependents,
+	// such as the ReplicaSets and Pods of a Deployment
+	ObjectID string
+
+	// Row is the detail of this event. Its Type is printers.READY when the object is ready
+	Row *printers.Row
+}
+
+// Watch watches resources in the request and renders their status in the terminal until all of them are ready
 func (wo *WatchOperation) Watch(req *WatchRequest) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// init runtimes
 	resources := req.Spec.Resources
-	runtimes, s := runtimeinit.Runtimes(resources)
-	if status.IsErr(s) {
-		return errors.New(s.Message())
+	watchers, err := wo.StartWatchers(ctx, req)
+	if err != nil {
+		return err
 	}
-	wo.RuntimeMap = runtimes
 
-	// Result channels
-	msgChs := make(map[string]*runtime.SequentialWatchers, len(resources))
 	// Keep sorted
 	ids := make([]string, resources.Len())
-	// Collect watchers
+	// Table data, resources without watchers have no table
+	tables := make(map[string]*printers.Table, len(watchers))
 	for i := range resources {
-		res := &resources[i]
-		t := res.Type
-
-		// Save id first, might have TF resources
-		ids[i] = res.ResourceKey()
-
-		// Get watchers, only support k8s resources
-		resp := runtimes[t].Watch(ctx, &runtime.WatchRequest{Resource: res})
-		if resp == nil {
-			log.Debug("unsupported resource type: %s", t)
-			continue
-		}
-		if status.IsErr(resp.Status) {
-			return fmt.Errorf(resp.Status.String())
+		ids[i] = resources[i].ResourceKey()
+		if sw, ok := watchers[ids[i]]; ok {
+			tables[ids[i]] = printers.NewTable(sw.IDs)
 		}
-
-		// Save watchers
-		msgChs[res.ResourceKey()] = resp.Watchers
 	}
 
 	// Console writer
@@ -74,112 +71,189 @@ func (wo *WatchOperation) Watch(req *WatchRequest) error {
 	writer.Start()
 	defer writer.Stop()
 
-	// Table data
-	tables := make(map[string]*printers.Table, len(ids))
+	// No watchable resources
+	if len(tables) == 0 {
+		PrintTables(writer, ids, tables)
+		return nil
+	}
+
+	// Receive events in background and render tables every 1s
+	var mu sync.Mutex
+	done := make(chan error, 1)
+	go func() {
+		done <- wo.ReceiveEvents(ctx, watchers, func(e *WatchEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			tables[e.ResourceID].Update(e.ObjectID, e.Row)
+		})
+	}()
+
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
+	for {
+		select {
+		case err = <-done:
+			mu.Lock()
+			PrintTables(writer, ids, tables)
+			mu.Unlock()
+			return err
+		case <-ticker.C:
+			mu.Lock()
+			PrintTables(writer, ids, tables)
+			mu.Unlock()
+		}
+	}
+}
+
+// StartWatchers starts watching resources in the request and returns the watchers keyed by resource keys.
+// Resources whose runtime doesn't support watching have no watchers. Runtimes initialized by StartWatchers are
+// closed when ctx is done
+func (wo *WatchOperation) StartWatchers(ctx context.Context, req *WatchRequest) (map[string]*runtime.SequentialWatchers, error) {
+	// init runtimes if not provided by the caller
+	resources := req.Spec.Resources
+	if wo.RuntimeMap == nil {
+		runtimes, s := runtimeinit.Runtimes(req.Project, resources)
+		if status.IsErr(s) {
+			return nil, errors.New(s.Message())
+		}
+		wo.RuntimeMap = runtimes
+		// runtimes initialized here, such as runtime plugins, serve the watchers until ctx is done
+		go func() {
+			<-ctx.Done()
+			runtimeinit.Close(runtimes)
+		}()
+	}
 
-	// Counting completed resource
-	finished := make(map[string]bool)
+	watchers := make(map[string]*runtime.SequentialWatchers, len(resources))
+	for i := range resources {
+		res := &resources[i]
+		t := res.Type
 
-	// Start go routine for each table
-	for _, id := range ids {
-		sw, ok := msgChs[id]
-		if !ok { // Terraform resource, skip
-			continue
+		rt, ok := wo.RuntimeMap[t]
+		if !ok {
+			return nil, fmt.Errorf("no runtime found for resource type: %s", t)
 		}
-		// New target table
-		table := printers.NewTable(sw.IDs)
-		// Save tables first
-		tables[id] = table
-		// Start watching resource
-		go func(id string, chs []<-chan k8swatch.Event, table *printers.Table) {
-			// Resources selects
-			cases := createSelectCases(chs)
-			// Default select
-			cases = append(cases, reflect.SelectCase{
-				Dir:  reflect.SelectDefault,
-				Chan: reflect.Value{},
-				Send: reflect.Value{},
-			})
-			for {
-				chosen, recv, recvOK := reflect.Select(cases)
-				if cases[chosen].Dir == reflect.SelectDefault {
-					continue
-				}
-				if recvOK {
-					e := recv.Interface().(k8swatch.Event)
-					o := e.Object.(*unstructured.Unstructured)
-					var detail string
-					var ready bool
-					if e.Type == k8swatch.Deleted {
-						detail = fmt.Sprintf("%s has beed deleted", o.GetName())
-						ready = true
-					} else {
-						// Restore to actual type
-						target := printers.Convert(o)
-						detail, ready = printers.Generate(target)
-					}
 
-					// Mark ready for breaking loop
-					if ready {
-						e.Type = printers.READY
-					}
+		// Get watchers, runtimes return nil if watching is not supported
+		resp := rt.Watch(ctx, &runtime.WatchRequest{Resource: res, Stack: req.Stack, Project: req.Project})
+		if resp == nil {
+			log.Debugf("unsupported resource type: %s", t)
+			continue
+		}
+		if status.IsErr(resp.Status) {
+			return nil, fmt.Errorf(resp.Status.String())
+		}
 
-					// Save watched msg
-					table.Update(
-						engine.BuildIDForKubernetes(o),
-						printers.NewRow(e.Type, o.GetKind(), o.GetName(), detail))
+		watchers[res.ResourceKey()] = resp.Watchers
+	}
+	return watchers, nil
+}
 
-					// Write back
-					tables[id] = table
-				}
+// ReceiveEvents receives events from all watchers and calls fn with each of them. It returns when all watched
+// objects are ready, all watchers are closed, or ctx is done. Calls of fn are serialized
+func (wo *WatchOperation) ReceiveEvents(
+	ctx context.Context,
+	watchers map[string]*runtime.SequentialWatchers,
+	fn func(*WatchEvent),
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-				// Break when completed
-				if table.AllCompleted() {
-					break
+	eventCh := make(chan *WatchEvent)
+	var wg sync.WaitGroup
+	tables := make(map[string]*printers.Table, len(watchers))
+	for id, sw := range watchers {
+		tables[id] = printers.NewTable(sw.IDs)
+		for _, ch := range sw.Watchers {
+			wg.Add(1)
+			go func(id string, ch <-chan k8swatch.Event) {
+				defer wg.Done()
+				for {
+					select {
+					case e, ok := <-ch:
+						if !ok {
+							return
+						}
+						select {
+						case eventCh <- newWatchEvent(id, e):
+						case <-ctx.Done():
+							return
+						}
+					case <-ctx.Done():
+						return
+					}
 				}
-			}
-		}(id, sw.Watchers, table)
+			}(id, ch)
+		}
 	}
 
-	// No k8s resources
-	if len(tables) == 0 {
-		wo.printTables(writer, ids, tables)
-		return nil
-	}
+	// Close the event channel once all watchers are closed
+	closed := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(closed)
+	}()
 
-	// Waiting for all tables completed
 	for {
-		// Finish watch
-		if len(finished) == len(tables) {
-			break
+		if allCompleted(tables) {
+			return nil
 		}
-
-		// Range tables
-		for id, table := range tables {
-			// All channels are isCompleted
-			if table.AllCompleted() {
-				finished[id] = true
-			}
+		select {
+		case e := <-eventCh:
+			tables[e.ResourceID].Update(e.ObjectID, e.Row)
+			fn(e)
+		case <-closed:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+	}
+}
+
+func newWatchEvent(resourceID string, e k8swatch.Event) *WatchEvent {
+	o := e.Object.(*unstructured.Unstructured)
+	var detail string
+	var ready bool
+	if e.Type == k8swatch.Deleted {
+		detail = fmt.Sprintf("%s has beed deleted", o.GetName())
+		ready = true
+	} else {
+		// Restore to actual type
+		target := printers.Convert(o)
+		detail, ready = printers.Generate(target)
+	}
+
+	// Mark ready for breaking loop
+	if ready {
+		e.Type = printers.READY
+	}
 
-		// Render table every 1s
-		<-ticker.C
-		wo.printTables(writer, ids, tables)
+	return &WatchEvent{
+		ResourceID: resourceID,
+		ObjectID:   engine.BuildIDForKubernetes(o),
+		Row:        printers.NewRow(e.Type, o.GetKind(), o.GetName(), detail),
 	}
-	return nil
 }
 
-func (wo *WatchOperation) printTables(w *uilive.Writer, ids []string, tables map[string]*printers.Table) {
+func allCompleted(tables map[string]*printers.Table) bool {
+	for _, table := range tables {
+		if !table.AllCompleted() {
+			return false
+		}
+	}
+	return true
+}
+
+// PrintTables renders tables of watched resources in the order of ids. Resources without tables are not watchable
+func PrintTables(w *uilive.Writer, ids []string, tables map[string]*printers.Table) {
 	for i, id := range ids {
 		// Print resource Key as heading text
 		_, _ = fmt.Fprintln(w, pretty.LightCyanBold("[%s]", id))
 
 		table, ok := tables[id]
 		if !ok {
-			// Terraform resource, leave a hint
-			_, _ = fmt.Fprintln(w, "Skip monitoring Terraform resources")
+			// Watching is not supported, leave a hint
+			_, _ = fmt.Fprintln(w, "Skip monitoring this resource")
 		} else {
 			// Print table
 			data := table.Print()
@@ -194,14 +268,3 @@ func (wo *WatchOperation) printTables(w *uilive.Writer, ids []string, tables map
 
 	_ = w.Flush()
 }
-
-func createSelectCases(chs []<-chan k8swatch.Event) []reflect.SelectCase {
-	cases := make([]reflect.SelectCase, 0, len(chs))
-	for _, ch := range chs {
-		cases = append(cases, reflect.SelectCase{
-			Dir:  reflect.SelectRecv,
-			Chan: reflect.ValueOf(ch),
-		})
-	}
-	return cases
-}
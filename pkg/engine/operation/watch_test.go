@@ -13,6 +13,7 @@ import (
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 )
 
@@ -30,7 +31,7 @@ func TestWatchOperation_Watch(t *testing.T) {
 			},
 		},
 	}
-	monkey.Patch(runtimeinit.Runtimes, func(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+	monkey.Patch(runtimeinit.Runtimes, func(_ *projectstack.Project, resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
 		return map[models.Type]runtime.Runtime{runtime.Kubernetes: fooRuntime}, nil
 	})
 	wo := &WatchOperation{opsmodels.Operation{RuntimeMap: map[models.Type]runtime.Runtime{runtime.Kubernetes: fooRuntime}}}
@@ -0,0 +1,26 @@
+package convertor
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TerraformGroupVersion is the GroupVersion of Terraform resources watched by the Terraform runtime
+var TerraformGroupVersion = schema.GroupVersion{Group: "terraform.kusionstack.io", Version: "v1"}
+
+// TerraformAttributes is the field which contains all attributes of a Terraform resource
+const TerraformAttributes = "attributes"
+
+// TerraformResource is a Terraform resource in the watch table. Its kind is the Terraform resource type,
+// like `alicloud_db_instance`, and its `attributes` field contains all attributes in the Terraform state
+type TerraformResource struct {
+	unstructured.Unstructured
+}
+
+func ToTerraform(o *unstructured.Unstructured) runtime.Object {
+	if o.GroupVersionKind().GroupVersion() != TerraformGroupVersion {
+		return nil
+	}
+	return &TerraformResource{Unstructured: *o}
+}
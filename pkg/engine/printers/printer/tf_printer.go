@@ -0,0 +1,53 @@
+package printer
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine/printers/convertor"
+)
+
+// tfStatusFields are attributes which represent the status of cloud resources, like the `status` of RDS instances
+var tfStatusFields = []string{"status", "state", "instance_status", "lifecycle_state", "phase"}
+
+// tfReadyStatuses are lower-case status values which mean the cloud resource is ready
+var tfReadyStatuses = map[string]bool{
+	"running":   true,
+	"available": true,
+	"active":    true,
+	"ready":     true,
+	"normal":    true,
+	"online":    true,
+	"healthy":   true,
+	"in_use":    true,
+	"inuse":     true,
+	"succeeded": true,
+	"success":   true,
+}
+
+func AddTerraformHandlers(h PrintHandler) {
+	h.TableHandler(printTerraformResource)
+}
+
+// printTerraformResource prints the ID and the status of the Terraform resource. Resources without status
+// fields are ready once they exist
+func printTerraformResource(obj *convertor.TerraformResource) (string, bool) {
+	v, _, _ := unstructured.NestedFieldNoCopy(obj.Object, convertor.TerraformAttributes)
+	attributes, _ := v.(map[string]interface{})
+
+	var details []string
+	if id, ok := attributes["id"]; ok {
+		details = append(details, fmt.Sprintf("ID: %v", id))
+	}
+	for _, field := range tfStatusFields {
+		v, ok := attributes[field].(string)
+		if !ok || v == "" {
+			continue
+		}
+		details = append(details, fmt.Sprintf("Status: %s", v))
+		return strings.Join(details, ", "), tfReadyStatuses[strings.ToLower(v)]
+	}
+	return strings.Join(details, ", "), true
+}
@@ -55,7 +55,11 @@ func (t *Table) AllCompleted() bool {
 func (t *Table) Print() [][]string {
 	data := [][]string{{"Type", "Kind", "Name", "Detail"}}
 	for _, id := range t.IDs {
-		row := t.Rows[id]
+		row, ok := t.Rows[id]
+		if !ok {
+			// No event received yet
+			continue
+		}
 		eventType := row.Type
 
 		// Colored type
@@ -0,0 +1,301 @@
+// Package fake provides an in-memory runtime for engine-level tests. It keeps live resources in memory, records
+// every call, and can be programmed to fail, slow down or drift, so tests of graph ordering, rollback and
+// concurrency run against the real engine deterministically. Register it for a resource type in tests with
+// runtimeinit.Register, or pass it in the RuntimeMap of an operation.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// Method is a method of runtime.Runtime
+type Method string
+
+const (
+	Apply  Method = "Apply"
+	Read   Method = "Read"
+	Import Method = "Import"
+	Delete Method = "Delete"
+	Watch  Method = "Watch"
+)
+
+// Call is a recorded call of the runtime
+type Call struct {
+	Method     Method
+	ResourceID string
+	DryRun     bool
+	Start      time.Time
+	End        time.Time
+}
+
+// failure is a programmed failure of calls of a method on a resource
+type failure struct {
+	status status.Status
+	times  int // the failure is permanent if times is not positive
+}
+
+var _ runtime.Runtime = (*Runtime)(nil)
+
+// Runtime is an in-memory runtime.Runtime. Its zero value is not usable, and NewRuntime must be used
+type Runtime struct {
+	mu        sync.Mutex
+	resources map[string]*models.Resource
+	failures  map[Method]map[string]*failure
+	latencies map[Method]time.Duration
+	calls     []Call
+
+	running    int
+	maxRunning int
+}
+
+func NewRuntime() *Runtime {
+	return &Runtime{
+		resources: map[string]*models.Resource{},
+		failures:  map[Method]map[string]*failure{},
+		latencies: map[Method]time.Duration{},
+	}
+}
+
+// InitFn returns an init func of the runtime for runtimeinit.Register. The same runtime is returned every time, so
+// tests can inspect it after operations
+func (r *Runtime) InitFn() func() (runtime.Runtime, error) {
+	return func() (runtime.Runtime, error) {
+		return r, nil
+	}
+}
+
+// FailOn makes calls of the method on the resource return an error status with msg. Only the next times calls fail
+// if times is positive, and all calls fail otherwise. Dry-run applies fail as well as real ones
+func (r *Runtime) FailOn(method Method, id string, msg string, times int) *Runtime {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures[method] == nil {
+		r.failures[method] = map[string]*failure{}
+	}
+	r.failures[method][id] = &failure{status: status.NewErrorStatusWithMsg(status.Internal, msg), times: times}
+	return r
+}
+
+// SetLatency makes every call of the method take at least d, which helps tests of concurrency and cancellation
+func (r *Runtime) SetLatency(method Method, d time.Duration) *Runtime {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies[method] = d
+	return r
+}
+
+// Drift changes attributes of the live resource out of band, as if someone modified it in the infrastructure. A
+// nil value removes the attribute
+func (r *Runtime) Drift(id string, attributes map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res, ok := r.resources[id]
+	if !ok {
+		return
+	}
+	for k, v := range attributes {
+		if v == nil {
+			delete(res.Attributes, k)
+		} else {
+			res.Attributes[k] = v
+		}
+	}
+}
+
+// Put creates or replaces the live resource out of band
+func (r *Runtime) Put(res *models.Resource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources[res.ResourceKey()] = res.DeepCopy()
+}
+
+// Remove deletes the live resource out of band
+func (r *Runtime) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.resources, id)
+}
+
+// Get returns a copy of the live resource, or nil if it doesn't exist
+func (r *Runtime) Get(id string) *models.Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.get(id)
+}
+
+// Resources returns IDs of all live resources
+func (r *Runtime) Resources() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.resources))
+	for id := range r.resources {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Calls returns all recorded calls in the order they started
+func (r *Runtime) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsOf returns IDs of resources in recorded calls of the method in the order they started. Dry-run applies are
+// skipped
+func (r *Runtime) CallsOf(method Method) []string {
+	var ids []string
+	for _, c := range r.Calls() {
+		if c.Method == method && !c.DryRun {
+			ids = append(ids, c.ResourceID)
+		}
+	}
+	return ids
+}
+
+// MaxConcurrency returns the max number of calls running at the same time
+func (r *Runtime) MaxConcurrency() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxRunning
+}
+
+// Reset clears recorded calls, failures and latencies, and keeps live resources
+func (r *Runtime) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+	r.failures = map[Method]map[string]*failure{}
+	r.latencies = map[Method]time.Duration{}
+	r.maxRunning = 0
+}
+
+func (r *Runtime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	plan := request.PlanResource
+	done, s := r.begin(ctx, Apply, plan.ResourceKey(), request.DryRun)
+	defer done()
+	if s != nil {
+		return &runtime.ApplyResponse{Status: s}
+	}
+	if !request.DryRun {
+		r.Put(plan)
+	}
+	return &runtime.ApplyResponse{Resource: plan.DeepCopy()}
+}
+
+func (r *Runtime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	res := request.PlanResource
+	if res == nil {
+		res = request.PriorResource
+	}
+	if res == nil {
+		return &runtime.ReadResponse{}
+	}
+	done, s := r.begin(ctx, Read, res.ResourceKey(), false)
+	defer done()
+	if s != nil {
+		return &runtime.ReadResponse{Status: s}
+	}
+	return &runtime.ReadResponse{Resource: r.Get(res.ResourceKey())}
+}
+
+func (r *Runtime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	id := request.PlanResource.ResourceKey()
+	done, s := r.begin(ctx, Import, id, false)
+	defer done()
+	if s != nil {
+		return &runtime.ImportResponse{Status: s}
+	}
+	live := r.Get(id)
+	if live == nil {
+		return &runtime.ImportResponse{Status: status.NewErrorStatusWithMsg(status.NotFound, fmt.Sprintf("resource %s is not found", id))}
+	}
+	return &runtime.ImportResponse{Resource: live}
+}
+
+func (r *Runtime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	id := request.Resource.ResourceKey()
+	done, s := r.begin(ctx, Delete, id, false)
+	defer done()
+	if s != nil {
+		return &runtime.DeleteResponse{Status: s}
+	}
+	r.Remove(id)
+	return &runtime.DeleteResponse{}
+}
+
+// Watch sends a Deleted event if the resource doesn't exist, or an Added event with its attributes otherwise, and
+// closes the watcher
+func (r *Runtime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	id := request.Resource.ResourceKey()
+	done, s := r.begin(ctx, Watch, id, false)
+	defer done()
+	if s != nil {
+		return &runtime.WatchResponse{Status: s}
+	}
+
+	event := watch.Event{Type: watch.Deleted, Object: &unstructured.Unstructured{Object: request.Resource.Attributes}}
+	if live := r.Get(id); live != nil {
+		event = watch.Event{Type: watch.Added, Object: &unstructured.Unstructured{Object: live.Attributes}}
+	}
+	out := make(chan watch.Event, 1)
+	out <- event
+	close(out)
+	watchers := runtime.NewWatchers()
+	watchers.Insert(id, out)
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+// begin records the call, waits for the latency, and returns the programmed failure if any. done must be called
+// when the call ends
+func (r *Runtime) begin(ctx context.Context, method Method, id string, dryRun bool) (done func(), s status.Status) {
+	r.mu.Lock()
+	index := len(r.calls)
+	r.calls = append(r.calls, Call{Method: method, ResourceID: id, DryRun: dryRun, Start: time.Now()})
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	latency := r.latencies[method]
+	if f := r.failures[method][id]; f != nil {
+		s = f.status
+		if f.times > 0 {
+			if f.times--; f.times == 0 {
+				delete(r.failures[method], id)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	done = func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.running--
+		r.calls[index].End = time.Now()
+	}
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return done, status.NewErrorStatusWithCode(status.Canceled, ctx.Err())
+		}
+	}
+	return done, s
+}
+
+func (r *Runtime) get(id string) *models.Resource {
+	if res, ok := r.resources[id]; ok {
+		return res.DeepCopy()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package fake
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var foo = &models.Resource{ID: "foo", Type: "Fake", Attributes: map[string]interface{}{"size": "small"}}
+
+func TestRuntime(t *testing.T) {
+	ctx := context.Background()
+	r := NewRuntime()
+
+	rsp := r.Apply(ctx, &runtime.ApplyRequest{PlanResource: foo, DryRun: true})
+	assert.Nil(t, rsp.Status)
+	assert.Nil(t, r.Get("foo"))
+
+	r.Apply(ctx, &runtime.ApplyRequest{PlanResource: foo})
+	assert.Equal(t, foo, r.Read(ctx, &runtime.ReadRequest{PlanResource: foo}).Resource)
+	assert.Equal(t, foo, r.Import(ctx, &runtime.ImportRequest{PlanResource: foo}).Resource)
+
+	r.Drift("foo", map[string]interface{}{"size": "large"})
+	assert.Equal(t, "large", r.Read(ctx, &runtime.ReadRequest{PriorResource: foo}).Resource.Attributes["size"])
+
+	events := r.Watch(ctx, &runtime.WatchRequest{Resource: foo}).Watchers.Watchers[0]
+	assert.Equal(t, watch.Added, (<-events).Type)
+
+	assert.Nil(t, r.Delete(ctx, &runtime.DeleteRequest{Resource: foo}).Status)
+	assert.Empty(t, r.Resources())
+	assert.Equal(t, status.NotFound, r.Import(ctx, &runtime.ImportRequest{PlanResource: foo}).Status.Code())
+
+	assert.Equal(t, []string{"foo"}, r.CallsOf(Apply))
+	assert.Len(t, r.Calls(), 8)
+	r.Reset()
+	assert.Empty(t, r.Calls())
+}
+
+func TestRuntime_FailOn(t *testing.T) {
+	ctx := context.Background()
+	r := NewRuntime().FailOn(Apply, "foo", "quota exceeded", 1)
+
+	rsp := r.Apply(ctx, &runtime.ApplyRequest{PlanResource: foo})
+	assert.True(t, status.IsErr(rsp.Status))
+	assert.Equal(t, "quota exceeded", rsp.Status.Message())
+	assert.Nil(t, r.Get("foo"))
+
+	// the failure is consumed
+	assert.Nil(t, r.Apply(ctx, &runtime.ApplyRequest{PlanResource: foo}).Status)
+
+	r.FailOn(Delete, "foo", "locked", 0)
+	for i := 0; i < 3; i++ {
+		assert.True(t, status.IsErr(r.Delete(ctx, &runtime.DeleteRequest{Resource: foo}).Status))
+	}
+}
+
+func TestRuntime_SetLatency(t *testing.T) {
+	r := NewRuntime().SetLatency(Read, 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Read(context.Background(), &runtime.ReadRequest{PlanResource: foo})
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, r.MaxConcurrency())
+	for _, c := range r.Calls() {
+		assert.GreaterOrEqual(t, c.End.Sub(c.Start), 20*time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rsp := r.Read(ctx, &runtime.ReadRequest{PlanResource: foo})
+	assert.Equal(t, status.Canceled, rsp.Status.Code())
+}
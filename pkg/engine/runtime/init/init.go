@@ -2,46 +2,165 @@ package init
 
 import (
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"sync"
 
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/engine/runtime/local"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 )
 
 var SupportRuntimes = map[models.Type]InitFn{
 	runtime.Kubernetes: kubernetes.NewKubernetesRuntime,
 	runtime.Terraform:  terraform.NewTerraformRuntime,
+	runtime.Local:      local.NewLocalRuntime,
 }
 
+// runtimesMu guards SupportRuntimes against concurrent registrations
+var runtimesMu sync.RWMutex
+
 // InitFn runtime init func
 type InitFn func() (runtime.Runtime, error)
 
-func Runtimes(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+// Register registers the init func of the runtime of resource type t in this process, and replaces the registered
+// one if any. The returned func restores the previous registration, which helps tests registering fake runtimes.
+// Runtime plugins are configured per project in project.yaml instead
+func Register(t models.Type, fn InitFn) (restore func()) {
+	runtimesMu.Lock()
+	defer runtimesMu.Unlock()
+	prev, ok := SupportRuntimes[t]
+	SupportRuntimes[t] = fn
+	return func() {
+		runtimesMu.Lock()
+		defer runtimesMu.Unlock()
+		if ok {
+			SupportRuntimes[t] = prev
+		} else {
+			delete(SupportRuntimes, t)
+		}
+	}
+}
+
+// pluginPaths returns paths of runtime plugins configured in project.yaml keyed by their types. Built-in types can't
+// be taken over by plugins
+func pluginPaths(project *projectstack.Project) (map[models.Type]string, error) {
+	paths := map[models.Type]string{}
+	if project == nil {
+		return paths, nil
+	}
+	for _, p := range project.RuntimePlugins {
+		if p.Type == "" || p.Path == "" {
+			return nil, fmt.Errorf("type and path of runtime plugins are required")
+		}
+		t := models.Type(p.Type)
+		if isBuiltIn(t) {
+			return nil, fmt.Errorf("runtime plugin %s can't manage the built-in resource type %s", p.Path, t)
+		}
+		path := p.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(project.GetPath(), path)
+		}
+		paths[t] = path
+	}
+	return paths, nil
+}
+
+func isBuiltIn(t models.Type) bool {
+	switch t {
+	case runtime.Kubernetes, runtime.Terraform, runtime.Local:
+		return true
+	default:
+		return false
+	}
+}
+
+// Runtimes initializes runtimes of resources by their types. Types not registered in SupportRuntimes are dispatched
+// to runtime plugins configured in project.yaml, or the runtime plugin on PATH. Plugins are started for this call
+// only, and one plugin process serves all types it is configured for. The returned runtimes must be closed by Close
+// when the operation ends
+func Runtimes(project *projectstack.Project, resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
 	runtimesMap := map[models.Type]runtime.Runtime{}
 	if resources == nil {
 		return runtimesMap, nil
 	}
+	paths, err := pluginPaths(project)
+	if err != nil {
+		return nil, status.NewErrorStatusWithCode(status.IllegalManifest, err)
+	}
 
+	// plugins started in this call keyed by their paths
+	plugins := map[string]*plugin.Runtime{}
 	for _, resource := range resources {
 		rt := resource.Type
 		if rt == "" {
+			Close(runtimesMap)
 			return nil, status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("no resource type in resource: %v", resource.ID))
 		}
 
-		if SupportRuntimes[rt] == nil {
-			return nil, status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("unknow resource type: %s. Currently supported resource types are: %v",
-				rt, reflect.ValueOf(SupportRuntimes).MapKeys()))
-		} else if runtimesMap[rt] == nil {
-			r, err := SupportRuntimes[rt]()
-			if err != nil {
-				return nil, status.NewErrorStatus(fmt.Errorf("init %s runtime failed", rt))
-			}
-			runtimesMap[rt] = r
+		if runtimesMap[rt] != nil {
+			continue
+		}
+		r, s := newRuntime(rt, paths, plugins)
+		if status.IsErr(s) {
+			Close(runtimesMap)
+			return nil, s
 		}
+		runtimesMap[rt] = r
 	}
 
 	return runtimesMap, nil
 }
+
+// newRuntime initializes the runtime of resource type t. Plugins already started are reused
+func newRuntime(t models.Type, paths map[models.Type]string, plugins map[string]*plugin.Runtime) (runtime.Runtime, status.Status) {
+	runtimesMu.RLock()
+	initFn := SupportRuntimes[t]
+	runtimesMu.RUnlock()
+	if initFn != nil {
+		r, err := initFn()
+		if err != nil {
+			return nil, status.NewErrorStatus(fmt.Errorf("init %s runtime failed: %v", t, err))
+		}
+		return r, nil
+	}
+
+	path, ok := paths[t]
+	if !ok {
+		var err error
+		if path, err = plugin.LookPath(t); err != nil {
+			runtimesMu.RLock()
+			supported := reflect.ValueOf(SupportRuntimes).MapKeys()
+			runtimesMu.RUnlock()
+			return nil, status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("unknow resource type: %s. Currently supported resource types are: %v, "+
+				"and other types need a runtime plugin configured in project.yaml or named %s<type in lower case> on PATH", t, supported, plugin.BinaryPrefix))
+		}
+	}
+	if r, ok := plugins[path]; ok {
+		return r, nil
+	}
+	r, err := plugin.NewRuntime(path)
+	if err != nil {
+		return nil, status.NewErrorStatus(fmt.Errorf("init %s runtime failed: %v", t, err))
+	}
+	plugins[path] = r
+	return r, nil
+}
+
+// Close closes runtimes which hold resources, such as runtime plugins. A runtime serving several types is closed once
+func Close(runtimes map[models.Type]runtime.Runtime) {
+	closed := map[runtime.Runtime]bool{}
+	for _, r := range runtimes {
+		c, ok := r.(interface{ Close() })
+		if !ok || closed[r] {
+			continue
+		}
+		closed[r] = true
+		c.Close()
+	}
+}
@@ -0,0 +1,87 @@
+package init
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestPluginPaths(t *testing.T) {
+	project := &projectstack.Project{
+		ProjectConfiguration: projectstack.ProjectConfiguration{
+			RuntimePlugins: []*projectstack.RuntimePluginConfig{{Type: "Database", Path: "./bin/kusion-runtime-database"}},
+		},
+		Path: "/path/to/project",
+	}
+	paths, err := pluginPaths(project)
+	assert.Nil(t, err)
+	assert.Equal(t, map[models.Type]string{"Database": "/path/to/project/bin/kusion-runtime-database"}, paths)
+
+	project.RuntimePlugins = []*projectstack.RuntimePluginConfig{{Type: "Database"}}
+	_, err = pluginPaths(project)
+	assert.NotNil(t, err)
+
+	project.RuntimePlugins = []*projectstack.RuntimePluginConfig{{Type: string(runtime.Kubernetes), Path: "./bin/kusion-runtime-k8s"}}
+	_, err = pluginPaths(project)
+	assert.ErrorContains(t, err, "built-in")
+
+	paths, err = pluginPaths(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestRuntimes(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, s := Runtimes(nil, models.Resources{{ID: "foo", Type: "Database"}})
+	assert.True(t, status.IsErr(s))
+	assert.Equal(t, status.IllegalManifest, s.Code())
+
+	// plugins configured in project.yaml are started for the call only, and never registered in SupportRuntimes
+	project := &projectstack.Project{
+		ProjectConfiguration: projectstack.ProjectConfiguration{
+			RuntimePlugins: []*projectstack.RuntimePluginConfig{{Type: "Database", Path: "./bin/kusion-runtime-database"}},
+		},
+		Path: t.TempDir(),
+	}
+	_, s = Runtimes(project, models.Resources{{ID: "foo", Type: "Database"}})
+	assert.True(t, status.IsErr(s))
+	assert.Contains(t, s.Message(), "kusion-runtime-database")
+	assert.NotContains(t, SupportRuntimes, models.Type("Database"))
+
+	project.RuntimePlugins[0].Type = string(runtime.Terraform)
+	_, s = Runtimes(project, models.Resources{{ID: "foo", Type: runtime.Terraform}})
+	assert.True(t, status.IsErr(s))
+	assert.Equal(t, status.IllegalManifest, s.Code())
+
+	restore := Register("Database", func() (runtime.Runtime, error) { return nil, nil })
+	runtimes, s := Runtimes(nil, models.Resources{{ID: "foo", Type: "Database"}})
+	assert.Nil(t, s)
+	assert.Contains(t, runtimes, models.Type("Database"))
+
+	restore()
+	assert.NotContains(t, SupportRuntimes, models.Type("Database"))
+	restore = Register(runtime.Kubernetes, nil)
+	restore()
+	assert.NotNil(t, SupportRuntimes[runtime.Kubernetes])
+}
+
+type closer struct {
+	runtime.Runtime
+	closed int
+}
+
+func (c *closer) Close() {
+	c.closed++
+}
+
+func TestClose(t *testing.T) {
+	c := &closer{}
+	Close(map[models.Type]runtime.Runtime{"Database": c, "Cache": c, runtime.Local: nil})
+	assert.Equal(t, 1, c.closed)
+}
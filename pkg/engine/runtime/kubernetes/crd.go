@@ -0,0 +1,131 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+
+	"kusionstack.io/kusion/pkg/log"
+)
+
+const (
+	// DefaultCRDEstablishedTimeout is the max duration to wait for a CRD to be established after applying it
+	DefaultCRDEstablishedTimeout = time.Minute
+
+	crdEstablishedInterval = time.Second
+)
+
+var crdGroupKind = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+// resettableMapper is a RESTMapper which can be rebuilt from API discovery, so that kinds of newly established CRDs
+// are discovered
+type resettableMapper struct {
+	mu        sync.RWMutex
+	mapper    meta.RESTMapper
+	newMapper func() (meta.RESTMapper, error)
+}
+
+var _ meta.ResettableRESTMapper = (*resettableMapper)(nil)
+
+func newResettableMapper(newMapper func() (meta.RESTMapper, error)) (*resettableMapper, error) {
+	mapper, err := newMapper()
+	if err != nil {
+		return nil, err
+	}
+	return &resettableMapper{mapper: mapper, newMapper: newMapper}, nil
+}
+
+// Reset rebuilds the RESTMapper, and keeps the current one if rebuilding fails
+func (m *resettableMapper) Reset() {
+	mapper, err := m.newMapper()
+	if err != nil {
+		log.Errorf("reset RESTMapper failed: %v", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mapper = mapper
+}
+
+func (m *resettableMapper) current() meta.RESTMapper {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.mapper
+}
+
+func (m *resettableMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	return m.current().KindFor(resource)
+}
+
+func (m *resettableMapper) KindsFor(resource schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	return m.current().KindsFor(resource)
+}
+
+func (m *resettableMapper) ResourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	return m.current().ResourceFor(input)
+}
+
+func (m *resettableMapper) ResourcesFor(input schema.GroupVersionResource) ([]schema.GroupVersionResource, error) {
+	return m.current().ResourcesFor(input)
+}
+
+func (m *resettableMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	return m.current().RESTMapping(gk, versions...)
+}
+
+func (m *resettableMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	return m.current().RESTMappings(gk, versions...)
+}
+
+func (m *resettableMapper) ResourceSingularizer(resource string) (string, error) {
+	return m.current().ResourceSingularizer(resource)
+}
+
+func isCRD(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == crdGroupKind
+}
+
+// waitForCRDEstablished waits until the Established condition of the CRD is True, and then resets the RESTMapper
+// so that custom resources of this CRD can be applied right after it
+func (k *KubernetesRuntime) waitForCRDEstablished(ctx context.Context, resource dynamic.ResourceInterface, name string) error {
+	timeout := k.crdEstablishedTimeout
+	if timeout <= 0 {
+		timeout = DefaultCRDEstablishedTimeout
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, crdEstablishedInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		crd, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			log.Infof("get CRD %s failed, retry later; err: %v", name, err)
+			return false, nil
+		}
+		return crdEstablished(crd), nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for CRD %s to be established failed: %v", name, err)
+	}
+
+	if m, ok := k.mapper.(meta.ResettableRESTMapper); ok {
+		m.Reset()
+	}
+	return nil
+}
+
+func crdEstablished(crd *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Established" && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+var crd = &models.Resource{
+	ID:   "apiextensions.k8s.io/v1:CustomResourceDefinition:foos.example.com",
+	Type: runtime.Kubernetes,
+	Attributes: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "foos.example.com"},
+	},
+}
+
+var foo = &models.Resource{
+	ID:   "example.com/v1:Foo:default:bar",
+	Type: runtime.Kubernetes,
+	Attributes: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Foo",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "bar"},
+	},
+}
+
+// newCRDRuntime returns a runtime whose RESTMapper knows Foo only after it is reset
+func newCRDRuntime(t *testing.T, established bool) *KubernetesRuntime {
+	crdGV := schema.GroupVersion{Group: "apiextensions.k8s.io", Version: "v1"}
+	fooGV := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	resets := 0
+	mapper, err := newResettableMapper(func() (meta.RESTMapper, error) {
+		m := meta.NewDefaultRESTMapper([]schema.GroupVersion{crdGV, fooGV})
+		m.Add(crdGV.WithKind("CustomResourceDefinition"), meta.RESTScopeRoot)
+		if resets > 0 {
+			m.Add(fooGV.WithKind("Foo"), meta.RESTScopeNamespace)
+		}
+		resets++
+		return m, nil
+	})
+	assert.Nil(t, err)
+
+	client := fake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	if !established {
+		return &KubernetesRuntime{client: client, mapper: mapper, crdEstablishedTimeout: 10 * time.Millisecond}
+	}
+	// CRDs are established as soon as they are created
+	client.PrependReactor("create", "customresourcedefinitions", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		_ = unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}, "status", "conditions")
+		return false, nil, nil
+	})
+	return &KubernetesRuntime{client: client, mapper: mapper, crdEstablishedTimeout: time.Second}
+}
+
+func TestKubernetesRuntime_ApplyCRD(t *testing.T) {
+	ctx := context.Background()
+	rt := newCRDRuntime(t, true)
+
+	// The CRD is not established, so Foo is regarded as not found and its dry run returns the planed resource
+	read := rt.Read(ctx, &runtime.ReadRequest{PlanResource: foo})
+	assert.Nil(t, read.Status)
+	assert.Nil(t, read.Resource)
+	dryRun := rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: foo, PriorResource: foo, DryRun: true})
+	assert.Nil(t, dryRun.Status)
+	assert.Equal(t, foo, dryRun.Resource)
+	assert.Nil(t, rt.Delete(ctx, &runtime.DeleteRequest{Resource: foo}).Status)
+
+	// Apply the CRD, and then Foo is known by the RESTMapper
+	response := rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: crd})
+	assert.Nil(t, response.Status)
+	response = rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: foo})
+	assert.Nil(t, response.Status)
+	read = rt.Read(ctx, &runtime.ReadRequest{PlanResource: foo})
+	assert.Nil(t, read.Status)
+	assert.NotNil(t, read.Resource)
+}
+
+func TestKubernetesRuntime_WaitForCRDEstablished(t *testing.T) {
+	rt := newCRDRuntime(t, false)
+	response := rt.Apply(context.Background(), &runtime.ApplyRequest{PlanResource: crd})
+	assert.NotNil(t, response.Status)
+	assert.Contains(t, response.Status.Message(), "established")
+}
@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+)
+
+const (
+	// DefaultDeletionTimeout is the max duration to wait for deletion if the timeout is not configured
+	DefaultDeletionTimeout = 5 * time.Minute
+
+	deletionInterval = time.Second
+)
+
+// Keys in resource Extensions to override the deletion configs of the project
+const (
+	PropagationPolicyExtensionKey = "propagationPolicy"
+	WaitForDeletionExtensionKey   = "waitForDeletion"
+	DeletionTimeoutExtensionKey   = "deletionTimeout"
+)
+
+// deleteConfig is the resolved deletion configs of one resource
+type deleteConfig struct {
+	propagationPolicy *metav1.DeletionPropagation
+	wait              bool
+	timeout           time.Duration
+}
+
+// resolveDeleteConfig resolves deletion configs of the deleted resource. Resource Extensions, with their references
+// resolved, take precedence over configs of the project
+func resolveDeleteConfig(request *runtime.DeleteRequest) (*deleteConfig, error) {
+	var policy, waitForDeletion, timeout string
+	if request.Project != nil && request.Project.Kubernetes != nil {
+		kc := request.Project.Kubernetes
+		policy = kc.PropagationPolicy
+		waitForDeletion = strconv.FormatBool(kc.WaitForDeletion)
+		timeout = kc.DeletionTimeout
+	}
+
+	id := request.Resource.ResourceKey()
+	if v := request.Resource.ResolvedExtension(PropagationPolicyExtensionKey); v != nil {
+		policy = fmt.Sprint(v)
+	}
+	if v := request.Resource.ResolvedExtension(WaitForDeletionExtensionKey); v != nil {
+		waitForDeletion = fmt.Sprint(v)
+	}
+	if v := request.Resource.ResolvedExtension(DeletionTimeoutExtensionKey); v != nil {
+		timeout = fmt.Sprint(v)
+	}
+
+	c := &deleteConfig{timeout: DefaultDeletionTimeout}
+	switch p := metav1.DeletionPropagation(policy); p {
+	case "":
+	case metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+		c.propagationPolicy = &p
+	default:
+		return nil, fmt.Errorf("invalid %s of resource %s: %s, must be %s, %s or %s", PropagationPolicyExtensionKey, id, policy,
+			metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan)
+	}
+	if waitForDeletion != "" {
+		w, err := strconv.ParseBool(waitForDeletion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of resource %s: %s", WaitForDeletionExtensionKey, id, waitForDeletion)
+		}
+		c.wait = w
+	}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s of resource %s: %s", DeletionTimeoutExtensionKey, id, timeout)
+		}
+		c.timeout = d
+	}
+	return c, nil
+}
+
+// waitForDeletion polls the object with the UID until it is gone. An object with the same name and a different UID
+// is recreated by controllers, so the deleted one is gone too. Finalizers of the object are reported if it is still
+// there when the timeout expires, since they are the usual blockers of deletion
+func waitForDeletion(ctx context.Context, resource dynamic.ResourceInterface, name string, uid types.UID, timeout time.Duration) error {
+	var finalizers []string
+	err := wait.PollUntilContextTimeout(ctx, deletionInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			log.Infof("get %s failed, retry later; err: %v", name, err)
+			return false, nil
+		}
+		if obj.GetUID() != uid {
+			log.Infof("%s is recreated with UID %s, regard %s as deleted", name, obj.GetUID(), uid)
+			return true, nil
+		}
+		finalizers = obj.GetFinalizers()
+		return false, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if len(finalizers) > 0 {
+		return fmt.Errorf("wait for deletion of %s failed: %v, blocked by finalizers %v", name, err, finalizers)
+	}
+	return fmt.Errorf("wait for deletion of %s failed: %v", name, err)
+}
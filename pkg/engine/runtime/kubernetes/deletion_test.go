@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestResolveDeleteConfig(t *testing.T) {
+	foreground, orphan := metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+		Kubernetes: &projectstack.KubernetesConfig{PropagationPolicy: "Foreground", WaitForDeletion: true, DeletionTimeout: "1m"},
+	}}
+	withExtensions := func(extensions map[string]interface{}) *models.Resource {
+		r := *configMap
+		r.Extensions = extensions
+		return &r
+	}
+
+	tests := []struct {
+		name    string
+		request *runtime.DeleteRequest
+		want    *deleteConfig
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			request: &runtime.DeleteRequest{Resource: configMap},
+			want:    &deleteConfig{timeout: DefaultDeletionTimeout},
+		},
+		{
+			name:    "project",
+			request: &runtime.DeleteRequest{Resource: configMap, Project: project},
+			want:    &deleteConfig{propagationPolicy: &foreground, wait: true, timeout: time.Minute},
+		},
+		{
+			name: "resource overrides project",
+			request: &runtime.DeleteRequest{
+				Resource: withExtensions(map[string]interface{}{
+					PropagationPolicyExtensionKey: "Orphan",
+					WaitForDeletionExtensionKey:   false,
+					DeletionTimeoutExtensionKey:   "10s",
+				}),
+				Project: project,
+			},
+			want: &deleteConfig{propagationPolicy: &orphan, timeout: 10 * time.Second},
+		},
+		{
+			name: "resolved extensions",
+			request: &runtime.DeleteRequest{
+				Resource: func() *models.Resource {
+					r := withExtensions(map[string]interface{}{DeletionTimeoutExtensionKey: "ref+vault://kusion/timeout"})
+					r.ResolvedExtensions = map[string]interface{}{DeletionTimeoutExtensionKey: "10s"}
+					return r
+				}(),
+				Project: project,
+			},
+			want: &deleteConfig{propagationPolicy: &foreground, wait: true, timeout: 10 * time.Second},
+		},
+		{
+			name:    "invalid policy",
+			request: &runtime.DeleteRequest{Resource: withExtensions(map[string]interface{}{PropagationPolicyExtensionKey: "foo"})},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			request: &runtime.DeleteRequest{Resource: withExtensions(map[string]interface{}{DeletionTimeoutExtensionKey: "foo"})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDeleteConfig(tt.request)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKubernetesRuntime_DeleteAndWait(t *testing.T) {
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+		Kubernetes: &projectstack.KubernetesConfig{PropagationPolicy: "Foreground", WaitForDeletion: true, DeletionTimeout: "100ms"},
+	}}
+	newConfigMap := func() *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: configMap.Attributes}
+		obj = obj.DeepCopy()
+		obj.SetFinalizers([]string{"kusionstack.io/foo"})
+		return obj
+	}
+
+	t.Run("deleted", func(t *testing.T) {
+		rt, client := newFakeRuntime()
+		assert.Nil(t, client.Tracker().Add(newConfigMap()))
+
+		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: configMap, Project: project})
+		assert.Nil(t, response.Status)
+	})
+
+	t.Run("blocked by finalizers", func(t *testing.T) {
+		rt, client := newFakeRuntime()
+		assert.Nil(t, client.Tracker().Add(newConfigMap()))
+		// the object is kept by its finalizer
+		client.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+			return true, nil, nil
+		})
+
+		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: configMap, Project: project})
+		assert.True(t, status.IsErr(response.Status))
+		assert.Contains(t, response.Status.Message(), "kusionstack.io/foo")
+	})
+
+	t.Run("recreated", func(t *testing.T) {
+		rt, client := newFakeRuntime()
+		obj := newConfigMap()
+		obj.SetUID("old")
+		assert.Nil(t, client.Tracker().Add(obj))
+		// a controller recreates the object right after it is deleted
+		client.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+			recreated := newConfigMap()
+			recreated.SetUID("new")
+			return true, nil, client.Tracker().Update(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, recreated, recreated.GetNamespace())
+		})
+
+		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: configMap, Project: project})
+		assert.Nil(t, response.Status)
+	})
+}
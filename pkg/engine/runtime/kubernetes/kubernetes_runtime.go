@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"context"
 	"errors"
+	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	yamlv2 "gopkg.in/yaml.v2"
@@ -20,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
 	k8swatch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
@@ -29,29 +31,27 @@ import (
 	"kusionstack.io/kusion/pkg/engine/printers/convertor"
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 	jsonutil "kusionstack.io/kusion/pkg/util/json"
-	"kusionstack.io/kusion/pkg/util/kube/config"
 )
 
 var _ runtime.Runtime = (*KubernetesRuntime)(nil)
 
 type KubernetesRuntime struct {
-	client dynamic.Interface
-	mapper meta.RESTMapper
+	client    dynamic.Interface
+	mapper    meta.RESTMapper
+	discovery discovery.DiscoveryInterface
+
+	// crdEstablishedTimeout is the max duration to wait for CRDs to be established. DefaultCRDEstablishedTimeout
+	// is used if it is zero
+	crdEstablishedTimeout time.Duration
 }
 
-// NewKubernetesRuntime create a new KubernetesRuntime
+// NewKubernetesRuntime create a new Kubernetes runtime, which operates each resource with the KubernetesRuntime of
+// its cluster. The cluster is resolved by the kubeconfig and context in stack.yaml and the `cluster` extension
 func NewKubernetesRuntime() (runtime.Runtime, error) {
-	client, mapper, err := getKubernetesClient()
-	if err != nil {
-		return nil, err
-	}
-
-	return &KubernetesRuntime{
-		client: client,
-		mapper: mapper,
-	}, nil
+	return newMultiClusterRuntime(), nil
 }
 
 // Apply kubernetes Resource by client-go
@@ -63,13 +63,47 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 	if planState == nil {
 		return &runtime.ApplyResponse{Status: status.NewErrorStatus(errors.New("plan state is nil"))}
 	}
+	// Stamp ownership labels and annotations, which are saved in the state as well
+	planState = stampOwnership(request)
 
 	// Get kubernetes Resource interface from plan state
 	planObj, resource, err := k.buildKubernetesResourceByState(planState)
 	if err != nil {
+		// The CRD of this custom resource is not established yet, return the planed resource as the dry-run result
+		if meta.IsNoMatchError(err) && request.DryRun {
+			log.Infof("%v, skip dry-run %s", err, planState.ResourceKey())
+			return &runtime.ApplyResponse{Resource: planState}
+		}
 		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
 	}
 
+	c, err := resolveApplyConfig(request)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	if c.mode == projectstack.ServerSideApply {
+		res, err := serverSideApply(ctx, resource, planObj, planState, c, request.DryRun)
+		if err != nil {
+			return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+		}
+		// Save modified
+		if !request.DryRun {
+			if isCRD(planObj) {
+				if err = k.waitForCRDEstablished(ctx, resource, planObj.GetName()); err != nil {
+					return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+				}
+			}
+			res = planObj
+		}
+		return &runtime.ApplyResponse{Resource: &models.Resource{
+			ID:         planState.ResourceKey(),
+			Type:       planState.Type,
+			Attributes: res.Object,
+			DependsOn:  planState.DependsOn,
+			Extensions: planState.Extensions,
+		}}
+	}
+
 	// Get live state
 	response := k.Read(ctx, &runtime.ReadRequest{PlanResource: planState})
 	if status.IsErr(response.Status) {
@@ -143,11 +177,17 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 			_, err = resource.Create(ctx, planObj, metav1.CreateOptions{})
 		} else {
 			// LiveState isn't nil, continue to patch liveObj
-			_, err = resource.Patch(ctx, planObj.GetName(), types.MergePatchType, patchBody, metav1.PatchOptions{FieldManager: "kusion"})
+			_, err = resource.Patch(ctx, planObj.GetName(), types.MergePatchType, patchBody, metav1.PatchOptions{FieldManager: c.fieldManager})
 		}
 		if err != nil {
 			return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
 		}
+		// Custom resources of this CRD can be applied only after it is established
+		if isCRD(planObj) {
+			if err = k.waitForCRDEstablished(ctx, resource, planObj.GetName()); err != nil {
+				return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+			}
+		}
 		// Save modified
 		res = planObj
 	}
@@ -175,9 +215,11 @@ func (k *KubernetesRuntime) Read(ctx context.Context, request *runtime.ReadReque
 	// Get resource by attribute
 	obj, resource, err := k.buildKubernetesResourceByState(requestResource)
 	if err != nil {
-		// Ignore no match error, cause target apiVersion or kind is not installed yet
+		// No match means the CRD of this custom resource is not established yet, so the resource can't exist.
+		// Treat it as not found so that the preview shows a creation, and the CRD in the same stack is applied
+		// before it. The RESTMapper is reset once the CRD is established, so it never returns a stale NoMatch
 		if meta.IsNoMatchError(err) {
-			log.Infof("%v, ignore", err)
+			log.Infof("%v, regard %s as not found", err, requestResource.ResourceKey())
 			return &runtime.ReadResponse{}
 		}
 		return &runtime.ReadResponse{Status: status.NewErrorStatus(err)}
@@ -285,11 +327,36 @@ func (k *KubernetesRuntime) Delete(ctx context.Context, request *runtime.DeleteR
 	// Get Resource by attribute
 	obj, resource, err := k.buildKubernetesResourceByState(requestResource)
 	if err != nil {
+		// The custom resource is deleted along with its CRD
+		if meta.IsNoMatchError(err) {
+			log.Infof("%v, regard %s as deleted", err, requestResource.ResourceKey())
+			return &runtime.DeleteResponse{}
+		}
 		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
 	}
 
+	c, err := resolveDeleteConfig(request)
+	if err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+
+	// Record the UID of the object to wait for, so that an object recreated by controllers during the deletion is
+	// regarded as another one
+	var uid types.UID
+	if c.wait {
+		live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				log.Infof("%s not found, ignore", requestResource.ResourceKey())
+				return &runtime.DeleteResponse{}
+			}
+			return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+		}
+		uid = live.GetUID()
+	}
+
 	// Delete Resource
-	err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+	err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: c.propagationPolicy})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			log.Infof("%s not found, ignore", requestResource.ResourceKey())
@@ -298,6 +365,13 @@ func (k *KubernetesRuntime) Delete(ctx context.Context, request *runtime.DeleteR
 		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
 	}
 
+	// Wait until the object is gone, so that resources depending on it are not deleted in advance
+	if c.wait {
+		if err = waitForDeletion(ctx, resource, obj.GetName(), uid, c.timeout); err != nil {
+			return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+		}
+	}
+
 	return &runtime.DeleteResponse{}
 }
 
@@ -373,27 +447,48 @@ func (k *KubernetesRuntime) Watch(ctx context.Context, request *runtime.WatchReq
 	return &runtime.WatchResponse{Watchers: watchers}
 }
 
-// getKubernetesClient get kubernetes client
-func getKubernetesClient() (dynamic.Interface, meta.RESTMapper, error) {
+// newKubernetesRuntime creates the KubernetesRuntime of the context in the kubeconfig, and the current context is used
+// if kubeContext is empty
+func newKubernetesRuntime(kubeConfig, kubeContext string) (*KubernetesRuntime, error) {
 	// build config
-	cfg, err := clientcmd.BuildConfigFromFlags("", config.GetKubeConfig())
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// DynamicRESTMapper can discover resource types at runtime dynamically
-	mapper, err := apiutil.NewDynamicRESTMapper(cfg)
+	// DynamicRESTMapper can discover resource types at runtime dynamically, and it is rebuilt once CRDs are established
+	mapper, err := newResettableMapper(func() (meta.RESTMapper, error) {
+		return apiutil.NewDynamicRESTMapper(cfg)
+	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Prepare the dynamic client
 	dyn, err := dynamic.NewForConfig(cfg)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+
+	// Prepare the discovery client to list owned resources of all kinds
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	return dyn, mapper, nil
+	return &KubernetesRuntime{client: dyn, mapper: mapper, discovery: dc}, nil
+}
+
+// Namespaced returns true if objects of the kind are namespaced in the cluster
+func (k *KubernetesRuntime) Namespaced(gvk schema.GroupVersionKind) (bool, error) {
+	mapping, err := k.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, err
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
 }
 
 // buildKubernetesResourceByState get resource by attribute
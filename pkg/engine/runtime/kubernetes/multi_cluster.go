@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/kube/config"
+)
+
+var _ runtime.Runtime = (*multiClusterRuntime)(nil)
+
+// cluster identifies a Kubernetes cluster by the kubeconfig file and the context in it
+type cluster struct {
+	kubeConfig string
+	context    string
+}
+
+func (c cluster) String() string {
+	if c.context == "" {
+		return fmt.Sprintf("current context of %s", c.kubeConfig)
+	}
+	return fmt.Sprintf("context %s of %s", c.context, c.kubeConfig)
+}
+
+// multiClusterRuntime dispatches requests to the KubernetesRuntime of the cluster of each resource.
+// Clients are created on first use and kept for later requests, one per cluster
+type multiClusterRuntime struct {
+	mu       sync.Mutex
+	runtimes map[cluster]*KubernetesRuntime
+
+	// newRuntime creates the KubernetesRuntime of a cluster
+	newRuntime func(c cluster) (*KubernetesRuntime, error)
+}
+
+func newMultiClusterRuntime() *multiClusterRuntime {
+	return &multiClusterRuntime{
+		runtimes: map[cluster]*KubernetesRuntime{},
+		newRuntime: func(c cluster) (*KubernetesRuntime, error) {
+			return newKubernetesRuntime(c.kubeConfig, c.context)
+		},
+	}
+}
+
+// clusterOf resolves the cluster of the resource. The kubeconfig is configured by the stack, and the context is
+// the `cluster` extension of the resource, or the context configured by the stack
+func clusterOf(res *models.Resource, stack *projectstack.Stack) cluster {
+	c := cluster{kubeConfig: config.GetKubeConfig()}
+	if stack != nil {
+		if kubeConfig := stack.GetKubeConfig(); kubeConfig != "" {
+			c.kubeConfig = kubeConfig
+		}
+		c.context = stack.Context
+	}
+	if res != nil {
+		if name, ok := res.ResolvedExtension(engine.ClusterExtension).(string); ok && name != "" {
+			c.context = name
+		}
+	}
+	return c
+}
+
+// NewClusterRuntime creates the KubernetesRuntime of the cluster of the stack. The cluster is the context named by
+// the `cluster` extension, and the context configured by the stack is used if the name is empty
+func NewClusterRuntime(stack *projectstack.Stack, name string) (*KubernetesRuntime, error) {
+	res := &models.Resource{Extensions: map[string]interface{}{engine.ClusterExtension: name}}
+	c := clusterOf(res, stack)
+	return newKubernetesRuntime(c.kubeConfig, c.context)
+}
+
+// runtimeFor returns the KubernetesRuntime of the cluster of the resource
+func (m *multiClusterRuntime) runtimeFor(res *models.Resource, stack *projectstack.Stack) (*KubernetesRuntime, status.Status) {
+	if res == nil {
+		return nil, status.NewErrorStatus(errors.New("requestResource is nil"))
+	}
+	c := clusterOf(res, stack)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rt, ok := m.runtimes[c]; ok {
+		return rt, nil
+	}
+	rt, err := m.newRuntime(c)
+	if err != nil {
+		return nil, status.NewErrorStatus(fmt.Errorf("init Kubernetes client of %s failed: %v", c, err))
+	}
+	m.runtimes[c] = rt
+	return rt, nil
+}
+
+func (m *multiClusterRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	rt, s := m.runtimeFor(request.PlanResource, request.Stack)
+	if status.IsErr(s) {
+		return &runtime.ApplyResponse{Status: s}
+	}
+	return rt.Apply(ctx, request)
+}
+
+func (m *multiClusterRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	res := request.PlanResource
+	if res == nil {
+		res = request.PriorResource
+	}
+	rt, s := m.runtimeFor(res, request.Stack)
+	if status.IsErr(s) {
+		return &runtime.ReadResponse{Status: s}
+	}
+	return rt.Read(ctx, request)
+}
+
+func (m *multiClusterRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	rt, s := m.runtimeFor(request.PlanResource, request.Stack)
+	if status.IsErr(s) {
+		return &runtime.ImportResponse{Status: s}
+	}
+	return rt.Import(ctx, request)
+}
+
+func (m *multiClusterRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	rt, s := m.runtimeFor(request.Resource, request.Stack)
+	if status.IsErr(s) {
+		return &runtime.DeleteResponse{Status: s}
+	}
+	return rt.Delete(ctx, request)
+}
+
+func (m *multiClusterRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	if request == nil {
+		return &runtime.WatchResponse{Status: status.NewErrorStatus(errors.New("requestResource is nil"))}
+	}
+	rt, s := m.runtimeFor(request.Resource, request.Stack)
+	if status.IsErr(s) {
+		return &runtime.WatchResponse{Status: s}
+	}
+	return rt.Watch(ctx, request)
+}
@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestClusterOf(t *testing.T) {
+	stack := &projectstack.Stack{
+		StackConfiguration: projectstack.StackConfiguration{Name: "dev", KubeConfig: "kubeconfig", Context: "west"},
+		Path:               "/stack",
+	}
+	east := &models.Resource{Extensions: map[string]interface{}{engine.ClusterExtension: "east"}}
+	resolved := &models.Resource{
+		Extensions:         map[string]interface{}{engine.ClusterExtension: "ref+vault://kusion/cluster"},
+		ResolvedExtensions: map[string]interface{}{engine.ClusterExtension: "east"},
+	}
+
+	assert.Equal(t, cluster{kubeConfig: filepath.Join("/stack", "kubeconfig"), context: "west"}, clusterOf(configMap, stack))
+	assert.Equal(t, cluster{kubeConfig: filepath.Join("/stack", "kubeconfig"), context: "east"}, clusterOf(east, stack))
+	assert.Equal(t, cluster{kubeConfig: filepath.Join("/stack", "kubeconfig"), context: "east"}, clusterOf(resolved, stack))
+}
+
+func TestMultiClusterRuntime(t *testing.T) {
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev", KubeConfig: "/kubeconfig"}}
+	east := *configMap
+	east.ID = "east:" + configMap.ID
+	east.Extensions = map[string]interface{}{engine.ClusterExtension: "east"}
+
+	var created []cluster
+	m := newMultiClusterRuntime()
+	m.newRuntime = func(c cluster) (*KubernetesRuntime, error) {
+		if c.context == "broken" {
+			return nil, errors.New("no such context")
+		}
+		created = append(created, c)
+		rt, _ := newFakeRuntime()
+		return rt, nil
+	}
+
+	ctx := context.Background()
+	for _, res := range []*models.Resource{configMap, &east, configMap, &east} {
+		response := m.Read(ctx, &runtime.ReadRequest{PlanResource: res, Stack: stack})
+		assert.Nil(t, response.Status)
+	}
+	assert.Equal(t, []cluster{{kubeConfig: "/kubeconfig"}, {kubeConfig: "/kubeconfig", context: "east"}}, created)
+
+	broken := *configMap
+	broken.Extensions = map[string]interface{}{engine.ClusterExtension: "broken"}
+	response := m.Read(ctx, &runtime.ReadRequest{PlanResource: &broken, Stack: stack})
+	assert.True(t, status.IsErr(response.Status))
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/discovery"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+)
+
+// Labels and annotations stamped on every object applied by Kusion, which record the owner of the object
+const (
+	ProjectLabel         = "kusionstack.io/project"
+	StackLabel           = "kusionstack.io/stack"
+	ClusterAnnotation    = "kusionstack.io/cluster"
+	ResourceIDAnnotation = "kusionstack.io/resource-id"
+)
+
+// stampOwnership returns a copy of the planed resource with ownership labels and annotations of the request.
+// Labels are skipped if their values are not valid label values, and the resource itself is returned if the
+// request has no project or stack
+func stampOwnership(request *runtime.ApplyRequest) *models.Resource {
+	planState := request.PlanResource
+	if request.Project == nil || request.Stack == nil {
+		return planState
+	}
+
+	// Copy attributes and metadata only, since labels and annotations are replaced with new maps when they are set
+	stamped := *planState
+	stamped.Attributes = copyMap(planState.Attributes)
+	metadata, _ := planState.Attributes["metadata"].(map[string]interface{})
+	stamped.Attributes["metadata"] = copyMap(metadata)
+	obj := &unstructured.Unstructured{Object: stamped.Attributes}
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	for k, v := range map[string]string{ProjectLabel: request.Project.Name, StackLabel: request.Stack.Name} {
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			log.Warnf("skip label %s of %s: %s", k, planState.ResourceKey(), strings.Join(errs, "; "))
+			continue
+		}
+		objLabels[k] = v
+	}
+	obj.SetLabels(objLabels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ResourceIDAnnotation] = planState.ResourceKey()
+	// only clusters named by the resource are recorded, since the context of the stack may be changed later
+	if cluster, _ := planState.ResolvedExtension(engine.ClusterExtension).(string); cluster != "" {
+		annotations[ClusterAnnotation] = cluster
+	}
+	obj.SetAnnotations(annotations)
+
+	stamped.Attributes = obj.Object
+	return &stamped
+}
+
+// ListOwned lists live objects labeled with the project and stack in all namespaces. Objects owned by other
+// objects are skipped, since they are managed by controllers instead of Kusion
+func (k *KubernetesRuntime) ListOwned(ctx context.Context, project, stack string) ([]unstructured.Unstructured, error) {
+	selector := labels.SelectorFromSet(labels.Set{ProjectLabel: project, StackLabel: stack}).String()
+
+	resourceLists, err := k.discovery.ServerPreferredResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, err
+		}
+		// Some API groups are unavailable, list the others
+		log.Warnf("discover API resources failed: %v", err)
+	}
+
+	var owned []unstructured.Unstructured
+	seen := map[types.UID]bool{}
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") || !contains(r.Verbs, "list") {
+				continue
+			}
+			objs, err := k.client.Resource(gv.WithResource(r.Name)).List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				log.Warnf("list %s failed, skip it: %v", gv.WithResource(r.Name), err)
+				continue
+			}
+			for _, obj := range objs.Items {
+				if len(obj.GetOwnerReferences()) > 0 || seen[obj.GetUID()] {
+					continue
+				}
+				seen[obj.GetUID()] = true
+				owned = append(owned, obj)
+			}
+		}
+	}
+	return owned, nil
+}
+
+// OwnedResourceID returns the ID of the owned object recorded in its annotation, or builds the ID by the object
+func OwnedResourceID(obj *unstructured.Unstructured) string {
+	if id := obj.GetAnnotations()[ResourceIDAnnotation]; id != "" {
+		return id
+	}
+	return engine.BuildIDWithCluster(obj.GetAnnotations()[ClusterAnnotation], engine.BuildIDForKubernetes(obj))
+}
+
+// AdoptedResource converts the live object to a resource which can be recorded in the state. Extensions are not
+// recovered from the object, and callers set them as the spec does
+func AdoptedResource(obj *unstructured.Unstructured) *models.Resource {
+	adopted := obj.DeepCopy()
+	normalizeServerSideFields(adopted)
+
+	return &models.Resource{
+		ID:         OwnedResourceID(obj),
+		Type:       runtime.Kubernetes,
+		Attributes: adopted.Object,
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// fakeDiscovery returns the configured resources as the preferred ones, which FakeDiscovery does not support
+type fakeDiscovery struct {
+	*discoveryfake.FakeDiscovery
+}
+
+func (d *fakeDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return d.Resources, nil
+}
+
+func TestStampOwnership(t *testing.T) {
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "foo"}}
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev", Context: "kind"}}
+
+	t.Run("stamp", func(t *testing.T) {
+		stamped := stampOwnership(&runtime.ApplyRequest{PlanResource: configMap, Project: project, Stack: stack})
+		obj := &unstructured.Unstructured{Object: stamped.Attributes}
+		assert.Equal(t, map[string]string{ProjectLabel: "foo", StackLabel: "dev"}, obj.GetLabels())
+		assert.Equal(t, map[string]string{ResourceIDAnnotation: configMap.ID}, obj.GetAnnotations())
+		// the planed resource is not modified
+		assert.NotContains(t, configMap.Attributes["metadata"], "labels")
+	})
+
+	t.Run("cluster extension", func(t *testing.T) {
+		east := *configMap
+		east.Extensions = map[string]interface{}{engine.ClusterExtension: "ref+vault://kusion/cluster"}
+		east.ResolvedExtensions = map[string]interface{}{engine.ClusterExtension: "east"}
+		stamped := stampOwnership(&runtime.ApplyRequest{PlanResource: &east, Project: project, Stack: stack})
+		obj := &unstructured.Unstructured{Object: stamped.Attributes}
+		assert.Equal(t, map[string]string{ResourceIDAnnotation: configMap.ID, ClusterAnnotation: "east"}, obj.GetAnnotations())
+	})
+
+	t.Run("invalid label value", func(t *testing.T) {
+		invalid := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev/v1"}}
+		stamped := stampOwnership(&runtime.ApplyRequest{PlanResource: configMap, Project: project, Stack: invalid})
+		obj := &unstructured.Unstructured{Object: stamped.Attributes}
+		assert.Equal(t, map[string]string{ProjectLabel: "foo"}, obj.GetLabels())
+	})
+
+	t.Run("no stack", func(t *testing.T) {
+		assert.Equal(t, configMap, stampOwnership(&runtime.ApplyRequest{PlanResource: configMap}))
+	})
+}
+
+func TestKubernetesRuntime_ListOwned(t *testing.T) {
+	newConfigMap := func(name string, labels map[string]string, owned bool) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace("foo")
+		obj.SetName(name)
+		obj.SetUID(types.UID(name))
+		obj.SetLabels(labels)
+		if owned {
+			obj.SetOwnerReferences([]metav1.OwnerReference{{Name: "owner"}})
+		}
+		return obj
+	}
+	labels := map[string]string{ProjectLabel: "foo", StackLabel: "dev"}
+
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(k8sruntime.NewScheme(),
+		map[schema.GroupVersionResource]string{{Version: "v1", Resource: "configmaps"}: "ConfigMapList"},
+		newConfigMap("a", labels, false),
+		newConfigMap("b", labels, true),
+		newConfigMap("c", map[string]string{ProjectLabel: "foo", StackLabel: "prod"}, false),
+	)
+	dc := &fakeDiscovery{&discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"get", "list"}},
+			{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: []string{"get", "list"}},
+		},
+	}}}}}
+	rt := &KubernetesRuntime{client: client, discovery: dc}
+
+	owned, err := rt.ListOwned(context.Background(), "foo", "dev")
+	assert.Nil(t, err)
+	assert.Len(t, owned, 1)
+	assert.Equal(t, "a", owned[0].GetName())
+}
+
+func TestAdoptedResource(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"namespace":       "foo",
+			"name":            "bar",
+			"resourceVersion": "1",
+			"annotations":     map[string]interface{}{ClusterAnnotation: "kind"},
+		},
+	}}
+
+	res := AdoptedResource(obj)
+	assert.Equal(t, engine.BuildIDWithCluster("kind", "v1:ConfigMap:foo:bar"), res.ID)
+	assert.Equal(t, runtime.Kubernetes, res.Type)
+	assert.Nil(t, res.Extensions)
+	_, found, _ := unstructured.NestedString(res.Attributes, "metadata", "resourceVersion")
+	assert.False(t, found)
+}
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// DefaultFieldManager is the field manager of Kusion when it is not configured
+const DefaultFieldManager = "kusion"
+
+// Keys in resource Extensions to override the apply configs of the project
+const (
+	ApplyModeExtensionKey      = "applyMode"
+	FieldManagerExtensionKey   = "fieldManager"
+	ForceConflictsExtensionKey = "forceConflicts"
+)
+
+// applyConfig is the resolved apply configs of one resource
+type applyConfig struct {
+	mode           projectstack.ApplyMode
+	fieldManager   string
+	forceConflicts bool
+}
+
+// resolveApplyConfig resolves apply configs of the planed resource. Resource Extensions, with their references
+// resolved, take precedence over configs of the project, and ForceConflicts of the request always forces the apply
+func resolveApplyConfig(request *runtime.ApplyRequest) (*applyConfig, error) {
+	c := &applyConfig{mode: projectstack.ClientSideApply, fieldManager: DefaultFieldManager}
+	if request.Project != nil && request.Project.Kubernetes != nil {
+		kc := request.Project.Kubernetes
+		if kc.ApplyMode != "" {
+			c.mode = kc.ApplyMode
+		}
+		if kc.FieldManager != "" {
+			c.fieldManager = kc.FieldManager
+		}
+		c.forceConflicts = kc.ForceConflicts
+	}
+
+	res := request.PlanResource
+	if v := res.ResolvedExtension(ApplyModeExtensionKey); v != nil {
+		c.mode = projectstack.ApplyMode(fmt.Sprint(v))
+	}
+	if v := res.ResolvedExtension(FieldManagerExtensionKey); v != nil {
+		c.fieldManager = fmt.Sprint(v)
+	}
+	if v := res.ResolvedExtension(ForceConflictsExtensionKey); v != nil {
+		force, err := strconv.ParseBool(fmt.Sprint(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of resource %s: %v", ForceConflictsExtensionKey, request.PlanResource.ID, v)
+		}
+		c.forceConflicts = force
+	}
+	if request.ForceConflicts {
+		c.forceConflicts = true
+	}
+
+	if c.mode != projectstack.ClientSideApply && c.mode != projectstack.ServerSideApply {
+		return nil, fmt.Errorf("invalid apply mode of resource %s: %s, must be %s or %s",
+			request.PlanResource.ID, c.mode, projectstack.ClientSideApply, projectstack.ServerSideApply)
+	}
+	return c, nil
+}
+
+// serverSideApply applies the planed object by server-side apply. Dry-run requests go through the same path with
+// DryRunAll, so that the preview result and conflicts are the same as the real apply
+func serverSideApply(
+	ctx context.Context,
+	resource dynamic.ResourceInterface,
+	planObj *unstructured.Unstructured,
+	planState *models.Resource,
+	c *applyConfig,
+	dryRun bool,
+) (*unstructured.Unstructured, error) {
+	data, err := planObj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	options := metav1.PatchOptions{FieldManager: c.fieldManager, Force: &c.forceConflicts}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	res, err := resource.Patch(ctx, planObj.GetName(), types.ApplyPatchType, data, options)
+	if err == nil {
+		return res, nil
+	}
+	if k8serrors.IsConflict(err) {
+		return nil, fmt.Errorf("server-side apply %s conflicts with other field managers, "+
+			"apply with --force-conflicts to take over these fields: %v", planState.ResourceKey(), err)
+	}
+	if dryRun {
+		// The dry run fails if dependencies are not created yet, like the namespace, fall back to the planed object
+		log.Errorf("ServerSideDryRun apply %s failed, fall back to the planed object; err: %v", planState.ID, err)
+		return planObj, nil
+	}
+	return nil, err
+}
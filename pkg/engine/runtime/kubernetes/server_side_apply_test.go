@@ -0,0 +1,149 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var configMap = &models.Resource{
+	ID:   "v1:ConfigMap:foo:bar",
+	Type: runtime.Kubernetes,
+	Attributes: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": "foo", "name": "bar"},
+		"data":       map[string]interface{}{"key": "value"},
+	},
+}
+
+func newFakeRuntime() (*KubernetesRuntime, *fake.FakeDynamicClient) {
+	gv := schema.GroupVersion{Version: "v1"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+	mapper.Add(gv.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	client := fake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	return &KubernetesRuntime{client: client, mapper: mapper}, client
+}
+
+func TestResolveApplyConfig(t *testing.T) {
+	serverSide := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+		Kubernetes: &projectstack.KubernetesConfig{ApplyMode: projectstack.ServerSideApply, FieldManager: "foo"},
+	}}
+	withExtensions := func(extensions map[string]interface{}) *models.Resource {
+		r := *configMap
+		r.Extensions = extensions
+		return &r
+	}
+
+	tests := []struct {
+		name    string
+		request *runtime.ApplyRequest
+		want    *applyConfig
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			request: &runtime.ApplyRequest{PlanResource: configMap},
+			want:    &applyConfig{mode: projectstack.ClientSideApply, fieldManager: DefaultFieldManager},
+		},
+		{
+			name:    "project",
+			request: &runtime.ApplyRequest{PlanResource: configMap, Project: serverSide},
+			want:    &applyConfig{mode: projectstack.ServerSideApply, fieldManager: "foo"},
+		},
+		{
+			name: "resource overrides project",
+			request: &runtime.ApplyRequest{
+				PlanResource: withExtensions(map[string]interface{}{
+					ApplyModeExtensionKey:      "ClientSide",
+					FieldManagerExtensionKey:   "bar",
+					ForceConflictsExtensionKey: "true",
+				}),
+				Project: serverSide,
+			},
+			want: &applyConfig{mode: projectstack.ClientSideApply, fieldManager: "bar", forceConflicts: true},
+		},
+		{
+			name: "resolved extensions",
+			request: &runtime.ApplyRequest{
+				PlanResource: func() *models.Resource {
+					r := withExtensions(map[string]interface{}{FieldManagerExtensionKey: "ref+vault://kusion/manager"})
+					r.ResolvedExtensions = map[string]interface{}{FieldManagerExtensionKey: "bar"}
+					return r
+				}(),
+				Project: serverSide,
+			},
+			want: &applyConfig{mode: projectstack.ServerSideApply, fieldManager: "bar"},
+		},
+		{
+			name:    "force conflicts",
+			request: &runtime.ApplyRequest{PlanResource: configMap, Project: serverSide, ForceConflicts: true},
+			want:    &applyConfig{mode: projectstack.ServerSideApply, fieldManager: "foo", forceConflicts: true},
+		},
+		{
+			name:    "invalid mode",
+			request: &runtime.ApplyRequest{PlanResource: withExtensions(map[string]interface{}{ApplyModeExtensionKey: "foo"})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveApplyConfig(tt.request)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKubernetesRuntime_ServerSideApply(t *testing.T) {
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+		Kubernetes: &projectstack.KubernetesConfig{ApplyMode: projectstack.ServerSideApply},
+	}}
+
+	t.Run("apply", func(t *testing.T) {
+		rt, client := newFakeRuntime()
+		var patchTypes []types.PatchType
+		client.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+			patch := action.(k8stesting.PatchAction)
+			patchTypes = append(patchTypes, patch.GetPatchType())
+			obj := &unstructured.Unstructured{}
+			return true, obj, obj.UnmarshalJSON(patch.GetPatch())
+		})
+
+		for _, dryRun := range []bool{true, false} {
+			response := rt.Apply(context.Background(), &runtime.ApplyRequest{PlanResource: configMap, Project: project, DryRun: dryRun})
+			assert.Nil(t, response.Status)
+			assert.Equal(t, configMap.Attributes, response.Resource.Attributes)
+		}
+		assert.Equal(t, []types.PatchType{types.ApplyPatchType, types.ApplyPatchType}, patchTypes)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		rt, client := newFakeRuntime()
+		client.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+			return true, nil, k8serrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "bar", nil)
+		})
+
+		response := rt.Apply(context.Background(), &runtime.ApplyRequest{PlanResource: configMap, Project: project, DryRun: true})
+		assert.True(t, status.IsErr(response.Status))
+		assert.Contains(t, response.Status.Message(), "--force-conflicts")
+	})
+}
@@ -0,0 +1,170 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/log"
+)
+
+// commandSpec is the parsed attributes of a Command resource
+type commandSpec struct {
+	create string
+	read   string
+	delete string
+	dir    string
+	env    []string
+}
+
+func parseCommand(attributes map[string]interface{}, dir string) (*commandSpec, error) {
+	c := &commandSpec{dir: dir}
+	var workDir string
+	for attribute, field := range map[string]*string{
+		CreateAttribute: &c.create,
+		ReadAttribute:   &c.read,
+		DeleteAttribute: &c.delete,
+		DirAttribute:    &workDir,
+	} {
+		v, ok := attributes[attribute]
+		if !ok || v == nil {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, &invalidAttributeError{attribute: attribute, value: v}
+		}
+		*field = s
+	}
+	if c.create == "" {
+		return nil, &invalidAttributeError{attribute: CreateAttribute, value: attributes[CreateAttribute]}
+	}
+	if workDir != "" {
+		c.dir = resolvePath(workDir, dir)
+	}
+
+	if v, ok := attributes[EnvironmentAttribute]; ok && v != nil {
+		env, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, &invalidAttributeError{attribute: EnvironmentAttribute, value: v}
+		}
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c.env = append(c.env, fmt.Sprintf("%s=%v", k, env[k]))
+		}
+	}
+	return c, nil
+}
+
+// run runs the script with sh -c and returns its stdout without leading and trailing spaces
+func (c *commandSpec) run(ctx context.Context, script, output string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", script)
+	cmd.Dir = c.dir
+	cmd.Env = append(append(os.Environ(), c.env...), OutputEnv+"="+output)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	log.Debugf("Run local command: %s", script)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("run %q failed: %w: %s", script, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// applyCommand runs the create script, and the read script if any, unless dryRun is true. The output is predicted
+// as the prior one in the dry-run mode
+func applyCommand(
+	ctx context.Context,
+	attributes map[string]interface{},
+	prior *models.Resource,
+	dir string,
+	dryRun bool,
+) (map[string]interface{}, error) {
+	c, err := parseCommand(attributes, dir)
+	if err != nil {
+		return nil, err
+	}
+	result := copyAttributes(attributes)
+	delete(result, OutputAttribute)
+	if dryRun {
+		if prior != nil {
+			if output, ok := prior.Attributes[OutputAttribute]; ok {
+				result[OutputAttribute] = output
+			}
+		}
+		return result, nil
+	}
+
+	output, err := c.run(ctx, c.create, "")
+	if err != nil {
+		return nil, err
+	}
+	if c.read != "" {
+		if output, err = c.run(ctx, c.read, output); err != nil {
+			return nil, err
+		}
+	}
+	result[OutputAttribute] = output
+	return result, nil
+}
+
+// readCommand returns attributes of the prior resource with the output of the read script, or nil if the read
+// script exits with a non-zero code. The prior resource is returned as is if there is no read script
+func readCommand(
+	ctx context.Context,
+	attributes map[string]interface{},
+	prior *models.Resource,
+	dir string,
+) (map[string]interface{}, error) {
+	c, err := parseCommand(attributes, dir)
+	if err != nil {
+		return nil, err
+	}
+	if c.read == "" {
+		if prior == nil {
+			return nil, nil
+		}
+		return copyAttributes(prior.Attributes), nil
+	}
+
+	// the live resource is what was applied, so that changes of scripts are detected
+	result := copyAttributes(attributes)
+	if prior != nil {
+		result = copyAttributes(prior.Attributes)
+	}
+	lastOutput, _ := result[OutputAttribute].(string)
+	output, err := c.run(ctx, c.read, lastOutput)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			log.Debugf("local resource is absent: %v", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	result[OutputAttribute] = output
+	return result, nil
+}
+
+func deleteCommand(ctx context.Context, attributes map[string]interface{}, dir string) error {
+	c, err := parseCommand(attributes, dir)
+	if err != nil {
+		return err
+	}
+	if c.delete == "" {
+		return nil
+	}
+	output, _ := attributes[OutputAttribute].(string)
+	_, err = c.run(ctx, c.delete, output)
+	return err
+}
@@ -0,0 +1,127 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultFileMode is the mode of files whose mode is not set
+const DefaultFileMode fs.FileMode = 0o644
+
+// fileSpec is the parsed attributes of a File resource
+type fileSpec struct {
+	path    string
+	content string
+	mode    fs.FileMode
+}
+
+func parseFile(attributes map[string]interface{}, dir string) (*fileSpec, error) {
+	path, _ := attributes[PathAttribute].(string)
+	if path == "" {
+		return nil, &invalidAttributeError{attribute: PathAttribute, value: attributes[PathAttribute]}
+	}
+	f := &fileSpec{path: resolvePath(path, dir), mode: DefaultFileMode}
+
+	if v, ok := attributes[ContentAttribute]; ok && v != nil {
+		content, ok := v.(string)
+		if !ok {
+			return nil, &invalidAttributeError{attribute: ContentAttribute, value: v}
+		}
+		f.content = content
+	}
+	if v, ok := attributes[ModeAttribute]; ok && v != nil {
+		// the mode must be an octal string, since numbers like 0644 are ambiguous in YAML and JSON
+		mode, ok := v.(string)
+		if !ok {
+			return nil, &invalidAttributeError{attribute: ModeAttribute, value: v}
+		}
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || perm > uint64(fs.ModePerm) {
+			return nil, &invalidAttributeError{attribute: ModeAttribute, value: v}
+		}
+		f.mode = fs.FileMode(perm)
+	}
+	return f, nil
+}
+
+// formatMode formats the mode as an octal string like 0644
+func formatMode(mode fs.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// applyFile writes the file unless dryRun is true, and returns attributes with the normalized mode
+func applyFile(attributes map[string]interface{}, dir string, dryRun bool) (map[string]interface{}, error) {
+	f, err := parseFile(attributes, dir)
+	if err != nil {
+		return nil, err
+	}
+	result := copyAttributes(attributes)
+	result[ContentAttribute] = f.content
+	result[ModeAttribute] = formatMode(f.mode)
+	if dryRun {
+		return result, nil
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(f.path, []byte(f.content), f.mode); err != nil {
+		return nil, err
+	}
+	// WriteFile doesn't change the mode of an existing file
+	if err = os.Chmod(f.path, f.mode); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readFile returns attributes with the live content and mode, or nil if the file doesn't exist
+func readFile(attributes map[string]interface{}, dir string) (map[string]interface{}, error) {
+	f, err := parseFile(attributes, dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", f.path)
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := copyAttributes(attributes)
+	result[ContentAttribute] = string(content)
+	result[ModeAttribute] = formatMode(info.Mode())
+	return result, nil
+}
+
+func deleteFile(attributes map[string]interface{}, dir string) error {
+	f, err := parseFile(attributes, dir)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// copyAttributes returns a shallow copy of attributes
+func copyAttributes(attributes map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(attributes)+1)
+	for k, v := range attributes {
+		result[k] = v
+	}
+	return result
+}
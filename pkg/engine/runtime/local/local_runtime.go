@@ -0,0 +1,227 @@
+// Package local implements the Local runtime, which manages files and commands on the machine running Kusion. It
+// is useful for bootstrapping, tests and hybrid setups, and is the reference runtime of engine tests without a
+// cluster.
+//
+// The kind attribute tells what a Local resource is. A File resource writes content to path with mode:
+//
+//	id: local:file:hello
+//	type: Local
+//	attributes:
+//	  kind: File
+//	  path: out/hello.txt   # a relative path is relative to the stack directory
+//	  content: hello
+//	  mode: "0600"          # optional, default is 0644
+//
+// A Command resource runs shell scripts with sh -c. Scripts should be idempotent, since create runs on both
+// creation and update:
+//
+//	id: local:command:db
+//	type: Local
+//	attributes:
+//	  kind: Command
+//	  create: ./create-db.sh             # required
+//	  read: ./get-db-id.sh               # optional, exits with non-zero code if the resource is absent
+//	  delete: ./delete-db.sh             # optional
+//	  dir: scripts                       # optional, default is the stack directory
+//	  environment:                       # optional
+//	    PASSWORD: $kusion_path.local:file:password.content
+//
+// The output attribute of a Command resource is computed. It is stdout of read if read is set, or stdout of create
+// otherwise. Scripts get it in the KUSION_OUTPUT environment variable: read right after create gets stdout of
+// create, and read and delete at other times get the last output. Other resources refer to attributes of Local
+// resources with implicit refs, such as $kusion_path.local:command:db.output.
+package local
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// Kinds of Local resources
+const (
+	File    = "File"
+	Command = "Command"
+)
+
+// Attributes of Local resources
+const (
+	KindAttribute = "kind"
+
+	PathAttribute    = "path"
+	ContentAttribute = "content"
+	ModeAttribute    = "mode"
+
+	CreateAttribute      = "create"
+	ReadAttribute        = "read"
+	DeleteAttribute      = "delete"
+	DirAttribute         = "dir"
+	EnvironmentAttribute = "environment"
+	OutputAttribute      = "output"
+)
+
+// OutputEnv is the environment variable of the output passed to read and delete scripts
+const OutputEnv = "KUSION_OUTPUT"
+
+var _ runtime.Runtime = (*LocalRuntime)(nil)
+
+// LocalRuntime manages Local resources. See the package doc for their attributes
+type LocalRuntime struct{}
+
+func NewLocalRuntime() (runtime.Runtime, error) {
+	return &LocalRuntime{}, nil
+}
+
+// Apply writes the file or runs the create script. Nothing is changed in the dry-run mode, and the output of a
+// command is predicted as the prior one
+func (l *LocalRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	plan := request.PlanResource
+	kind, s := kindOf(plan)
+	if status.IsErr(s) {
+		return &runtime.ApplyResponse{Status: s}
+	}
+
+	var attributes map[string]interface{}
+	var err error
+	switch kind {
+	case File:
+		attributes, err = applyFile(plan.Attributes, stackDir(request.Stack), request.DryRun)
+	default:
+		attributes, err = applyCommand(ctx, plan.Attributes, request.PriorResource, stackDir(request.Stack), request.DryRun)
+	}
+	if err != nil {
+		return &runtime.ApplyResponse{Status: errorStatus(plan, err)}
+	}
+	return &runtime.ApplyResponse{Resource: withAttributes(plan, attributes)}
+}
+
+// Read returns nil if the file doesn't exist, or the read script of the command fails. A command without the read
+// script is trusted to be the prior resource
+func (l *LocalRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	res := request.PlanResource
+	if res == nil {
+		res = request.PriorResource
+	}
+	if res == nil {
+		return &runtime.ReadResponse{}
+	}
+	kind, s := kindOf(res)
+	if status.IsErr(s) {
+		return &runtime.ReadResponse{Status: s}
+	}
+
+	var attributes map[string]interface{}
+	var err error
+	switch kind {
+	case File:
+		attributes, err = readFile(res.Attributes, stackDir(request.Stack))
+	default:
+		attributes, err = readCommand(ctx, res.Attributes, request.PriorResource, stackDir(request.Stack))
+	}
+	if err != nil {
+		return &runtime.ReadResponse{Status: errorStatus(res, err)}
+	}
+	if attributes == nil {
+		return &runtime.ReadResponse{}
+	}
+	return &runtime.ReadResponse{Resource: withAttributes(res, attributes)}
+}
+
+// Import reads the resource, and fails if it doesn't exist
+func (l *LocalRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	rsp := l.Read(ctx, &runtime.ReadRequest{PlanResource: request.PlanResource, Stack: request.Stack})
+	if status.IsErr(rsp.Status) {
+		return &runtime.ImportResponse{Status: rsp.Status}
+	}
+	if rsp.Resource == nil {
+		return &runtime.ImportResponse{Status: status.NewErrorStatusWithMsg(status.NotFound,
+			fmt.Sprintf("local resource %s is not found", request.PlanResource.ResourceKey()))}
+	}
+	return &runtime.ImportResponse{Resource: rsp.Resource}
+}
+
+// Delete removes the file or runs the delete script. A command without the delete script is only removed from
+// the state
+func (l *LocalRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	res := request.Resource
+	kind, s := kindOf(res)
+	if status.IsErr(s) {
+		return &runtime.DeleteResponse{Status: s}
+	}
+
+	var err error
+	switch kind {
+	case File:
+		err = deleteFile(res.Attributes, stackDir(request.Stack))
+	default:
+		err = deleteCommand(ctx, res.Attributes, stackDir(request.Stack))
+	}
+	if err != nil {
+		return &runtime.DeleteResponse{Status: errorStatus(res, err)}
+	}
+	return &runtime.DeleteResponse{}
+}
+
+// Watch is not supported, since Local resources are ready once they are applied
+func (l *LocalRuntime) Watch(_ context.Context, _ *runtime.WatchRequest) *runtime.WatchResponse {
+	return nil
+}
+
+func kindOf(res *models.Resource) (string, status.Status) {
+	if res == nil {
+		return "", status.NewErrorStatusWithMsg(status.InvalidArgument, "no local resource in the request")
+	}
+	kind, _ := res.Attributes[KindAttribute].(string)
+	if kind != File && kind != Command {
+		return "", status.NewErrorStatusWithMsg(status.IllegalManifest,
+			fmt.Sprintf("kind of local resource %s must be %s or %s, got %v", res.ResourceKey(), File, Command, res.Attributes[KindAttribute]))
+	}
+	return kind, nil
+}
+
+// stackDir is the base of relative paths. The working directory is used if there is no stack
+func stackDir(stack *projectstack.Stack) string {
+	if stack == nil {
+		return ""
+	}
+	return stack.GetPath()
+}
+
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) || dir == "" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func withAttributes(res *models.Resource, attributes map[string]interface{}) *models.Resource {
+	return &models.Resource{
+		ID:         res.ID,
+		Type:       res.Type,
+		Attributes: attributes,
+		DependsOn:  res.DependsOn,
+		Extensions: res.Extensions,
+	}
+}
+
+func errorStatus(res *models.Resource, err error) status.Status {
+	if _, ok := err.(*invalidAttributeError); ok {
+		return status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("local resource %s: %v", res.ResourceKey(), err))
+	}
+	return status.NewErrorStatus(fmt.Errorf("local resource %s: %v", res.ResourceKey(), err))
+}
+
+// invalidAttributeError means attributes of a resource are illegal
+type invalidAttributeError struct {
+	attribute string
+	value     interface{}
+}
+
+func (e *invalidAttributeError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.attribute, e.value)
+}
@@ -0,0 +1,143 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func newStack(t *testing.T) *projectstack.Stack {
+	return &projectstack.Stack{Path: t.TempDir()}
+}
+
+func TestLocalRuntime_File(t *testing.T) {
+	ctx := context.Background()
+	l := &LocalRuntime{}
+	stack := newStack(t)
+	path := filepath.Join(stack.Path, "foo", "bar.txt")
+	res := &models.Resource{
+		ID:         "local:file:bar",
+		Type:       runtime.Local,
+		Attributes: map[string]interface{}{KindAttribute: File, PathAttribute: "foo/bar.txt", ContentAttribute: "bar", ModeAttribute: "600"},
+	}
+	expected := map[string]interface{}{KindAttribute: File, PathAttribute: "foo/bar.txt", ContentAttribute: "bar", ModeAttribute: "0600"}
+
+	t.Run("dry run", func(t *testing.T) {
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: res, Stack: stack, DryRun: true})
+		assert.Nil(t, rsp.Status)
+		assert.Equal(t, expected, rsp.Resource.Attributes)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("apply and read", func(t *testing.T) {
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: res, Stack: stack})
+		assert.Nil(t, rsp.Status)
+		assert.Equal(t, expected, rsp.Resource.Attributes)
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		assert.Nil(t, os.WriteFile(path, []byte("changed"), 0o600))
+		readRsp := l.Read(ctx, &runtime.ReadRequest{PlanResource: res, Stack: stack})
+		assert.Nil(t, readRsp.Status)
+		assert.Equal(t, "changed", readRsp.Resource.Attributes[ContentAttribute])
+
+		importRsp := l.Import(ctx, &runtime.ImportRequest{PlanResource: res, Stack: stack})
+		assert.Nil(t, importRsp.Status)
+		assert.Equal(t, "changed", importRsp.Resource.Attributes[ContentAttribute])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Nil(t, l.Delete(ctx, &runtime.DeleteRequest{Resource: res, Stack: stack}).Status)
+		assert.NoFileExists(t, path)
+		assert.Nil(t, l.Read(ctx, &runtime.ReadRequest{PlanResource: res, Stack: stack}).Resource)
+		assert.Equal(t, status.NotFound, l.Import(ctx, &runtime.ImportRequest{PlanResource: res, Stack: stack}).Status.Code())
+
+		// deleting an absent file succeeds
+		assert.Nil(t, l.Delete(ctx, &runtime.DeleteRequest{Resource: res, Stack: stack}).Status)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		invalid := &models.Resource{ID: "local:file:bar", Attributes: map[string]interface{}{KindAttribute: File, PathAttribute: "bar", ModeAttribute: 644}}
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: invalid, Stack: stack})
+		assert.Equal(t, status.IllegalManifest, rsp.Status.Code())
+	})
+}
+
+func TestLocalRuntime_Command(t *testing.T) {
+	ctx := context.Background()
+	l := &LocalRuntime{}
+	stack := newStack(t)
+	res := &models.Resource{
+		ID:   "local:command:foo",
+		Type: runtime.Local,
+		Attributes: map[string]interface{}{
+			KindAttribute:        Command,
+			CreateAttribute:      `echo "$NAME" > created && echo id-1`,
+			ReadAttribute:        `test -f created && cat created`,
+			DeleteAttribute:      `test "$KUSION_OUTPUT" = foo && rm created`,
+			EnvironmentAttribute: map[string]interface{}{"NAME": "foo"},
+		},
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: res, Stack: stack, DryRun: true})
+		assert.Nil(t, rsp.Status)
+		assert.NotContains(t, rsp.Resource.Attributes, OutputAttribute)
+		assert.NoFileExists(t, filepath.Join(stack.Path, "created"))
+		assert.Nil(t, l.Read(ctx, &runtime.ReadRequest{PlanResource: res, Stack: stack}).Resource)
+	})
+
+	var applied *models.Resource
+	t.Run("apply and read", func(t *testing.T) {
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: res, Stack: stack})
+		assert.Nil(t, rsp.Status)
+		applied = rsp.Resource
+		assert.Equal(t, "foo", applied.Attributes[OutputAttribute])
+
+		readRsp := l.Read(ctx, &runtime.ReadRequest{PlanResource: res, PriorResource: applied, Stack: stack})
+		assert.Nil(t, readRsp.Status)
+		assert.Equal(t, applied.Attributes, readRsp.Resource.Attributes)
+
+		// the output is predicted as the prior one
+		dryRunRsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: res, PriorResource: applied, Stack: stack, DryRun: true})
+		assert.Equal(t, "foo", dryRunRsp.Resource.Attributes[OutputAttribute])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Nil(t, l.Delete(ctx, &runtime.DeleteRequest{Resource: applied, Stack: stack}).Status)
+		assert.NoFileExists(t, filepath.Join(stack.Path, "created"))
+	})
+
+	t.Run("create fails", func(t *testing.T) {
+		failed := &models.Resource{ID: "local:command:bar", Attributes: map[string]interface{}{KindAttribute: Command, CreateAttribute: "echo oops >&2; exit 1"}}
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: failed, Stack: stack})
+		assert.True(t, status.IsErr(rsp.Status))
+		assert.Contains(t, rsp.Status.Message(), "oops")
+	})
+
+	t.Run("without read", func(t *testing.T) {
+		noRead := &models.Resource{ID: "local:command:bar", Attributes: map[string]interface{}{KindAttribute: Command, CreateAttribute: "echo bar"}}
+		assert.Nil(t, l.Read(ctx, &runtime.ReadRequest{PlanResource: noRead, Stack: stack}).Resource)
+
+		rsp := l.Apply(ctx, &runtime.ApplyRequest{PlanResource: noRead, Stack: stack})
+		assert.Equal(t, "bar", rsp.Resource.Attributes[OutputAttribute])
+		readRsp := l.Read(ctx, &runtime.ReadRequest{PlanResource: noRead, PriorResource: rsp.Resource, Stack: stack})
+		assert.Equal(t, rsp.Resource.Attributes, readRsp.Resource.Attributes)
+	})
+}
+
+func TestLocalRuntime_InvalidKind(t *testing.T) {
+	rsp := (&LocalRuntime{}).Apply(context.Background(), &runtime.ApplyRequest{
+		PlanResource: &models.Resource{ID: "foo", Attributes: map[string]interface{}{KindAttribute: "Directory"}},
+	})
+	assert.Equal(t, status.IllegalManifest, rsp.Status.Code())
+}
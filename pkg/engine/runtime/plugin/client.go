@@ -0,0 +1,256 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// BinaryPrefix is the prefix of plugin binaries looked up on PATH
+const BinaryPrefix = "kusion-runtime-"
+
+// HandshakeTimeout is the max duration for a plugin to start and finish the handshake, or to exit after Close
+var HandshakeTimeout = 10 * time.Second
+
+// Runtime is a runtime.Runtime calling a plugin process
+type Runtime struct {
+	path   string
+	cmd    *exec.Cmd
+	exited chan struct{}
+	conn   *grpc.ClientConn
+	types  []models.Type
+
+	closeOnce sync.Once
+}
+
+var _ runtime.Runtime = (*Runtime)(nil)
+
+// LookPath returns the path of the plugin of the resource type on PATH
+func LookPath(t models.Type) (string, error) {
+	return exec.LookPath(BinaryPrefix + strings.ToLower(string(t)))
+}
+
+// NewRuntime starts the plugin at path and finishes the handshake. Every call starts a new plugin process, which
+// must be closed by Close once it is not used anymore
+func NewRuntime(path string) (*Runtime, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), MagicCookieKey+"="+MagicCookie)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start runtime plugin %s failed: %v", path, err)
+	}
+	go logStderr(path, stderr)
+	r := &Runtime{path: path, cmd: cmd, exited: make(chan struct{})}
+	go func() {
+		_ = cmd.Wait()
+		close(r.exited)
+	}()
+
+	var dialed bool
+	r.conn, err = grpc.Dial("stdio",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			// the plugin can't be reconnected once the pipes are closed
+			if dialed {
+				return nil, fmt.Errorf("runtime plugin %s exited", path)
+			}
+			dialed = true
+			return newStdioConn(stdout, stdin), nil
+		}),
+	)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return nil, err
+	}
+
+	// fail fast if the plugin exits before the handshake, such as a binary which is not a plugin
+	ctx, cancel := context.WithTimeout(context.Background(), HandshakeTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.exited:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	rsp := &HandshakeResponse{}
+	if err = r.conn.Invoke(ctx, fullMethod("Handshake"), &HandshakeRequest{ProtocolVersion: ProtocolVersion}, rsp); err != nil {
+		r.stop(true)
+		return nil, fmt.Errorf("handshake with runtime plugin %s failed: %v", path, err)
+	}
+	if rsp.ProtocolVersion != ProtocolVersion {
+		r.stop(true)
+		return nil, fmt.Errorf("runtime plugin %s speaks protocol version %d, but %d is required", path, rsp.ProtocolVersion, ProtocolVersion)
+	}
+	r.types = rsp.Types
+	log.Infof("Runtime plugin %s started, supported types: %v", path, rsp.Types)
+	return r, nil
+}
+
+// logStderr logs every line written to stderr by the plugin
+func logStderr(path string, stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			log.Infof("runtime plugin %s: %s", filepath.Base(path), line)
+		}
+	}
+}
+
+// Types returns resource types supported by the plugin
+func (r *Runtime) Types() []models.Type {
+	return r.types
+}
+
+// Close closes the connection, which makes the plugin exit, and kills the plugin if it doesn't exit in time. It is
+// safe to call Close more than once
+func (r *Runtime) Close() {
+	r.closeOnce.Do(func() {
+		r.stop(false)
+	})
+}
+
+// stop closes the connection and waits for the plugin to exit. The plugin is killed at once if kill is true
+func (r *Runtime) stop(kill bool) {
+	_ = r.conn.Close()
+	if kill {
+		_ = r.cmd.Process.Kill()
+	}
+	select {
+	case <-r.exited:
+	case <-time.After(HandshakeTimeout):
+		_ = r.cmd.Process.Kill()
+		<-r.exited
+	}
+}
+
+func (r *Runtime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	rsp := &ResourceResponse{}
+	if err := r.conn.Invoke(ctx, fullMethod("Apply"), request, rsp); err != nil {
+		return &runtime.ApplyResponse{Status: r.errorStatus("Apply", err)}
+	}
+	return &runtime.ApplyResponse{Resource: rsp.Resource, Change: rsp.Change, Status: fromWire(rsp.Status)}
+}
+
+func (r *Runtime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	rsp := &ResourceResponse{}
+	if err := r.conn.Invoke(ctx, fullMethod("Read"), request, rsp); err != nil {
+		return &runtime.ReadResponse{Status: r.errorStatus("Read", err)}
+	}
+	return &runtime.ReadResponse{Resource: rsp.Resource, Status: fromWire(rsp.Status)}
+}
+
+func (r *Runtime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	rsp := &ResourceResponse{}
+	if err := r.conn.Invoke(ctx, fullMethod("Import"), request, rsp); err != nil {
+		return &runtime.ImportResponse{Status: r.errorStatus("Import", err)}
+	}
+	return &runtime.ImportResponse{Resource: rsp.Resource, Status: fromWire(rsp.Status)}
+}
+
+func (r *Runtime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	rsp := &DeleteResponse{}
+	if err := r.conn.Invoke(ctx, fullMethod("Delete"), request, rsp); err != nil {
+		return &runtime.DeleteResponse{Status: r.errorStatus("Delete", err)}
+	}
+	return &runtime.DeleteResponse{Status: fromWire(rsp.Status)}
+}
+
+// Watch returns nil if the plugin doesn't support watching the resource. Events are received until the stream
+// ends or ctx is done, and then all watchers are closed
+func (r *Runtime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	stream, err := r.conn.NewStream(ctx, &serviceDesc.Streams[0], fullMethod("Watch"))
+	if err == nil {
+		err = stream.SendMsg(request)
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	header := &WatchMessage{}
+	if err == nil {
+		err = stream.RecvMsg(header)
+	}
+	if err != nil {
+		return &runtime.WatchResponse{Status: r.errorStatus("Watch", err)}
+	}
+	if len(header.IDs) == 0 {
+		if header.Status == nil {
+			return nil
+		}
+		return &runtime.WatchResponse{Status: fromWire(header.Status)}
+	}
+
+	watchers := runtime.NewWatchers()
+	channels := make([]chan watch.Event, len(header.IDs))
+	for i, id := range header.IDs {
+		channels[i] = make(chan watch.Event)
+		watchers.Insert(id, channels[i])
+	}
+	go func() {
+		defer func() {
+			for _, ch := range channels {
+				close(ch)
+			}
+		}()
+		for {
+			msg := &WatchMessage{}
+			if err := stream.RecvMsg(msg); err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					log.Errorf("watch %s by runtime plugin %s failed: %v", request.Resource.ResourceKey(), r.path, err)
+				}
+				return
+			}
+			if msg.Index < 0 || msg.Index >= len(channels) {
+				log.Warnf("runtime plugin %s sent an event of unknown watcher %d", r.path, msg.Index)
+				continue
+			}
+			event := watch.Event{Type: msg.Type, Object: &unstructured.Unstructured{Object: msg.Object}}
+			select {
+			case channels[msg.Index] <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &runtime.WatchResponse{Watchers: watchers, Status: fromWire(header.Status)}
+}
+
+func (r *Runtime) errorStatus(method string, err error) status.Status {
+	return status.NewErrorStatusWithMsg(status.Unavailable, fmt.Sprintf("call %s of runtime plugin %s failed: %v", method, r.path, err))
+}
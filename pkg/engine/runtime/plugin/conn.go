@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var errListenerClosed = errors.New("stdio listener closed")
+
+// stdioAddr is the address of both ends of a stdioConn
+type stdioAddr struct{}
+
+func (stdioAddr) Network() string { return "stdio" }
+func (stdioAddr) String() string  { return "stdio" }
+
+// stdioConn is a net.Conn reading from r and writing to w, which are stdout and stdin of the plugin on the host
+// side, or stdin and stdout of the plugin on the plugin side. Deadlines are not supported
+type stdioConn struct {
+	r io.ReadCloser
+	w io.WriteCloser
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newStdioConn(r io.ReadCloser, w io.WriteCloser) *stdioConn {
+	return &stdioConn{r: r, w: w, done: make(chan struct{})}
+}
+
+func (c *stdioConn) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if err != nil {
+		c.once.Do(func() { close(c.done) })
+	}
+	return n, err
+}
+
+func (c *stdioConn) Write(b []byte) (int, error) {
+	return c.w.Write(b)
+}
+
+func (c *stdioConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	werr := c.w.Close()
+	rerr := c.r.Close()
+	if werr != nil {
+		return werr
+	}
+	return rerr
+}
+
+func (c *stdioConn) LocalAddr() net.Addr                { return stdioAddr{} }
+func (c *stdioConn) RemoteAddr() net.Addr               { return stdioAddr{} }
+func (c *stdioConn) SetDeadline(_ time.Time) error      { return nil }
+func (c *stdioConn) SetReadDeadline(_ time.Time) error  { return nil }
+func (c *stdioConn) SetWriteDeadline(_ time.Time) error { return nil }
+
+// stdioListener accepts the stdioConn once. Later calls of Accept block until the conn or the listener is closed,
+// so that the gRPC server stops when Kusion closes stdin of the plugin
+type stdioListener struct {
+	conns chan net.Conn
+	conn  *stdioConn
+
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newStdioListener(conn *stdioConn) *stdioListener {
+	conns := make(chan net.Conn, 1)
+	conns <- conn
+	return &stdioListener{conns: conns, conn: conn, closed: make(chan struct{})}
+}
+
+func (l *stdioListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.conn.done:
+		return nil, errListenerClosed
+	case <-l.closed:
+		return nil, errListenerClosed
+	}
+}
+
+func (l *stdioListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *stdioListener) Addr() net.Addr {
+	return stdioAddr{}
+}
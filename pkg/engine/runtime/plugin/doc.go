@@ -0,0 +1,46 @@
+// Package plugin runs runtimes out of process, so that Kusion can manage a new type of infrastructure without
+// forking the engine.
+//
+// A runtime plugin is an executable implementing runtime.Runtime. Plugins written in Go call Serve in their main
+// function, and the example package is a complete plugin used in tests:
+//
+//	func main() {
+//		if err := plugin.Serve(database.NewRuntime(), "Database"); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+//
+// Resources whose type is not built in are dispatched to the plugin of their type. Plugins are configured in
+// project.yaml, and a relative path is relative to the project directory:
+//
+//	runtimePlugins:
+//	  - type: Database
+//	    path: ./bin/kusion-runtime-database
+//
+// Types not configured are looked up on PATH as kusion-runtime-<type in lower case>, such as kusion-runtime-database.
+// Built-in types like Kubernetes and Terraform can't be managed by plugins. Plugins are started for each operation,
+// and exit when the operation ends.
+//
+// Kusion starts the plugin with the environment variable KUSION_RUNTIME_PLUGIN set to MagicCookie, and talks to it
+// with gRPC over stdin and stdout of the plugin. Messages are encoded in JSON instead of protobuf, and their schemas
+// are the request and response types of the runtime package and this package. The service is
+// kusion.runtime.v1.Runtime:
+//
+//	Handshake(HandshakeRequest) returns (HandshakeResponse)
+//	Apply(Request{runtime.ApplyRequest}) returns (ResourceResponse)
+//	Read(Request{runtime.ReadRequest}) returns (ResourceResponse)
+//	Import(Request{runtime.ImportRequest}) returns (ResourceResponse)
+//	Delete(Request{runtime.DeleteRequest}) returns (DeleteResponse)
+//	Watch(Request{runtime.WatchRequest}) returns (stream WatchMessage)
+//
+// Runtime requests are wrapped in Request, which carries extensions of the resources resolved by the engine, such as
+// providerMeta with secrets read from Vault. Plugins read them by Resource.ResolvedExtension, and the resolved values
+// are never sent back in responses.
+//
+// Handshake is called first, and the plugin is rejected if its ProtocolVersion differs from the one of Kusion. The
+// first message of Watch carries IDs of all watchers, and each of the following messages carries an event of the
+// watcher at Index. Watchers are closed when the stream ends.
+//
+// Stdout of the plugin is reserved for the protocol, and every line written to stderr is logged by Kusion.
+package plugin
@@ -0,0 +1,103 @@
+// Package example is an example runtime plugin keeping resources of the Example type in memory. It shows how to
+// write a plugin, and is used in tests of the plugin package. The plugin binary is built from
+// ./kusion-runtime-example
+package example
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// Type is the resource type managed by the example plugin
+const Type models.Type = "Example"
+
+// Runtime keeps applied resources in memory, so they live as long as the plugin process
+type Runtime struct {
+	mu        sync.Mutex
+	resources map[string]*models.Resource
+}
+
+var _ runtime.Runtime = (*Runtime)(nil)
+
+func NewRuntime() *Runtime {
+	return &Runtime{resources: map[string]*models.Resource{}}
+}
+
+func (r *Runtime) Apply(_ context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	plan := request.PlanResource
+	if plan == nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatusWithMsg(status.InvalidArgument, "no plan resource")}
+	}
+	if plan.Type != Type {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatusWithMsg(status.InvalidArgument,
+			fmt.Sprintf("resource %s of type %s is not supported", plan.ID, plan.Type))}
+	}
+	if request.DryRun {
+		return &runtime.ApplyResponse{Resource: plan}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources[plan.ID] = plan.DeepCopy()
+	return &runtime.ApplyResponse{Resource: plan}
+}
+
+func (r *Runtime) Read(_ context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	res := request.PlanResource
+	if res == nil {
+		res = request.PriorResource
+	}
+	if res == nil {
+		return &runtime.ReadResponse{}
+	}
+	return &runtime.ReadResponse{Resource: r.get(res.ID)}
+}
+
+func (r *Runtime) Import(_ context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	live := r.get(request.PlanResource.ID)
+	if live == nil {
+		return &runtime.ImportResponse{Status: status.NewErrorStatusWithMsg(status.NotFound,
+			fmt.Sprintf("resource %s is not found", request.PlanResource.ID))}
+	}
+	return &runtime.ImportResponse{Resource: live}
+}
+
+func (r *Runtime) Delete(_ context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.resources, request.Resource.ID)
+	return &runtime.DeleteResponse{}
+}
+
+// Watch sends an Added event with attributes of the resource and closes the watcher. It returns nil if the
+// resource is not applied
+func (r *Runtime) Watch(_ context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	live := r.get(request.Resource.ID)
+	if live == nil {
+		return nil
+	}
+	events := make(chan watch.Event, 1)
+	events <- watch.Event{Type: watch.Added, Object: &unstructured.Unstructured{Object: live.Attributes}}
+	close(events)
+
+	watchers := runtime.NewWatchers()
+	watchers.Insert(live.ID, events)
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+func (r *Runtime) get(id string) *models.Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res, ok := r.resources[id]; ok {
+		return res.DeepCopy()
+	}
+	return nil
+}
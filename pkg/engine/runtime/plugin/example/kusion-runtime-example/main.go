@@ -0,0 +1,18 @@
+// Command kusion-runtime-example is the example runtime plugin. Put it on PATH to apply resources of the Example
+// type with Kusion
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin/example"
+)
+
+func main() {
+	if err := plugin.Serve(example.NewRuntime(), example.Type); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,275 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin/example"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// testPluginEnv makes the test binary serve the example runtime, so tests start it as a plugin
+const testPluginEnv = "KUSION_TEST_RUNTIME_PLUGIN"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "resolved":
+		if err := Serve(&resolvedRuntime{Runtime: example.NewRuntime()}, example.Type); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "change":
+		if err := Serve(&changeRuntime{Runtime: example.NewRuntime()}, example.Type); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "incompatible":
+		if err := serve(example.NewRuntime(), ProtocolVersion+1, []models.Type{example.Type}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		if err := Serve(example.NewRuntime(), example.Type); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+// resolvedRuntime applies resources with the resolved token extension saved in attributes, which tells tests what
+// the plugin receives
+type resolvedRuntime struct {
+	runtime.Runtime
+}
+
+func (r *resolvedRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	res := *request.PlanResource
+	res.Attributes = map[string]interface{}{"token": res.ResolvedExtension("token")}
+	return r.Runtime.Apply(ctx, &runtime.ApplyRequest{PlanResource: &res})
+}
+
+// changeRuntime plans a replacement of the resource in the dry-run mode
+type changeRuntime struct {
+	runtime.Runtime
+}
+
+func (r *changeRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	rsp := r.Runtime.Apply(ctx, request)
+	if request.DryRun {
+		rsp.Change = &runtime.ResourceChange{
+			Actions:      []string{"delete", "create"},
+			AfterUnknown: map[string]interface{}{"id": true},
+			Replacements: []runtime.Replacement{{Reason: "size can not be updated", ForcedBy: []string{"size"}}},
+		}
+	}
+	return rsp
+}
+
+// pluginBinary writes a script starting the test binary as a plugin
+func pluginBinary(t *testing.T, mode string) string {
+	self, err := os.Executable()
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), BinaryPrefix+"example")
+	script := fmt.Sprintf("#!/bin/sh\n%s=%s exec %s\n", testPluginEnv, mode, self)
+	assert.Nil(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func TestRuntime(t *testing.T) {
+	r, err := NewRuntime(pluginBinary(t, "serve"))
+	assert.Nil(t, err)
+	defer r.Close()
+	assert.Equal(t, []models.Type{example.Type}, r.Types())
+
+	ctx := context.Background()
+	res := &models.Resource{ID: "foo", Type: example.Type, Attributes: map[string]interface{}{"size": "small"}}
+
+	t.Run("apply", func(t *testing.T) {
+		rsp := r.Apply(ctx, &runtime.ApplyRequest{PlanResource: res})
+		assert.Nil(t, rsp.Status)
+		assert.Equal(t, res, rsp.Resource)
+
+		rsp = r.Apply(ctx, &runtime.ApplyRequest{PlanResource: &models.Resource{ID: "bar", Type: "Kubernetes"}})
+		assert.True(t, status.IsErr(rsp.Status))
+		assert.Equal(t, status.InvalidArgument, rsp.Status.Code())
+	})
+
+	t.Run("read and import", func(t *testing.T) {
+		rsp := r.Read(ctx, &runtime.ReadRequest{PlanResource: res})
+		assert.Nil(t, rsp.Status)
+		assert.Equal(t, res, rsp.Resource)
+
+		importRsp := r.Import(ctx, &runtime.ImportRequest{PlanResource: res})
+		assert.Nil(t, importRsp.Status)
+		assert.Equal(t, res, importRsp.Resource)
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		rsp := r.Watch(ctx, &runtime.WatchRequest{Resource: res})
+		assert.NotNil(t, rsp)
+		assert.Equal(t, []string{"foo"}, rsp.Watchers.IDs)
+		var events []watch.Event
+		for e := range rsp.Watchers.Watchers[0] {
+			events = append(events, e)
+		}
+		assert.Equal(t, []watch.Event{{
+			Type:   watch.Added,
+			Object: &unstructured.Unstructured{Object: map[string]interface{}{"size": "small"}},
+		}}, events)
+
+		assert.Nil(t, r.Watch(ctx, &runtime.WatchRequest{Resource: &models.Resource{ID: "bar"}}))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rsp := r.Delete(ctx, &runtime.DeleteRequest{Resource: res})
+		assert.Nil(t, rsp.Status)
+		assert.Nil(t, r.Read(ctx, &runtime.ReadRequest{PlanResource: res}).Resource)
+	})
+
+	t.Run("start a new plugin every time", func(t *testing.T) {
+		another, err := NewRuntime(r.path)
+		assert.Nil(t, err)
+		assert.NotSame(t, r, another)
+
+		another.Close()
+		another.Close()
+		rsp := another.Read(ctx, &runtime.ReadRequest{PlanResource: res})
+		assert.True(t, status.IsErr(rsp.Status))
+		assert.Nil(t, r.Read(ctx, &runtime.ReadRequest{PlanResource: res}).Status)
+	})
+}
+
+func TestRuntime_Change(t *testing.T) {
+	r, err := NewRuntime(pluginBinary(t, "change"))
+	assert.Nil(t, err)
+	defer r.Close()
+
+	ctx := context.Background()
+	res := &models.Resource{ID: "foo", Type: example.Type, Attributes: map[string]interface{}{"size": "small"}}
+	rsp := r.Apply(ctx, &runtime.ApplyRequest{PlanResource: res, DryRun: true})
+	assert.Nil(t, rsp.Status)
+	assert.Equal(t, &runtime.ResourceChange{
+		Actions:      []string{"delete", "create"},
+		AfterUnknown: map[string]interface{}{"id": true},
+		Replacements: []runtime.Replacement{{Reason: "size can not be updated", ForcedBy: []string{"size"}}},
+	}, rsp.Change)
+
+	rsp = r.Apply(ctx, &runtime.ApplyRequest{PlanResource: res})
+	assert.Nil(t, rsp.Status)
+	assert.Nil(t, rsp.Change)
+}
+
+func TestRuntime_ResolvedExtensions(t *testing.T) {
+	r, err := NewRuntime(pluginBinary(t, "resolved"))
+	assert.Nil(t, err)
+	defer r.Close()
+
+	res := &models.Resource{
+		ID:                 "foo",
+		Type:               example.Type,
+		Extensions:         map[string]interface{}{"token": "ref+vault://kusion/token"},
+		ResolvedExtensions: map[string]interface{}{"token": "secret"},
+	}
+	rsp := r.Apply(context.Background(), &runtime.ApplyRequest{PlanResource: res})
+	assert.Nil(t, rsp.Status)
+	assert.Equal(t, map[string]interface{}{"token": "secret"}, rsp.Resource.Attributes)
+	assert.Equal(t, map[string]interface{}{"token": "ref+vault://kusion/token"}, rsp.Resource.Extensions)
+	assert.Nil(t, rsp.Resource.ResolvedExtensions)
+}
+
+func TestJSONCodec(t *testing.T) {
+	resolved := func(id string) *models.Resource {
+		return &models.Resource{
+			ID:                 id,
+			Extensions:         map[string]interface{}{"token": "ref+vault://kusion/token"},
+			ResolvedExtensions: map[string]interface{}{"token": id},
+		}
+	}
+	tests := []struct {
+		name string
+		in   interface{}
+		out  interface{}
+	}{
+		{
+			name: "apply",
+			in:   &runtime.ApplyRequest{PriorResource: resolved("prior"), PlanResource: resolved("plan"), DryRun: true},
+			out:  &runtime.ApplyRequest{},
+		},
+		{
+			name: "read without prior resource",
+			in:   &runtime.ReadRequest{PlanResource: resolved("plan")},
+			out:  &runtime.ReadRequest{},
+		},
+		{
+			name: "delete",
+			in:   &runtime.DeleteRequest{Resource: resolved("foo")},
+			out:  &runtime.DeleteRequest{},
+		},
+		{
+			name: "not resolved",
+			in:   &runtime.WatchRequest{Resource: &models.Resource{ID: "foo"}},
+			out:  &runtime.WatchRequest{},
+		},
+		{
+			name: "not a request",
+			in:   &HandshakeRequest{ProtocolVersion: ProtocolVersion},
+			out:  &HandshakeRequest{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := jsonCodec{}.Marshal(tt.in)
+			assert.Nil(t, err)
+			assert.Nil(t, jsonCodec{}.Unmarshal(data, tt.out))
+			assert.Equal(t, tt.in, tt.out)
+		})
+	}
+}
+
+func TestNewRuntime(t *testing.T) {
+	t.Run("incompatible protocol version", func(t *testing.T) {
+		_, err := NewRuntime(pluginBinary(t, "incompatible"))
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "incompatible plugin protocol version")
+	})
+
+	t.Run("not a plugin", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "foo")
+		assert.Nil(t, os.WriteFile(path, []byte("#!/bin/sh\necho foo\n"), 0o755))
+		_, err := NewRuntime(path)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := NewRuntime(filepath.Join(t.TempDir(), "foo"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestServe(t *testing.T) {
+	t.Setenv(MagicCookieKey, "")
+	assert.Equal(t, ErrNotStartedByKusion, Serve(example.NewRuntime(), example.Type))
+}
+
+func TestLookPath(t *testing.T) {
+	dir := filepath.Dir(pluginBinary(t, "serve"))
+	t.Setenv("PATH", dir)
+	path, err := LookPath(example.Type)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, BinaryPrefix+"example"), path)
+
+	_, err = LookPath("Database")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,245 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const (
+	// ProtocolVersion is the version of the plugin protocol. It is increased on incompatible changes
+	ProtocolVersion = 2
+
+	// MagicCookieKey and MagicCookie tell a plugin that it is started by Kusion rather than by a user
+	MagicCookieKey = "KUSION_RUNTIME_PLUGIN"
+	MagicCookie    = "d3b2a0c4-kusion-runtime-plugin"
+
+	serviceName = "kusion.runtime.v1.Runtime"
+)
+
+// HandshakeRequest is the first request sent to the plugin
+type HandshakeRequest struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+// HandshakeResponse tells the protocol version and resource types supported by the plugin
+type HandshakeResponse struct {
+	ProtocolVersion int           `json:"protocolVersion"`
+	Types           []models.Type `json:"types,omitempty"`
+}
+
+// Status is status.Status on the wire
+type Status struct {
+	Kind    status.Kind `json:"kind"`
+	Code    status.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+// Request is a runtime request on the wire. ResolvedExtensions of resources are not encoded with the resources, so
+// they are carried beside the request, keyed by names of the resource fields in the request, such as PlanResource
+type Request struct {
+	Request            json.RawMessage                   `json:"request"`
+	ResolvedExtensions map[string]map[string]interface{} `json:"resolvedExtensions,omitempty"`
+}
+
+// ResourceResponse is the response of Apply, Read and Import. Change is only returned by Apply in the dry-run mode
+type ResourceResponse struct {
+	Resource *models.Resource        `json:"resource,omitempty"`
+	Change   *runtime.ResourceChange `json:"change,omitempty"`
+	Status   *Status                 `json:"status,omitempty"`
+}
+
+// DeleteResponse is the response of Delete
+type DeleteResponse struct {
+	Status *Status `json:"status,omitempty"`
+}
+
+// WatchMessage is a message in the stream of Watch. The first message carries IDs of watchers or an error status,
+// and the others carry events of the watcher at Index
+type WatchMessage struct {
+	IDs    []string               `json:"ids,omitempty"`
+	Status *Status                `json:"status,omitempty"`
+	Index  int                    `json:"index,omitempty"`
+	Type   watch.EventType        `json:"type,omitempty"`
+	Object map[string]interface{} `json:"object,omitempty"`
+}
+
+func toWire(s status.Status) *Status {
+	if s == nil {
+		return nil
+	}
+	return &Status{Kind: s.Kind(), Code: s.Code(), Message: s.Message()}
+}
+
+func fromWire(s *Status) status.Status {
+	if s == nil {
+		return nil
+	}
+	return status.NewBaseStatus(s.Kind, s.Code, s.Message)
+}
+
+// jsonCodec encodes gRPC messages in JSON, so that the protocol has no generated code. Runtime requests are
+// wrapped in Request, so that plugins receive extensions resolved by the engine
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	resources, ok := requestResources(v)
+	if !ok {
+		return json.Marshal(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req := &Request{Request: data}
+	for name, res := range resources {
+		if res == nil || len(res.ResolvedExtensions) == 0 {
+			continue
+		}
+		if req.ResolvedExtensions == nil {
+			req.ResolvedExtensions = map[string]map[string]interface{}{}
+		}
+		req.ResolvedExtensions[name] = res.ResolvedExtensions
+	}
+	return json.Marshal(req)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := requestResources(v); !ok {
+		return json.Unmarshal(data, v)
+	}
+	req := &Request{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(req.Request, v); err != nil {
+		return err
+	}
+	// resources are decoded into new objects, so they are looked up again
+	resources, _ := requestResources(v)
+	for name, res := range resources {
+		if res != nil {
+			res.ResolvedExtensions = req.ResolvedExtensions[name]
+		}
+	}
+	return nil
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// requestResources returns resources of the runtime request keyed by names of their fields, and false if v is not a
+// runtime request
+func requestResources(v interface{}) (map[string]*models.Resource, bool) {
+	switch req := v.(type) {
+	case *runtime.ApplyRequest:
+		return map[string]*models.Resource{"PriorResource": req.PriorResource, "PlanResource": req.PlanResource}, true
+	case *runtime.ReadRequest:
+		return map[string]*models.Resource{"PriorResource": req.PriorResource, "PlanResource": req.PlanResource}, true
+	case *runtime.ImportRequest:
+		return map[string]*models.Resource{"PlanResource": req.PlanResource}, true
+	case *runtime.DeleteRequest:
+		return map[string]*models.Resource{"Resource": req.Resource}, true
+	case *runtime.WatchRequest:
+		return map[string]*models.Resource{"Resource": req.Resource}, true
+	default:
+		return nil, false
+	}
+}
+
+// runtimeServer is the server side of the service
+type runtimeServer interface {
+	Handshake(context.Context, *HandshakeRequest) (*HandshakeResponse, error)
+	Apply(context.Context, *runtime.ApplyRequest) (*ResourceResponse, error)
+	Read(context.Context, *runtime.ReadRequest) (*ResourceResponse, error)
+	Import(context.Context, *runtime.ImportRequest) (*ResourceResponse, error)
+	Delete(context.Context, *runtime.DeleteRequest) (*DeleteResponse, error)
+	Watch(*runtime.WatchRequest, grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*runtimeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Handshake",
+			Handler: unaryHandler("Handshake", func() interface{} { return &HandshakeRequest{} },
+				func(srv runtimeServer, ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.Handshake(ctx, req.(*HandshakeRequest))
+				}),
+		},
+		{
+			MethodName: "Apply",
+			Handler: unaryHandler("Apply", func() interface{} { return &runtime.ApplyRequest{} },
+				func(srv runtimeServer, ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.Apply(ctx, req.(*runtime.ApplyRequest))
+				}),
+		},
+		{
+			MethodName: "Read",
+			Handler: unaryHandler("Read", func() interface{} { return &runtime.ReadRequest{} },
+				func(srv runtimeServer, ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.Read(ctx, req.(*runtime.ReadRequest))
+				}),
+		},
+		{
+			MethodName: "Import",
+			Handler: unaryHandler("Import", func() interface{} { return &runtime.ImportRequest{} },
+				func(srv runtimeServer, ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.Import(ctx, req.(*runtime.ImportRequest))
+				}),
+		},
+		{
+			MethodName: "Delete",
+			Handler: unaryHandler("Delete", func() interface{} { return &runtime.DeleteRequest{} },
+				func(srv runtimeServer, ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.Delete(ctx, req.(*runtime.DeleteRequest))
+				}),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := &runtime.WatchRequest{}
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(runtimeServer).Watch(req, stream)
+			},
+		},
+	},
+}
+
+// unaryHandler adapts a method of runtimeServer to grpc.MethodDesc. newReq returns an empty request to decode into
+func unaryHandler(
+	method string,
+	newReq func() interface{},
+	call func(srv runtimeServer, ctx context.Context, req interface{}) (interface{}, error),
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := newReq()
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(runtimeServer), ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod(method)}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(runtimeServer), ctx, req)
+		})
+	}
+}
+
+func fullMethod(method string) string {
+	return "/" + serviceName + "/" + method
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+// ErrNotStartedByKusion is returned by Serve if the plugin is executed directly
+var ErrNotStartedByKusion = errors.New("this binary is a Kusion runtime plugin, and is not meant to be executed directly")
+
+// Serve serves rt as a runtime plugin of resource types over stdin and stdout until Kusion closes stdin. It is
+// called in the main function of the plugin, and stdout must not be written by anything else
+func Serve(rt runtime.Runtime, types ...models.Type) error {
+	return serve(rt, ProtocolVersion, types)
+}
+
+func serve(rt runtime.Runtime, version int, types []models.Type) error {
+	if os.Getenv(MagicCookieKey) != MagicCookie {
+		return ErrNotStartedByKusion
+	}
+
+	// keep stray writes to stdout, such as fmt.Println, out of the protocol
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdout = os.Stderr
+
+	s := grpc.NewServer(grpc.ForceServerCodec(jsonCodec{}))
+	s.RegisterService(&serviceDesc, &server{runtime: rt, version: version, types: types})
+	err := s.Serve(newStdioListener(newStdioConn(stdin, stdout)))
+	if errors.Is(err, errListenerClosed) {
+		return nil
+	}
+	return err
+}
+
+// server implements runtimeServer with a runtime.Runtime
+type server struct {
+	runtime runtime.Runtime
+	version int
+	types   []models.Type
+}
+
+var _ runtimeServer = (*server)(nil)
+
+func (s *server) Handshake(_ context.Context, req *HandshakeRequest) (*HandshakeResponse, error) {
+	if req.ProtocolVersion != s.version {
+		return nil, fmt.Errorf("incompatible plugin protocol version %d, and the plugin supports %d", req.ProtocolVersion, s.version)
+	}
+	return &HandshakeResponse{ProtocolVersion: s.version, Types: s.types}, nil
+}
+
+func (s *server) Apply(ctx context.Context, req *runtime.ApplyRequest) (*ResourceResponse, error) {
+	rsp := s.runtime.Apply(ctx, req)
+	return &ResourceResponse{Resource: rsp.Resource, Change: rsp.Change, Status: toWire(rsp.Status)}, nil
+}
+
+func (s *server) Read(ctx context.Context, req *runtime.ReadRequest) (*ResourceResponse, error) {
+	rsp := s.runtime.Read(ctx, req)
+	return &ResourceResponse{Resource: rsp.Resource, Status: toWire(rsp.Status)}, nil
+}
+
+func (s *server) Import(ctx context.Context, req *runtime.ImportRequest) (*ResourceResponse, error) {
+	rsp := s.runtime.Import(ctx, req)
+	return &ResourceResponse{Resource: rsp.Resource, Status: toWire(rsp.Status)}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *runtime.DeleteRequest) (*DeleteResponse, error) {
+	rsp := s.runtime.Delete(ctx, req)
+	return &DeleteResponse{Status: toWire(rsp.Status)}, nil
+}
+
+// Watch sends IDs of watchers in the first message, then events of all watchers until they are closed or Kusion
+// cancels the stream
+func (s *server) Watch(req *runtime.WatchRequest, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	rsp := s.runtime.Watch(ctx, req)
+	if rsp == nil {
+		return stream.SendMsg(&WatchMessage{})
+	}
+	if rsp.Watchers == nil {
+		return stream.SendMsg(&WatchMessage{Status: toWire(rsp.Status)})
+	}
+	if err := stream.SendMsg(&WatchMessage{IDs: rsp.Watchers.IDs, Status: toWire(rsp.Status)}); err != nil {
+		return err
+	}
+
+	// SendMsg is not safe to be called in multiple goroutines
+	var mu sync.Mutex
+	errs := make(chan error, len(rsp.Watchers.Watchers))
+	var wg sync.WaitGroup
+	for i, watcher := range rsp.Watchers.Watchers {
+		wg.Add(1)
+		go func(index int, watcher <-chan watch.Event) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event, ok := <-watcher:
+					if !ok {
+						return
+					}
+					obj, err := toUnstructured(event.Object)
+					if err != nil {
+						errs <- err
+						return
+					}
+					mu.Lock()
+					err = stream.SendMsg(&WatchMessage{Index: index, Type: event.Type, Object: obj})
+					mu.Unlock()
+					if err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}(i, watcher)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func toUnstructured(obj k8sruntime.Object) (map[string]interface{}, error) {
+	switch o := obj.(type) {
+	case nil:
+		return nil, nil
+	case *unstructured.Unstructured:
+		return o.Object, nil
+	default:
+		return k8sruntime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	}
+}
@@ -13,6 +13,7 @@ import (
 const (
 	Kubernetes models.Type = "Kubernetes"
 	Terraform  models.Type = "Terraform"
+	Local      models.Type = "Local"
 )
 
 // Runtime represents an actual infrastructure runtime managed by Kusion and every runtime implements this interface can be orchestrated
@@ -52,18 +53,60 @@ type ApplyRequest struct {
 	// Stack contains info about where this command is invoked
 	Stack *projectstack.Stack
 
+	// Project contains configs of the project that this resource belongs to
+	Project *projectstack.Project
+
 	// DryRun means this a dry-run request and will not make any changes in actual infra
 	DryRun bool
+
+	// ForceConflicts means taking over fields owned by others when the runtime detects conflicts
+	ForceConflicts bool
 }
 
 type ApplyResponse struct {
 	// Resource is the result returned by Runtime
 	Resource *models.Resource
 
+	// Change is the change planned in the dry-run mode. It is optional, and returned by runtimes that know more about
+	// the change than the planned resource, such as the Terraform runtime
+	Change *ResourceChange
+
 	// Status contains messages will show to users
 	Status status.Status
 }
 
+// ResourceChange is the change of a resource planned by the runtime, which shows attributes unknown until apply,
+// sensitive attributes and replacements in previews. It doesn't carry values of attributes, which may be sensitive,
+// and they are compared between the prior and planned resources instead
+type ResourceChange struct {
+	// Actions are actions planned by the runtime, such as ["update"] or ["delete", "create"]. They are empty if the
+	// change is aggregated from changes of parts of the resource, such as resources in a Terraform module
+	Actions []string `json:"actions,omitempty" yaml:"actions,omitempty"`
+
+	// AfterUnknown has the structure of attributes, whose leaves are true if the attributes are unknown until apply
+	AfterUnknown interface{} `json:"afterUnknown,omitempty" yaml:"afterUnknown,omitempty"`
+
+	// BeforeSensitive and AfterSensitive have the structure of attributes, whose leaves are true if the attributes
+	// are sensitive
+	BeforeSensitive interface{} `json:"beforeSensitive,omitempty" yaml:"beforeSensitive,omitempty"`
+	AfterSensitive  interface{} `json:"afterSensitive,omitempty" yaml:"afterSensitive,omitempty"`
+
+	// Replacements are replacements of the resource or its parts planned by the runtime
+	Replacements []Replacement `json:"replacements,omitempty" yaml:"replacements,omitempty"`
+}
+
+// Replacement is a planned replacement, which deletes and creates an object instead of updating it
+type Replacement struct {
+	// Path is the path of the replaced part in attributes. It is empty if the resource itself is replaced
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Reason is why the object is replaced
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	// ForcedBy are paths of attributes whose changes force the replacement, relative to the replaced object
+	ForcedBy []string `json:"forcedBy,omitempty" yaml:"forcedBy,omitempty"`
+}
+
 type ReadRequest struct {
 	// PriorResource is the last applied resource saved in state storage
 	PriorResource *models.Resource
@@ -73,6 +116,9 @@ type ReadRequest struct {
 
 	// Stack contains info about where this command is invoked
 	Stack *projectstack.Stack
+
+	// Project contains configs of the project that this resource belongs to
+	Project *projectstack.Project
 }
 
 type ReadResponse struct {
@@ -103,6 +149,9 @@ type DeleteRequest struct {
 	// Resource represents the resource we want to delete from the actual infra
 	Resource *models.Resource
 
+	// Project contains configs of the project, such as the deletion policy of Kubernetes resources
+	Project *projectstack.Project
+
 	// Stack contains info about where this command is invoked
 	Stack *projectstack.Stack
 }
@@ -115,6 +164,12 @@ type DeleteResponse struct {
 type WatchRequest struct {
 	// Resource represents the resource we want to watch from the actual infra
 	Resource *models.Resource
+
+	// Stack contains info about where this command is invoked
+	Stack *projectstack.Stack
+
+	// Project contains configs of the project that this resource belongs to
+	Project *projectstack.Project
 }
 
 type WatchResponse struct {
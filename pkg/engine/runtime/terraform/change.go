@@ -0,0 +1,141 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
+)
+
+// replaceReasons are reasons of replacements shown in previews, keyed by action reasons in Terraform plans
+var replaceReasons = map[string]string{
+	"replace_because_cannot_update": "attributes can't be updated in place",
+	"replace_because_tainted":       "the resource is tainted",
+	"replace_by_request":            "the replacement is requested",
+	"replace_by_triggers":           "the replacement is triggered by replace_triggered_by",
+}
+
+// resourceChange returns the change of the resource in the root module of the plan, or nil if there isn't one
+func resourceChange(pr *tfops.PlanRepresentation) (*runtime.ResourceChange, error) {
+	for i := range pr.ResourceChanges {
+		rc := &pr.ResourceChanges[i]
+		if rc.ModuleAddress == "" && rc.Mode != tfops.DataMode {
+			return convertResourceChange(rc, "")
+		}
+	}
+	return nil, nil
+}
+
+// moduleChange aggregates changes of resources in the module. Their masks are keyed by their addresses in the
+// module under the resources attribute, like attributes of the module. Nil is returned if there aren't any
+func moduleChange(pr *tfops.PlanRepresentation, res *models.Resource) (*runtime.ResourceChange, error) {
+	prefix := "module." + tfops.ModuleName(res) + "."
+	afterUnknown := map[string]interface{}{}
+	beforeSensitive := map[string]interface{}{}
+	afterSensitive := map[string]interface{}{}
+	var replacements []runtime.Replacement
+	for i := range pr.ResourceChanges {
+		rc := &pr.ResourceChanges[i]
+		if !strings.HasPrefix(rc.Address, prefix) || rc.Mode == tfops.DataMode {
+			continue
+		}
+		address := strings.TrimPrefix(rc.Address, prefix)
+		c, err := convertResourceChange(rc, tfops.ModuleResourcesAttribute+"."+address)
+		if err != nil {
+			return nil, err
+		}
+		afterUnknown[address] = c.AfterUnknown
+		beforeSensitive[address] = c.BeforeSensitive
+		afterSensitive[address] = c.AfterSensitive
+		replacements = append(replacements, c.Replacements...)
+	}
+	if len(afterUnknown) == 0 {
+		return nil, nil
+	}
+
+	return &runtime.ResourceChange{
+		AfterUnknown:    map[string]interface{}{tfops.ModuleResourcesAttribute: afterUnknown},
+		BeforeSensitive: map[string]interface{}{tfops.ModuleResourcesAttribute: beforeSensitive},
+		AfterSensitive:  map[string]interface{}{tfops.ModuleResourcesAttribute: afterSensitive},
+		Replacements:    replacements,
+	}, nil
+}
+
+// convertResourceChange converts the Terraform resource change. The path is the path of the resource in attributes
+// of the Kusion resource, which is empty if they are the same one
+func convertResourceChange(rc *tfops.ResourceChange, path string) (*runtime.ResourceChange, error) {
+	c := &runtime.ResourceChange{Actions: rc.Change.Actions}
+	values := []struct {
+		raw   json.RawMessage
+		value *interface{}
+	}{
+		{raw: rc.Change.AfterUnknown, value: &c.AfterUnknown},
+		{raw: rc.Change.BeforeSensitive, value: &c.BeforeSensitive},
+		{raw: rc.Change.AfterSensitive, value: &c.AfterSensitive},
+	}
+	for _, v := range values {
+		if len(v.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(v.raw, v.value); err != nil {
+			return nil, fmt.Errorf("json unmarshal change of %s failed: %v", rc.Address, err)
+		}
+	}
+
+	if !isReplace(rc.Change.Actions) {
+		return c, nil
+	}
+	var replacePaths [][]interface{}
+	if len(rc.Change.ReplacePaths) > 0 {
+		if err := json.Unmarshal(rc.Change.ReplacePaths, &replacePaths); err != nil {
+			return nil, fmt.Errorf("json unmarshal replace paths of %s failed: %v", rc.Address, err)
+		}
+	}
+	forcedBy := make([]string, 0, len(replacePaths))
+	for _, p := range replacePaths {
+		forcedBy = append(forcedBy, attributePath(p))
+	}
+	reason, ok := replaceReasons[rc.ActionReason]
+	if !ok {
+		reason = rc.ActionReason
+	}
+	c.Replacements = []runtime.Replacement{{Path: path, Reason: reason, ForcedBy: forcedBy}}
+	return c, nil
+}
+
+// isReplace returns true if the actions delete and create the object
+func isReplace(actions []string) bool {
+	var deleted, created bool
+	for _, a := range actions {
+		switch a {
+		case "delete":
+			deleted = true
+		case "create":
+			created = true
+		}
+	}
+	return deleted && created
+}
+
+// attributePath converts steps of a path in a Terraform plan, which are strings or numbers, to a path like
+// rule[0].cidr_blocks
+func attributePath(steps []interface{}) string {
+	var b strings.Builder
+	for _, step := range steps {
+		switch s := step.(type) {
+		case string:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s)
+		case float64:
+			fmt.Fprintf(&b, "[%d]", int(s))
+		default:
+			fmt.Fprintf(&b, "[%v]", s)
+		}
+	}
+	return b.String()
+}
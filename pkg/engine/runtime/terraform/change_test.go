@@ -0,0 +1,107 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
+)
+
+func TestResourceChange(t *testing.T) {
+	pr := &tfops.PlanRepresentation{}
+	err := json.Unmarshal([]byte(`{
+  "resource_changes": [
+    {
+      "address": "aws_instance.web",
+      "mode": "managed",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"ami": "ami-1", "password": "foo", "ebs": [{"size": 8}]},
+        "after": {"ami": "ami-2", "password": "foo", "ebs": [{"size": 16}]},
+        "after_unknown": {"id": true, "ebs": [{}]},
+        "before_sensitive": {"password": true},
+        "after_sensitive": {"password": true},
+        "replace_paths": [["ami"], ["ebs", 0, "size"]]
+      },
+      "action_reason": "replace_because_cannot_update"
+    }
+  ]
+}`), pr)
+	assert.Nil(t, err)
+
+	change, err := resourceChange(pr)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"delete", "create"}, change.Actions)
+	assert.Equal(t, map[string]interface{}{"id": true, "ebs": []interface{}{map[string]interface{}{}}}, change.AfterUnknown)
+	assert.Equal(t, map[string]interface{}{"password": true}, change.AfterSensitive)
+	assert.Equal(t, []runtime.Replacement{{
+		Reason:   "attributes can't be updated in place",
+		ForcedBy: []string{"ami", "ebs[0].size"},
+	}}, change.Replacements)
+
+	change, err = resourceChange(&tfops.PlanRepresentation{})
+	assert.Nil(t, err)
+	assert.Nil(t, change)
+}
+
+func TestModuleChange(t *testing.T) {
+	res := &models.Resource{ID: "hashicorp:aws:module:vpc", Extensions: map[string]interface{}{
+		tfops.ModeExtension: tfops.ModuleMode,
+	}}
+	pr := &tfops.PlanRepresentation{}
+	err := json.Unmarshal([]byte(`{
+  "resource_changes": [
+    {
+      "address": "module.vpc.aws_vpc.this[0]",
+      "module_address": "module.vpc",
+      "mode": "managed",
+      "change": {
+        "actions": ["create"],
+        "after": {"cidr_block": "10.0.0.0/16"},
+        "after_unknown": {"id": true}
+      }
+    },
+    {
+      "address": "module.vpc.aws_subnet.public[0]",
+      "module_address": "module.vpc",
+      "mode": "managed",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"cidr_block": "10.0.1.0/24"},
+        "after": {"cidr_block": "10.0.2.0/24"},
+        "replace_paths": [["cidr_block"]]
+      },
+      "action_reason": "replace_because_tainted"
+    },
+    {
+      "address": "aws_instance.other",
+      "mode": "managed",
+      "change": {"actions": ["create"]}
+    }
+  ]
+}`), pr)
+	assert.Nil(t, err)
+
+	change, err := moduleChange(pr, res)
+	assert.Nil(t, err)
+	assert.Empty(t, change.Actions)
+	assert.Equal(t, map[string]interface{}{
+		tfops.ModuleResourcesAttribute: map[string]interface{}{
+			"aws_vpc.this[0]":      map[string]interface{}{"id": true},
+			"aws_subnet.public[0]": nil,
+		},
+	}, change.AfterUnknown)
+	assert.Equal(t, []runtime.Replacement{{
+		Path:     "resources.aws_subnet.public[0]",
+		Reason:   "the resource is tainted",
+		ForcedBy: []string{"cidr_block"},
+	}}, change.Replacements)
+
+	change, err = moduleChange(&tfops.PlanRepresentation{}, res)
+	assert.Nil(t, err)
+	assert.Nil(t, change)
+}
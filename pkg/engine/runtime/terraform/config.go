@@ -0,0 +1,33 @@
+package terraform
+
+import (
+	"path/filepath"
+
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// terraformConfig returns a copy of Terraform configs of the project, whose relative paths are resolved against the
+// project directory. A binary without any path separator is looked up in PATH
+func terraformConfig(project *projectstack.Project) *projectstack.TerraformConfig {
+	if project == nil || project.Terraform == nil {
+		return nil
+	}
+	config := *project.Terraform
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) || project.Path == "" {
+			return path
+		}
+		return filepath.Join(project.Path, path)
+	}
+
+	if filepath.Base(config.Binary) != config.Binary {
+		config.Binary = resolve(config.Binary)
+	}
+	config.PluginCacheDir = resolve(config.PluginCacheDir)
+	config.ProviderMirror = resolve(config.ProviderMirror)
+	config.PluginDirs = make([]string, len(project.Terraform.PluginDirs))
+	for i, dir := range project.Terraform.PluginDirs {
+		config.PluginDirs[i] = resolve(dir)
+	}
+	return &config
+}
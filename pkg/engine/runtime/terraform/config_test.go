@@ -0,0 +1,34 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func TestTerraformConfig(t *testing.T) {
+	assert.Nil(t, terraformConfig(nil))
+	assert.Nil(t, terraformConfig(&projectstack.Project{Path: "/project"}))
+
+	project := &projectstack.Project{
+		ProjectConfiguration: projectstack.ProjectConfiguration{Terraform: &projectstack.TerraformConfig{
+			Binary:         "tofu",
+			PluginCacheDir: "/cache",
+			ProviderMirror: "mirror",
+			PluginDirs:     []string{"plugins", "/plugins"},
+		}},
+		Path: "/project",
+	}
+	assert.Equal(t, &projectstack.TerraformConfig{
+		Binary:         "tofu",
+		PluginCacheDir: "/cache",
+		ProviderMirror: "/project/mirror",
+		PluginDirs:     []string{"/project/plugins", "/plugins"},
+	}, terraformConfig(project))
+	assert.Equal(t, "plugins", project.Terraform.PluginDirs[0])
+
+	project.Terraform.Binary = "bin/terraform"
+	assert.Equal(t, "/project/bin/terraform", terraformConfig(project).Binary)
+}
@@ -0,0 +1,62 @@
+package terraform
+
+import (
+	"context"
+	"sync"
+
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// DefaultParallelism is the max number of Terraform resources processed at the same time if it is not set in
+// project.yaml, which is the same as the default parallelism of Terraform
+const DefaultParallelism = 10
+
+// parallelism returns the limit of concurrent Terraform resources configured in the project
+func parallelism(project *projectstack.Project) int {
+	if project != nil && project.Terraform != nil && project.Terraform.Parallelism > 0 {
+		return project.Terraform.Parallelism
+	}
+	return DefaultParallelism
+}
+
+// limiter bounds the number of running calls. Unlike a semaphore, the limit is given by each call, since it is
+// configured per project but the runtime is shared
+type limiter struct {
+	mu      sync.Mutex
+	running int
+	// released is closed and replaced whenever a call is released, which wakes up waiting calls
+	released chan struct{}
+}
+
+func newLimiter() *limiter {
+	return &limiter{released: make(chan struct{})}
+}
+
+// acquire waits until less than limit calls are running, or ctx is done. release must be called after a successful
+// acquire
+func (l *limiter) acquire(ctx context.Context, limit int) error {
+	for {
+		l.mu.Lock()
+		if l.running < limit {
+			l.running++
+			l.mu.Unlock()
+			return nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running--
+	close(l.released)
+	l.released = make(chan struct{})
+}
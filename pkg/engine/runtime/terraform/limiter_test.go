@@ -0,0 +1,45 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func TestParallelism(t *testing.T) {
+	assert.Equal(t, DefaultParallelism, parallelism(nil))
+	assert.Equal(t, DefaultParallelism, parallelism(&projectstack.Project{}))
+	assert.Equal(t, 3, parallelism(&projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+		Terraform: &projectstack.TerraformConfig{Parallelism: 3},
+	}}))
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter()
+	assert.Nil(t, l.acquire(context.TODO(), 1))
+
+	// the limit is reached, so acquiring waits until ctx is done
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.acquire(ctx, 1), context.DeadlineExceeded)
+
+	// a higher limit of another call is not reached
+	assert.Nil(t, l.acquire(context.TODO(), 2))
+	l.release()
+
+	acquired := make(chan struct{})
+	go func() {
+		assert.Nil(t, l.acquire(context.TODO(), 1))
+		close(acquired)
+	}()
+	l.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiting call is not woken up after release")
+	}
+}
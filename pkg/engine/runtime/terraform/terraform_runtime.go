@@ -2,66 +2,141 @@ package terraform
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
 
+	"kusionstack.io/kusion/pkg/engine"
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/printers"
+	"kusionstack.io/kusion/pkg/engine/printers/convertor"
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 )
 
 var _ runtime.Runtime = &TerraformRuntime{}
 
+// DefaultWatchInterval is the interval of refreshing Terraform resources when watching them
+const DefaultWatchInterval = 10 * time.Second
+
+// TerraformRuntime runs Terraform commands of each resource in its own workspace, so different resources are
+// processed concurrently up to the parallelism of the project, and calls on the same resource are serialized
 type TerraformRuntime struct {
-	tfops.WorkSpace
-	mu *sync.Mutex
+	fs      afero.Afero
+	limiter *limiter
+	// locks are mutexes of resources keyed by their cache directories
+	locks sync.Map
+	// executor runs Terraform commands of workspaces. The Terraform CLI is run if it is nil
+	executor      tfops.Executor
+	watchInterval time.Duration
 }
 
 func NewTerraformRuntime() (runtime.Runtime, error) {
-	fs := afero.Afero{Fs: afero.NewOsFs()}
-	ws := tfops.NewWorkSpace(fs)
-	TFRuntime := &TerraformRuntime{
-		WorkSpace: *ws,
-		mu:        &sync.Mutex{},
+	return newTerraformRuntime(), nil
+}
+
+func newTerraformRuntime() *TerraformRuntime {
+	return &TerraformRuntime{
+		fs:            afero.Afero{Fs: afero.NewOsFs()},
+		limiter:       newLimiter(),
+		watchInterval: DefaultWatchInterval,
+	}
+}
+
+// lock waits until no other call is running on the resource of the cache directory, and the number of running calls
+// is less than the parallelism of the project. unlock must be called when the call ends
+func (t *TerraformRuntime) lock(ctx context.Context, tfCacheDir string, project *projectstack.Project) (unlock func(), err error) {
+	v, _ := t.locks.LoadOrStore(tfCacheDir, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	if err = t.limiter.acquire(ctx, parallelism(project)); err != nil {
+		mu.Unlock()
+		return nil, err
 	}
-	return TFRuntime, nil
+	return func() {
+		t.limiter.release()
+		mu.Unlock()
+	}, nil
+}
+
+// newWorkSpace returns a workspace of the resource with Terraform configs of the project, which must not be shared
+// with other calls. The version of the binary is checked if it is required
+func (t *TerraformRuntime) newWorkSpace(ctx context.Context, res *models.Resource, project *projectstack.Project, stackPath, tfCacheDir string) (*tfops.WorkSpace, error) {
+	ws := tfops.NewWorkSpace(t.fs)
+	ws.SetStackDir(stackPath)
+	ws.SetCacheDir(tfCacheDir)
+	ws.SetResource(res)
+	ws.SetConfig(terraformConfig(project))
+	ws.SetExecutor(t.executor)
+	if err := ws.CheckVersion(ctx); err != nil {
+		return nil, err
+	}
+	return ws, nil
 }
 
 // Apply Terraform resource
 func (t *TerraformRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	plan := request.PlanResource
 	stackPath := request.Stack.GetPath()
 	tfCacheDir := filepath.Join(stackPath, "."+plan.ResourceKey())
-	t.WorkSpace.SetStackDir(stackPath)
-	t.WorkSpace.SetCacheDir(tfCacheDir)
-	t.WorkSpace.SetResource(plan)
+	unlock, err := t.lock(ctx, tfCacheDir, request.Project)
+	if err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	defer unlock()
+	ws, err := t.newWorkSpace(ctx, plan, request.Project, stackPath, tfCacheDir)
+	if err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
 
-	if err := t.WorkSpace.WriteHCL(); err != nil {
+	if err := ws.WriteHCL(); err != nil {
 		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
 
-	_, err := os.Stat(filepath.Join(tfCacheDir, tfops.LockHCLFile))
-	if err != nil {
-		if os.IsNotExist(err) {
-			if err := t.WorkSpace.InitWorkSpace(ctx); err != nil {
-				return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-			}
-		} else {
+	if err = initWorkSpace(ctx, ws, plan, tfCacheDir); err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+
+	// data sources are read by refreshing, which changes nothing but the terraform state in the workspace, so they
+	// are read in the dry-run mode as well, and other resources can refer to their attributes in previews
+	if tfops.IsDataSource(plan) {
+		attributes, err := refreshAttributes(ctx, ws)
+		if err == nil && attributes == nil {
+			err = fmt.Errorf("data source %s is not found", plan.ResourceKey())
+		}
+		if err != nil {
 			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 		}
+		return &runtime.ApplyResponse{
+			Resource: &models.Resource{
+				ID:         plan.ID,
+				Type:       plan.Type,
+				Attributes: attributes,
+				DependsOn:  plan.DependsOn,
+				Extensions: plan.Extensions,
+			},
+			Status: nil,
+		}
+	}
+
+	if tfops.IsModule(plan) {
+		return applyModule(ctx, ws, plan, request.DryRun)
 	}
 
 	// dry run by terraform plan
 	if request.DryRun {
-		pr, err := t.WorkSpace.Plan(ctx)
+		pr, err := ws.Plan(ctx)
 		if err != nil {
 			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 		}
@@ -70,6 +145,10 @@ func (t *TerraformRuntime) Apply(ctx context.Context, request *runtime.ApplyRequ
 			log.Debugf("no resource found in terraform plan file")
 			return &runtime.ApplyResponse{Resource: &models.Resource{}, Status: nil}
 		}
+		change, err := resourceChange(pr)
+		if err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+		}
 
 		return &runtime.ApplyResponse{
 			Resource: &models.Resource{
@@ -79,17 +158,18 @@ func (t *TerraformRuntime) Apply(ctx context.Context, request *runtime.ApplyRequ
 				DependsOn:  plan.DependsOn,
 				Extensions: plan.Extensions,
 			},
+			Change: change,
 			Status: nil,
 		}
 	}
 
-	tfstate, err := t.WorkSpace.Apply(ctx)
+	tfstate, err := ws.Apply(ctx)
 	if err != nil {
 		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
 
 	// get terraform provider version
-	providerAddr, err := t.WorkSpace.GetProvider()
+	providerAddr, err := ws.GetProvider()
 	if err != nil {
 		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
@@ -122,46 +202,57 @@ func (t *TerraformRuntime) Read(ctx context.Context, request *runtime.ReadReques
 		// Most fields in attributes in resources aren't necessary for the command `terraform apply -refresh-only` and will make errors
 		// if fields copied from kusion_state.json but read-only in main.tf.json
 		planResource = &models.Resource{
-			ID:         priorResource.ID,
-			Type:       priorResource.Type,
-			Attributes: nil,
-			DependsOn:  priorResource.DependsOn,
-			Extensions: priorResource.Extensions,
+			ID:                 priorResource.ID,
+			Type:               priorResource.Type,
+			Attributes:         nil,
+			DependsOn:          priorResource.DependsOn,
+			Extensions:         priorResource.Extensions,
+			ResolvedExtensions: priorResource.ResolvedExtensions,
+		}
+		// modules can't be initialized without their inputs
+		if tfops.IsModule(priorResource) {
+			planResource.Attributes = tfops.ModuleInputs(priorResource.Attributes)
 		}
 	}
 	if priorResource == nil {
 		return &runtime.ReadResponse{Resource: nil, Status: nil}
 	}
+	// a data source removed from the spec can't be refreshed without its arguments, and is trusted to be the prior
+	// one since it is never deleted
+	if request.PlanResource == nil && tfops.IsDataSource(priorResource) {
+		return &runtime.ReadResponse{Resource: priorResource, Status: nil}
+	}
 	var tfstate *tfops.StateRepresentation
 
-	t.mu.Lock()
-	defer t.mu.Unlock()
 	stackPath := request.Stack.GetPath()
 	tfCacheDir := filepath.Join(stackPath, "."+planResource.ResourceKey())
-	t.WorkSpace.SetStackDir(stackPath)
-	t.WorkSpace.SetCacheDir(tfCacheDir)
-	t.WorkSpace.SetResource(planResource)
-
-	if err := t.WorkSpace.WriteHCL(); err != nil {
+	unlock, err := t.lock(ctx, tfCacheDir, request.Project)
+	if err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
-	_, err := os.Stat(filepath.Join(tfCacheDir, tfops.LockHCLFile))
+	defer unlock()
+	ws, err := t.newWorkSpace(ctx, planResource, request.Project, stackPath, tfCacheDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			if err := t.WorkSpace.InitWorkSpace(ctx); err != nil {
-				return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-			}
-		} else {
-			return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-		}
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+
+	if err := ws.WriteHCL(); err != nil {
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	if err = initWorkSpace(ctx, ws, planResource, tfCacheDir); err != nil {
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+
+	if tfops.IsModule(planResource) {
+		return readModule(ctx, ws, planResource, priorResource, tfCacheDir)
 	}
 
 	// priorResource overwrite tfstate in workspace
-	if err = t.WorkSpace.WriteTFState(priorResource); err != nil {
+	if err = ws.WriteTFState(priorResource); err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
 
-	tfstate, err = t.WorkSpace.RefreshOnly(ctx)
+	tfstate, err = ws.RefreshOnly(ctx)
 	if err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
@@ -171,7 +262,7 @@ func (t *TerraformRuntime) Read(ctx context.Context, request *runtime.ReadReques
 	}
 
 	// get terraform provider addr
-	providerAddr, err := t.WorkSpace.GetProvider()
+	providerAddr, err := ws.GetProvider()
 	if err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
@@ -195,29 +286,252 @@ func (t *TerraformRuntime) Import(ctx context.Context, request *runtime.ImportRe
 	return nil
 }
 
-// Delete terraform resource and remove workspace
+// Delete terraform resource and remove workspace. Data sources are not destroyed, and only their workspaces are
+// removed
 func (t *TerraformRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) (res *runtime.DeleteResponse) {
 	stackPath := request.Stack.GetPath()
 	tfCacheDir := filepath.Join(stackPath, "."+request.Resource.ResourceKey())
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	t.WorkSpace.SetStackDir(stackPath)
-	t.WorkSpace.SetCacheDir(tfCacheDir)
-	t.WorkSpace.SetResource(request.Resource)
-	if err := t.WorkSpace.Destroy(ctx); err != nil {
+	unlock, err := t.lock(ctx, tfCacheDir, request.Project)
+	if err != nil {
 		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
 	}
+	defer unlock()
+
+	if !tfops.IsDataSource(request.Resource) {
+		ws, err := t.newWorkSpace(ctx, request.Resource, request.Project, stackPath, tfCacheDir)
+		if err != nil {
+			return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+		}
+		if err := ws.Destroy(ctx); err != nil {
+			return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+		}
+	}
 
 	// delete tf directory after destroy operation is success
-	err := os.RemoveAll(tfCacheDir)
+	err = os.RemoveAll(tfCacheDir)
 	if err != nil {
 		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
 	}
 	return &runtime.DeleteResponse{Status: nil}
 }
 
-// Watch terraform resource
+// Watch terraform resource by polling `terraform apply --refresh-only` at an interval. An Added event is sent at the
+// first poll, and a Modified event is sent whenever attributes change. Watching stops when the resource is ready,
+// deleted or ctx is done. Resources which have not been applied yet, data sources and modules are not watched
 func (t *TerraformRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
-	return nil
+	res := request.Resource
+	if request.Stack == nil || tfops.IsDataSource(res) || tfops.IsModule(res) {
+		return nil
+	}
+	stackPath := request.Stack.GetPath()
+	tfCacheDir := filepath.Join(stackPath, "."+res.ResourceKey())
+	if _, err := os.Stat(filepath.Join(tfCacheDir, tfops.TFStateFile)); err != nil {
+		log.Debugf("no terraform state of %s found, skip watching", res.ResourceKey())
+		return nil
+	}
+
+	obj := newWatchObject(res, nil)
+	out := make(chan k8swatch.Event)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(t.watchInterval)
+		defer ticker.Stop()
+		var last map[string]interface{}
+		for {
+			attributes, err := t.refresh(ctx, res, request.Project, stackPath, tfCacheDir)
+			if err != nil {
+				log.Warnf("refresh terraform resource %s failed: %v", res.ResourceKey(), err)
+			} else {
+				e, stop := nextWatchEvent(res, last, attributes)
+				if e != nil {
+					select {
+					case out <- *e:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if stop {
+					return
+				}
+				last = attributes
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	watchers := runtime.NewWatchers()
+	watchers.Insert(engine.BuildIDForKubernetes(obj), out)
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+// refresh refreshes the terraform state of the applied resource and returns its attributes. Nil attributes mean
+// the resource has been deleted
+func (t *TerraformRuntime) refresh(ctx context.Context, res *models.Resource, project *projectstack.Project, stackPath, tfCacheDir string) (map[string]interface{}, error) {
+	unlock, err := t.lock(ctx, tfCacheDir, project)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ws, err := t.newWorkSpace(ctx, res, project, stackPath, tfCacheDir)
+	if err != nil {
+		return nil, err
+	}
+	return refreshAttributes(ctx, ws)
+}
+
+// initWorkSpace runs `terraform init` if the workspace has not been initialized. Modules are initialized every time,
+// since their sources and versions may change
+func initWorkSpace(ctx context.Context, ws *tfops.WorkSpace, res *models.Resource, tfCacheDir string) error {
+	_, err := os.Stat(filepath.Join(tfCacheDir, tfops.LockHCLFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && !tfops.IsModule(res) {
+		return nil
+	}
+	return ws.InitWorkSpace(ctx)
+}
+
+// applyModule plans or applies the module. Its outputs and resources are in attributes of the result without
+// sensitive values, so previews show changes of resources in the module, and their planned changes are aggregated in
+// the dry-run mode
+func applyModule(ctx context.Context, ws *tfops.WorkSpace, plan *models.Resource, dryRun bool) *runtime.ApplyResponse {
+	sensitiveOutputs, err := ws.SensitiveModuleOutputs()
+	if err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+
+	var attributes map[string]interface{}
+	var change *runtime.ResourceChange
+	if dryRun {
+		pr, err := ws.Plan(ctx)
+		if err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+		}
+		attributes = tfops.ConvertModulePlan(pr, plan, sensitiveOutputs)
+		if change, err = moduleChange(pr, plan); err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+		}
+	} else {
+		tfstate, err := ws.Apply(ctx)
+		if err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+		}
+		attributes = tfops.ConvertModuleState(tfstate, plan, sensitiveOutputs)
+	}
+	if attributes == nil {
+		attributes = tfops.ModuleInputs(plan.Attributes)
+	}
+
+	return &runtime.ApplyResponse{
+		Resource: &models.Resource{
+			ID:         plan.ID,
+			Type:       plan.Type,
+			Attributes: attributes,
+			DependsOn:  plan.DependsOn,
+			Extensions: plan.Extensions,
+		},
+		Change: change,
+		Status: nil,
+	}
+}
+
+// readModule refreshes the terraform state of the module in the workspace. Unlike a single resource, the state of a
+// module can't be rebuilt from the prior resource, since providers and schema versions of resources in the module
+// are not recorded and sensitive values are dropped. Reading fails if the state is missing, otherwise the module would
+// be planned to create all its resources again
+func readModule(ctx context.Context, ws *tfops.WorkSpace, planResource, priorResource *models.Resource, tfCacheDir string) *runtime.ReadResponse {
+	if _, err := os.Stat(filepath.Join(tfCacheDir, tfops.TFStateFile)); err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("terraform state of module %s is missing in %s and can't be rebuilt from the prior state. "+
+				"Restore the directory, or import resources of the module with terraform import in it", priorResource.ResourceKey(), tfCacheDir)
+		}
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+
+	tfstate, err := ws.RefreshOnly(ctx)
+	if err != nil {
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	sensitiveOutputs, err := ws.SensitiveModuleOutputs()
+	if err != nil {
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	attributes := tfops.ConvertModuleState(tfstate, planResource, sensitiveOutputs)
+	if attributes == nil {
+		return &runtime.ReadResponse{Resource: nil, Status: nil}
+	}
+	return &runtime.ReadResponse{
+		Resource: &models.Resource{
+			ID:         planResource.ID,
+			Type:       planResource.Type,
+			Attributes: attributes,
+			DependsOn:  planResource.DependsOn,
+			Extensions: planResource.Extensions,
+		},
+		Status: nil,
+	}
+}
+
+// refreshAttributes runs `terraform apply -refresh-only` in the workspace and returns attributes of the resource.
+// Nil attributes mean the resource doesn't exist
+func refreshAttributes(ctx context.Context, ws *tfops.WorkSpace) (map[string]interface{}, error) {
+	tfstate, err := ws.RefreshOnly(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tfstate == nil || tfstate.Values == nil {
+		return nil, nil
+	}
+	providerAddr, err := ws.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+	r := tfops.ConvertTFState(tfstate, providerAddr)
+	return r.Attributes, nil
+}
+
+// nextWatchEvent compares the latest attributes with the last ones, and returns the event to send and whether to
+// stop watching
+func nextWatchEvent(res *models.Resource, last, attributes map[string]interface{}) (*k8swatch.Event, bool) {
+	obj := newWatchObject(res, attributes)
+	switch {
+	case attributes == nil:
+		return &k8swatch.Event{Type: k8swatch.Deleted, Object: obj}, true
+	case last == nil:
+		e := &k8swatch.Event{Type: k8swatch.Added, Object: obj}
+		return e, isReady(obj)
+	case !reflect.DeepEqual(last, attributes):
+		e := &k8swatch.Event{Type: k8swatch.Modified, Object: obj}
+		return e, isReady(obj)
+	default:
+		return nil, false
+	}
+}
+
+func isReady(obj *unstructured.Unstructured) bool {
+	_, ready := printers.Generate(printers.Convert(obj))
+	return ready
+}
+
+// newWatchObject converts the Terraform resource to an object in the watch table, whose kind is the resource type
+func newWatchObject(res *models.Resource, attributes map[string]interface{}) *unstructured.Unstructured {
+	resourceType, _ := res.ResolvedExtension("resourceType").(string)
+	names := strings.Split(res.ResourceKey(), engine.Separator)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(convertor.TerraformGroupVersion.String())
+	obj.SetKind(resourceType)
+	obj.SetName(names[len(names)-1])
+	if attributes != nil {
+		obj.Object[convertor.TerraformAttributes] = attributes
+	}
+	return obj
 }
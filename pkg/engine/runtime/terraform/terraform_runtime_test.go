@@ -8,15 +8,17 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"bou.ke/monkey"
-	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
 
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
 	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
 )
 
 var testResource = models.Resource{
@@ -45,10 +47,7 @@ func TestTerraformRuntime(t *testing.T) {
 		Path:               filepath.Join(cwd, "fakePath"),
 	}
 	defer os.RemoveAll(stack.GetPath())
-	tfRuntime := TerraformRuntime{
-		WorkSpace: *tfops.NewWorkSpace(afero.Afero{Fs: afero.NewOsFs()}),
-		mu:        &sync.Mutex{},
-	}
+	tfRuntime := newTerraformRuntime()
 
 	t.Run("ApplyDryRun", func(t *testing.T) {
 		defer monkey.UnpatchAll()
@@ -117,3 +116,301 @@ func mockApplySetup() {
 		return "registry.terraform.io/hashicorp/local/2.2.3", nil
 	})
 }
+
+func TestTerraformRuntime_Watch(t *testing.T) {
+	stack := &projectstack.Stack{
+		StackConfiguration: projectstack.StackConfiguration{Name: "fakeStack"},
+		Path:               t.TempDir(),
+	}
+	tfRuntime := newTerraformRuntime()
+	tfRuntime.watchInterval = time.Millisecond
+
+	t.Run("NotApplied", func(t *testing.T) {
+		response := tfRuntime.Watch(context.TODO(), &runtime.WatchRequest{Resource: &testResource, Stack: stack})
+		assert.Nil(t, response)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+
+		tfCacheDir := filepath.Join(stack.GetPath(), "."+testResource.ResourceKey())
+		assert.Nil(t, os.MkdirAll(tfCacheDir, os.ModePerm))
+		assert.Nil(t, os.WriteFile(filepath.Join(tfCacheDir, tfops.TFStateFile), []byte("{}"), os.ModePerm))
+
+		// status changes from creating to running
+		statuses := []string{"creating", "creating", "running"}
+		monkey.Patch((*tfops.WorkSpace).RefreshOnly, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.StateRepresentation, error) {
+			s := statuses[0]
+			if len(statuses) > 1 {
+				statuses = statuses[1:]
+			}
+			sr := &tfops.StateRepresentation{}
+			data := fmt.Sprintf(`{"values":{"root_module":{"resources":[{"type":"local_file","name":"kusion_example","values":{"id":"foo","status":"%s"}}]}}}`, s)
+			return sr, json.Unmarshal([]byte(data), sr)
+		})
+		monkey.Patch((*tfops.WorkSpace).GetProvider, func(ws *tfops.WorkSpace) (string, error) {
+			return "registry.terraform.io/hashicorp/local/2.2.3", nil
+		})
+
+		response := tfRuntime.Watch(context.TODO(), &runtime.WatchRequest{Resource: &testResource, Stack: stack})
+		assert.Equal(t, []string{"terraform.kusionstack.io/v1:local_file:kusion_example"}, response.Watchers.IDs)
+
+		var events []k8swatch.EventType
+		for e := range response.Watchers.Watchers[0] {
+			events = append(events, e.Type)
+		}
+		assert.Equal(t, []k8swatch.EventType{k8swatch.Added, k8swatch.Modified}, events)
+	})
+}
+
+func TestTerraformRuntime_Parallelism(t *testing.T) {
+	defer monkey.UnpatchAll()
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	monkey.Patch((*tfops.WorkSpace).InitWorkSpace, func(ws *tfops.WorkSpace, ctx context.Context) error {
+		return nil
+	})
+	monkey.Patch((*tfops.WorkSpace).Plan, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.PlanRepresentation, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return &tfops.PlanRepresentation{}, nil
+	})
+
+	// applies resources concurrently and returns the max number of plans running at the same time
+	applyAll := func(parallelism int, resources []models.Resource) int {
+		maxRunning = 0
+		tfRuntime := newTerraformRuntime()
+		stack := &projectstack.Stack{Path: t.TempDir()}
+		project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+			Name:      "fakeProject",
+			Terraform: &projectstack.TerraformConfig{Parallelism: parallelism},
+		}}
+
+		var wg sync.WaitGroup
+		for i := range resources {
+			wg.Add(1)
+			go func(res *models.Resource) {
+				defer wg.Done()
+				response := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: res, Stack: stack, Project: project, DryRun: true})
+				assert.Nil(t, response.Status)
+			}(&resources[i])
+		}
+		wg.Wait()
+		return maxRunning
+	}
+
+	t.Run("Limited", func(t *testing.T) {
+		resources := make([]models.Resource, 6)
+		for i := range resources {
+			resources[i] = *testResource.DeepCopy()
+			resources[i].ID = fmt.Sprintf("%s_%d", testResource.ID, i)
+		}
+		assert.Equal(t, 2, applyAll(2, resources))
+	})
+
+	t.Run("SameResource", func(t *testing.T) {
+		resources := []models.Resource{*testResource.DeepCopy(), *testResource.DeepCopy(), *testResource.DeepCopy()}
+		assert.Equal(t, 1, applyAll(10, resources))
+	})
+}
+
+func TestTerraformRuntime_DataSource(t *testing.T) {
+	defer monkey.UnpatchAll()
+
+	stack := &projectstack.Stack{Path: t.TempDir()}
+	tfRuntime := newTerraformRuntime()
+	dataSource := testResource.DeepCopy()
+	dataSource.Attributes = map[string]interface{}{"filename": "test.txt"}
+	dataSource.Extensions[tfops.ModeExtension] = tfops.DataMode
+
+	monkey.Patch((*tfops.WorkSpace).InitWorkSpace, func(ws *tfops.WorkSpace, ctx context.Context) error {
+		return nil
+	})
+	monkey.Patch((*tfops.WorkSpace).GetProvider, func(ws *tfops.WorkSpace) (string, error) {
+		return "registry.terraform.io/hashicorp/local/2.2.3", nil
+	})
+	monkey.Patch((*tfops.WorkSpace).RefreshOnly, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.StateRepresentation, error) {
+		sr := &tfops.StateRepresentation{}
+		data := `{"values":{"root_module":{"resources":[{"mode":"data","type":"local_file","name":"kusion_example","values":{"filename":"test.txt","content":"kusion"}}]}}}`
+		return sr, json.Unmarshal([]byte(data), sr)
+	})
+	failed := func(ws *tfops.WorkSpace, ctx context.Context) error {
+		return fmt.Errorf("data sources must not be planned, applied or destroyed")
+	}
+	monkey.Patch((*tfops.WorkSpace).Plan, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.PlanRepresentation, error) {
+		return nil, failed(ws, ctx)
+	})
+	monkey.Patch((*tfops.WorkSpace).Apply, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.StateRepresentation, error) {
+		return nil, failed(ws, ctx)
+	})
+	monkey.Patch((*tfops.WorkSpace).Destroy, failed)
+
+	for _, dryRun := range []bool{true, false} {
+		response := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: dataSource, Stack: stack, DryRun: dryRun})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, "kusion", response.Resource.Attributes["content"])
+	}
+
+	// a data source removed from the spec is not refreshed
+	readResponse := tfRuntime.Read(context.TODO(), &runtime.ReadRequest{PriorResource: dataSource, Stack: stack})
+	assert.Nil(t, readResponse.Status)
+	assert.Equal(t, dataSource, readResponse.Resource)
+
+	assert.Nil(t, tfRuntime.Watch(context.TODO(), &runtime.WatchRequest{Resource: dataSource, Stack: stack}))
+
+	tfCacheDir := filepath.Join(stack.GetPath(), "."+dataSource.ResourceKey())
+	assert.DirExists(t, tfCacheDir)
+	assert.Nil(t, tfRuntime.Delete(context.TODO(), &runtime.DeleteRequest{Resource: dataSource, Stack: stack}).Status)
+	assert.NoDirExists(t, tfCacheDir)
+}
+
+func TestTerraformRuntime_Module(t *testing.T) {
+	defer monkey.UnpatchAll()
+
+	stack := &projectstack.Stack{Path: t.TempDir()}
+	tfRuntime := newTerraformRuntime()
+	module := &models.Resource{
+		ID:         "aws:module:vpc",
+		Type:       "Terraform",
+		Attributes: map[string]interface{}{"cidr": "10.0.0.0/16"},
+		Extensions: map[string]interface{}{
+			tfops.ModeExtension:   tfops.ModuleMode,
+			tfops.SourceExtension: "terraform-aws-modules/vpc/aws",
+		},
+	}
+
+	// terraform init installs the module, whose token output is sensitive
+	inits := 0
+	monkey.Patch((*tfops.WorkSpace).InitWorkSpace, func(ws *tfops.WorkSpace, ctx context.Context) error {
+		inits++
+		tfCacheDir := filepath.Join(stack.GetPath(), "."+module.ResourceKey())
+		moduleDir := filepath.Join(tfCacheDir, ".terraform", "modules", "vpc")
+		if err := os.MkdirAll(moduleDir, os.ModePerm); err != nil {
+			return err
+		}
+		manifest := `{"Modules":[{"Key":"vpc","Dir":".terraform/modules/vpc"}]}`
+		if err := os.WriteFile(filepath.Join(tfCacheDir, ".terraform", "modules", "modules.json"), []byte(manifest), os.ModePerm); err != nil {
+			return err
+		}
+		outputs := "output \"vpc_id\" {\n  value = aws_vpc.this.id\n}\n\noutput \"token\" {\n  value     = \"secret\"\n  sensitive = true\n}\n"
+		return os.WriteFile(filepath.Join(moduleDir, "outputs.tf"), []byte(outputs), os.ModePerm)
+	})
+	monkey.Patch((*tfops.WorkSpace).Plan, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.PlanRepresentation, error) {
+		pr := &tfops.PlanRepresentation{}
+		data := `{"planned_values":{"root_module":{"child_modules":[{"address":"module.vpc","resources":[{"address":"module.vpc.aws_vpc.this","values":{"cidr_block":"10.0.0.0/16","password":"secret"},"sensitive_values":{"password":true}}]}]}}}`
+		return pr, json.Unmarshal([]byte(data), pr)
+	})
+	monkey.Patch((*tfops.WorkSpace).Apply, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.StateRepresentation, error) {
+		sr := &tfops.StateRepresentation{}
+		data := `{"values":{"outputs":{"vpc":{"sensitive":true,"value":{"vpc_id":"vpc-1","token":"secret"}}},"root_module":{"child_modules":[{"address":"module.vpc","resources":[{"address":"module.vpc.aws_vpc.this","values":{"id":"vpc-1","cidr_block":"10.0.0.0/16","password":"secret"},"sensitive_values":{"password":true}}]}]}}}`
+		return sr, json.Unmarshal([]byte(data), sr)
+	})
+
+	t.Run("Preview", func(t *testing.T) {
+		response := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: module, Stack: stack, DryRun: true})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, map[string]interface{}{
+			"aws_vpc.this": map[string]interface{}{"cidr_block": "10.0.0.0/16"},
+		}, response.Resource.Attributes[tfops.ModuleResourcesAttribute])
+	})
+
+	var applied *models.Resource
+	t.Run("Apply", func(t *testing.T) {
+		response := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: module, Stack: stack})
+		assert.Nil(t, response.Status)
+		applied = response.Resource
+		assert.Equal(t, "10.0.0.0/16", applied.Attributes["cidr"])
+		// sensitive outputs and values are not saved
+		assert.Equal(t, map[string]interface{}{"vpc_id": "vpc-1"}, applied.Attributes[tfops.ModuleOutputsAttribute])
+		assert.Equal(t, map[string]interface{}{
+			"aws_vpc.this": map[string]interface{}{"id": "vpc-1", "cidr_block": "10.0.0.0/16"},
+		}, applied.Attributes[tfops.ModuleResourcesAttribute])
+		// modules are initialized every time
+		assert.Equal(t, 2, inits)
+	})
+
+	t.Run("ReadWithoutState", func(t *testing.T) {
+		// the module must not be planned to be created again
+		response := tfRuntime.Read(context.TODO(), &runtime.ReadRequest{PriorResource: applied, Stack: stack})
+		assert.True(t, status.IsErr(response.Status))
+		assert.Contains(t, response.Status.Message(), "terraform state of module aws:module:vpc is missing")
+		assert.Nil(t, response.Resource)
+	})
+
+	assert.Nil(t, tfRuntime.Watch(context.TODO(), &runtime.WatchRequest{Resource: module, Stack: stack}))
+}
+
+// TestTerraformRuntime_Replay runs the runtime with recorded outputs of the Terraform CLI in test_data
+func TestTerraformRuntime_Replay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	stack := &projectstack.Stack{Path: filepath.Join(dir, "stack")}
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{
+		Name:      "fakeProject",
+		Terraform: &projectstack.TerraformConfig{PluginCacheDir: filepath.Join(dir, "plugin-cache")},
+	}}
+	newRuntime := func(fixtures string) (*TerraformRuntime, *tfops.ReplayExecutor) {
+		executor, err := tfops.LoadFixtures(filepath.Join("test_data", fixtures))
+		assert.Nil(t, err)
+		tfRuntime := newTerraformRuntime()
+		tfRuntime.executor = executor
+		return tfRuntime, executor
+	}
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		tfRuntime, executor := newRuntime("local_file")
+
+		preview := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: &testResource, Stack: stack, Project: project, DryRun: true})
+		assert.Nil(t, preview.Status)
+		assert.Equal(t, "0777", preview.Resource.Attributes["file_permission"])
+		assert.NotContains(t, preview.Resource.Attributes, "id")
+		assert.Equal(t, []string{"create"}, preview.Change.Actions)
+		assert.Equal(t, map[string]interface{}{"id": true}, preview.Change.AfterUnknown)
+
+		applied := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: &testResource, Stack: stack, Project: project})
+		assert.Nil(t, applied.Status)
+		assert.Equal(t, "2d3f3c4f0a8e9e5b1f4ab8ab8b3e0f4c0d2b2a8c", applied.Resource.Attributes["id"])
+		assert.Equal(t, testResource.Extensions, applied.Resource.Extensions)
+
+		read := tfRuntime.Read(context.TODO(), &runtime.ReadRequest{PlanResource: &testResource, PriorResource: applied.Resource, Stack: stack, Project: project})
+		assert.Nil(t, read.Status)
+		assert.Equal(t, applied.Resource.Attributes, read.Resource.Attributes)
+
+		tfCacheDir := filepath.Join(stack.GetPath(), "."+testResource.ResourceKey())
+		deleted := tfRuntime.Delete(context.TODO(), &runtime.DeleteRequest{Resource: applied.Resource, Stack: stack, Project: project})
+		assert.Nil(t, deleted.Status)
+		assert.NoDirExists(t, tfCacheDir)
+
+		assert.Equal(t, []string{
+			tfops.InitCommand, tfops.PlanCommand, tfops.ShowPlanCommand,
+			tfops.ApplyCommand, tfops.RefreshCommand, tfops.ShowStateCommand,
+			tfops.RefreshCommand, tfops.ShowStateCommand,
+			tfops.DestroyCommand,
+		}, executor.Commands())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		tfRuntime, _ := newRuntime("apply_error")
+
+		applied := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: &testResource, Stack: stack, Project: project})
+		assert.NotNil(t, applied.Status)
+		assert.Contains(t, applied.Status.Message(), "Invalid value for filename. The filename must not be empty.")
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		tfRuntime, executor := newRuntime("local_file")
+
+		// importing Terraform resources is not supported yet
+		assert.Nil(t, tfRuntime.Import(context.TODO(), &runtime.ImportRequest{PlanResource: &testResource, Stack: stack}))
+		assert.Empty(t, executor.Commands())
+	})
+}
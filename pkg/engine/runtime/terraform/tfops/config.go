@@ -0,0 +1,106 @@
+package tfops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	// DefaultBinary is the Terraform CLI used if no binary is configured
+	DefaultBinary = "terraform"
+	cliConfigFile = "terraform.rc"
+)
+
+// binaryVersions caches versions of binaries keyed by their executors and paths, so each binary is only queried once
+var binaryVersions sync.Map
+
+type binaryKey struct {
+	executor Executor
+	binary   string
+}
+
+// binary returns the configured Terraform CLI
+func (w *WorkSpace) binary() string {
+	if w.config != nil && w.config.Binary != "" {
+		return w.config.Binary
+	}
+	return DefaultBinary
+}
+
+// CheckVersion checks the version of the binary against the required version in the configs. Nothing is checked if
+// no version is required
+func (w *WorkSpace) CheckVersion(ctx context.Context) error {
+	if w.config == nil || w.config.RequiredVersion == "" {
+		return nil
+	}
+	constraints, err := version.NewConstraint(w.config.RequiredVersion)
+	if err != nil {
+		return fmt.Errorf("invalid required terraform version %s: %v", w.config.RequiredVersion, err)
+	}
+	v, err := w.binaryVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if !constraints.Check(v) {
+		return fmt.Errorf("version %s of %s doesn't satisfy the required version %s", v, w.binary(), w.config.RequiredVersion)
+	}
+	return nil
+}
+
+// binaryVersion returns the version reported by `<binary> version -json`. Both Terraform and OpenTofu report it in
+// the terraform_version field
+func (w *WorkSpace) binaryVersion(ctx context.Context) (*version.Version, error) {
+	executor := w.getExecutor()
+	binary := w.binary()
+	key := binaryKey{executor: executor, binary: binary}
+	if v, ok := binaryVersions.Load(key); ok {
+		return v.(*version.Version), nil
+	}
+
+	out, err := executor.Execute(ctx, &Command{Binary: binary, Args: []string{"version", "-json"}})
+	if err != nil {
+		if _, ok := err.(*ExitError); ok {
+			return nil, fmt.Errorf("get version of %s failed: %s", binary, out)
+		}
+		return nil, fmt.Errorf("get version of %s failed: %v", binary, err)
+	}
+	info := struct {
+		TerraformVersion string `json:"terraform_version"`
+	}{}
+	if err = json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("json umarshal version of %s failed: %v", binary, err)
+	}
+	if info.TerraformVersion == "" {
+		return nil, errors.New("no terraform_version found in the output of " + binary + " version -json")
+	}
+	v, err := version.NewVersion(info.TerraformVersion)
+	if err != nil {
+		return nil, err
+	}
+	binaryVersions.Store(key, v)
+	return v, nil
+}
+
+// writeCLIConfig writes a CLI config file to the workspace, which installs providers from the filesystem mirror only,
+// and returns its path
+func (w *WorkSpace) writeCLIConfig() (string, error) {
+	content := fmt.Sprintf(`provider_installation {
+  filesystem_mirror {
+    path = %s
+  }
+}
+`, strconv.Quote(w.config.ProviderMirror))
+
+	path := filepath.Join(w.tfCacheDir, cliConfigFile)
+	if err := w.fs.WriteFile(path, []byte(content), 0o600); err != nil {
+		return "", fmt.Errorf("write terraform cli config error: %v", err)
+	}
+	return path, nil
+}
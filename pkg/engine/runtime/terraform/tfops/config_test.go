@@ -0,0 +1,77 @@
+package tfops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// fakeBinary writes a script reporting the version like `terraform version -json`, and recording arguments and the
+// CLI config file of other commands to args in dir
+func fakeBinary(t *testing.T, dir, version string) string {
+	path := filepath.Join(dir, "fake-terraform")
+	script := `#!/bin/sh
+if [ "$1" = version ]; then
+  echo '{"terraform_version":"` + version + `"}'
+  exit 0
+fi
+echo "$@" > ` + filepath.Join(dir, "args") + `
+echo "$TF_CLI_CONFIG_FILE" >> ` + filepath.Join(dir, "args") + `
+`
+	assert.Nil(t, os.WriteFile(path, []byte(script), 0o700))
+	return path
+}
+
+func TestWorkSpace_CheckVersion(t *testing.T) {
+	dir := t.TempDir()
+	binary := fakeBinary(t, dir, "1.5.7")
+	w := NewWorkSpace(fs)
+
+	assert.Nil(t, w.CheckVersion(context.TODO()))
+
+	w.SetConfig(&projectstack.TerraformConfig{Binary: binary, RequiredVersion: ">= 1.3.0, < 2.0.0"})
+	assert.Nil(t, w.CheckVersion(context.TODO()))
+
+	w.SetConfig(&projectstack.TerraformConfig{Binary: binary, RequiredVersion: "~> 1.6.0"})
+	assert.ErrorContains(t, w.CheckVersion(context.TODO()), "version 1.5.7 of "+binary+" doesn't satisfy the required version ~> 1.6.0")
+
+	w.SetConfig(&projectstack.TerraformConfig{Binary: binary, RequiredVersion: "latest"})
+	assert.ErrorContains(t, w.CheckVersion(context.TODO()), "invalid required terraform version latest")
+
+	w.SetConfig(&projectstack.TerraformConfig{Binary: filepath.Join(dir, "not-exist"), RequiredVersion: ">= 1.0.0"})
+	assert.ErrorContains(t, w.CheckVersion(context.TODO()), "get version of")
+}
+
+func TestWorkSpace_InitOffline(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	w := NewWorkSpace(fs)
+	w.SetResource(&resourceTest)
+	w.SetStackDir(dir)
+	w.SetCacheDir(filepath.Join(dir, "cache"))
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "cache"), os.ModePerm))
+	w.SetConfig(&projectstack.TerraformConfig{
+		Binary:         fakeBinary(t, dir, "1.5.7"),
+		PluginCacheDir: filepath.Join(dir, "plugin-cache"),
+		ProviderMirror: filepath.Join(dir, "mirror"),
+		PluginDirs:     []string{"/plugins/a", "/plugins/b"},
+	})
+
+	assert.Nil(t, w.InitWorkSpace(context.TODO()))
+	assert.DirExists(t, filepath.Join(dir, "plugin-cache"))
+
+	out, err := os.ReadFile(filepath.Join(dir, "args"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Equal(t, "-chdir="+filepath.Join(dir, "cache")+" init -plugin-dir=/plugins/a -plugin-dir=/plugins/b", lines[0])
+
+	cliConfig, err := os.ReadFile(lines[1])
+	assert.Nil(t, err)
+	assert.Contains(t, string(cliConfig), `path = "`+filepath.Join(dir, "mirror")+`"`)
+}
@@ -0,0 +1,58 @@
+package tfops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// Command is a command of the Terraform CLI run in a workspace
+type Command struct {
+	// Binary is the Terraform CLI, such as terraform or tofu
+	Binary string
+
+	// Args are arguments of the binary. The first one is -chdir with the cache directory of the workspace, and the
+	// second one is the subcommand
+	Args []string
+
+	// Dir is the working directory
+	Dir string
+
+	// Env is the environment of the command. The environment of Kusion is used if it is nil
+	Env []string
+}
+
+// Executor runs commands of the Terraform CLI for workspaces. CLIExecutor is used by default, and tests use
+// ReplayExecutor to run workspaces without the Terraform CLI, providers or network
+type Executor interface {
+	// Execute runs the command and returns its combined stdout and stderr. The error is an *ExitError if the command
+	// exits with a non-zero code
+	Execute(ctx context.Context, cmd *Command) ([]byte, error)
+}
+
+// ExitError means a command exits with a non-zero code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+var _ Executor = CLIExecutor{}
+
+// CLIExecutor runs commands with the Terraform CLI on the machine
+type CLIExecutor struct{}
+
+func (CLIExecutor) Execute(ctx context.Context, c *Command) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.Binary, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = c.Env
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out, &ExitError{Code: exitErr.ExitCode()}
+	}
+	return out, err
+}
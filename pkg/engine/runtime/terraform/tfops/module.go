@@ -0,0 +1,293 @@
+package tfops
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+// Extensions of Terraform modules. A module is named after the last part of its ID, and its provider and
+// providerMeta extensions are optional
+const (
+	// SourceExtension is the source of the module, such as terraform-aws-modules/vpc/aws
+	SourceExtension = "source"
+
+	// VersionExtension is the version constraint of the module from a registry
+	VersionExtension = "version"
+)
+
+// Attributes of Terraform modules computed by Kusion. Other attributes are inputs of the module
+const (
+	// ModuleOutputsAttribute holds outputs of the module, which other resources refer to with implicit refs like
+	// $kusion_path.<id>.outputs.vpc_id
+	ModuleOutputsAttribute = "outputs"
+
+	// ModuleResourcesAttribute holds values of resources in the module keyed by their addresses in the module, so
+	// previews show changes of them
+	ModuleResourcesAttribute = "resources"
+)
+
+// modulesManifest is the manifest of modules installed by `terraform init`
+var modulesManifest = filepath.Join(".terraform", "modules", "modules.json")
+
+// IsModule returns true if the resource is a Terraform module
+func IsModule(res *models.Resource) bool {
+	if res == nil {
+		return false
+	}
+	mode, _ := res.ResolvedExtension(ModeExtension).(string)
+	return mode == ModuleMode
+}
+
+// ModuleName returns the name of the module block, which is the last part of the resource ID
+func ModuleName(res *models.Resource) string {
+	names := strings.Split(res.ResourceKey(), ":")
+	return names[len(names)-1]
+}
+
+// ModuleInputs returns a copy of attributes without the ones computed by Kusion
+func ModuleInputs(attributes map[string]interface{}) map[string]interface{} {
+	inputs := make(map[string]interface{}, len(attributes))
+	for k, v := range attributes {
+		if k != ModuleOutputsAttribute && k != ModuleResourcesAttribute {
+			inputs[k] = v
+		}
+	}
+	return inputs
+}
+
+// moduleHCL returns the HCL json of the module, which also outputs all outputs of the module as an object named after
+// the module. The output is sensitive, since outputs of the module may be. Sensitive outputs of the module are dropped
+// by ConvertModuleState and ConvertModulePlan
+func (w *WorkSpace) moduleHCL() (map[string]interface{}, error) {
+	source, _ := w.resource.ResolvedExtension(SourceExtension).(string)
+	if source == "" {
+		return nil, fmt.Errorf("source of terraform module %s is empty", w.resource.ResourceKey())
+	}
+	name := ModuleName(w.resource)
+	block := ModuleInputs(w.resource.Attributes)
+	block["source"] = source
+	if version, _ := w.resource.ResolvedExtension(VersionExtension).(string); version != "" {
+		block["version"] = version
+	}
+
+	m := map[string]interface{}{}
+	if providerAddr, _ := w.resource.ResolvedExtension("provider").(string); providerAddr != "" {
+		m = providerHCL(providerAddr, w.resource)
+	}
+	m["module"] = map[string]interface{}{name: block}
+	m["output"] = map[string]interface{}{
+		name: map[string]interface{}{
+			"value":     fmt.Sprintf("${module.%s}", name),
+			"sensitive": true,
+		},
+	}
+	return m, nil
+}
+
+// SensitiveModuleOutputs returns names of outputs declared sensitive by the module installed in the workspace. The
+// workspace must have been initialized
+func (w *WorkSpace) SensitiveModuleOutputs() (map[string]bool, error) {
+	data, err := w.fs.ReadFile(filepath.Join(w.tfCacheDir, modulesManifest))
+	if err != nil {
+		return nil, fmt.Errorf("read installed terraform modules failed: %v", err)
+	}
+	manifest := struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}{}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("json unmarshal installed terraform modules failed: %v", err)
+	}
+
+	name := ModuleName(w.resource)
+	for _, m := range manifest.Modules {
+		if m.Key != name {
+			continue
+		}
+		dir := m.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(w.tfCacheDir, dir)
+		}
+		return w.sensitiveOutputs(dir)
+	}
+	return nil, fmt.Errorf("terraform module %s is not installed", name)
+}
+
+// sensitiveOutputs parses output blocks in the configuration files of the module in dir
+func (w *WorkSpace) sensitiveOutputs(dir string) (map[string]bool, error) {
+	files, err := w.fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	parser := hclparse.NewParser()
+	sensitive := map[string]bool{}
+	for _, f := range files {
+		isHCL := strings.HasSuffix(f.Name(), ".tf")
+		if f.IsDir() || !isHCL && !strings.HasSuffix(f.Name(), ".tf.json") {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		src, err := w.fs.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var hclFile *hcl.File
+		var diags hcl.Diagnostics
+		if isHCL {
+			hclFile, diags = parser.ParseHCL(src, path)
+		} else {
+			hclFile, diags = parser.ParseJSON(src, path)
+		}
+		if diags.HasErrors() {
+			return nil, errors.New(diags.Error())
+		}
+
+		content, _, diags := hclFile.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "output", LabelNames: []string{"name"}}},
+		})
+		if diags.HasErrors() {
+			return nil, errors.New(diags.Error())
+		}
+		for _, block := range content.Blocks {
+			output, _, diags := block.Body.PartialContent(&hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{{Name: "sensitive"}},
+			})
+			if diags.HasErrors() {
+				return nil, errors.New(diags.Error())
+			}
+			attr, ok := output.Attributes["sensitive"]
+			if !ok {
+				continue
+			}
+			var isSensitive bool
+			if diags = gohcl.DecodeExpression(attr.Expr, nil, &isSensitive); diags.HasErrors() {
+				return nil, errors.New(diags.Error())
+			}
+			if isSensitive {
+				sensitive[block.Labels[0]] = true
+			}
+		}
+	}
+	return sensitive, nil
+}
+
+// ConvertModuleState converts the Terraform state of the module to attributes of the Kusion resource, which are
+// inputs of the module with its outputs and resources. Outputs in sensitiveOutputs and sensitive values of resources
+// are dropped, so they are never saved in Kusion states or shown in previews. Nil is returned if the module doesn't
+// exist in the state
+func ConvertModuleState(tfState *StateRepresentation, res *models.Resource, sensitiveOutputs map[string]bool) map[string]interface{} {
+	if tfState == nil || tfState.Values == nil {
+		return nil
+	}
+	return moduleAttributes(tfState.Values, res, sensitiveOutputs)
+}
+
+// ConvertModulePlan converts planned values of the module to attributes of the Kusion resource like
+// ConvertModuleState. Values unknown until apply are absent
+func ConvertModulePlan(pr *PlanRepresentation, res *models.Resource, sensitiveOutputs map[string]bool) map[string]interface{} {
+	if pr == nil {
+		return nil
+	}
+	return moduleAttributes(&pr.PlannedValues, res, sensitiveOutputs)
+}
+
+func moduleAttributes(values *stateValues, res *models.Resource, sensitiveOutputs map[string]bool) map[string]interface{} {
+	name := ModuleName(res)
+	outputs := map[string]interface{}{}
+	o, hasOutputs := values.Outputs[name]
+	if hasOutputs && len(o.Value) > 0 {
+		// the output is an object of all outputs of the module
+		_ = json.Unmarshal(o.Value, &outputs)
+	}
+	for k := range outputs {
+		if sensitiveOutputs[k] {
+			delete(outputs, k)
+		}
+	}
+
+	resources := map[string]interface{}{}
+	address := "module." + name
+	found := false
+	for _, m := range values.RootModule.ChildModules {
+		if m.Address == address {
+			collectModuleResources(m, address+".", resources)
+			found = true
+		}
+	}
+	if !found && !hasOutputs {
+		return nil
+	}
+
+	attributes := ModuleInputs(res.Attributes)
+	attributes[ModuleOutputsAttribute] = outputs
+	attributes[ModuleResourcesAttribute] = resources
+	return attributes
+}
+
+// collectModuleResources collects values of resources in the module and its child modules, keyed by addresses
+// without the prefix. Sensitive values are dropped
+func collectModuleResources(m module, prefix string, resources map[string]interface{}) {
+	for _, r := range m.Resources {
+		var sensitive interface{}
+		if len(r.SensitiveValues) > 0 {
+			_ = json.Unmarshal(r.SensitiveValues, &sensitive)
+		}
+		values, keep := dropSensitive(map[string]interface{}(r.AttributeValues), sensitive)
+		if !keep {
+			values = map[string]interface{}{}
+		}
+		resources[strings.TrimPrefix(r.Address, prefix)] = values
+	}
+	for _, child := range m.ChildModules {
+		collectModuleResources(child, prefix, resources)
+	}
+}
+
+// dropSensitive returns a copy of value without the parts marked true in sensitive, which has the structure of
+// value. False is returned if the whole value is sensitive
+func dropSensitive(value, sensitive interface{}) (interface{}, bool) {
+	switch s := sensitive.(type) {
+	case bool:
+		return value, !s
+	case map[string]interface{}:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value, true
+		}
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if v, keep := dropSensitive(v, s[k]); keep {
+				result[k] = v
+			}
+		}
+		return result, true
+	case []interface{}:
+		l, ok := value.([]interface{})
+		if !ok {
+			return value, true
+		}
+		// sensitive elements are replaced by nil to keep indexes of others
+		result := make([]interface{}, len(l))
+		for i, v := range l {
+			if i >= len(s) {
+				result[i] = v
+			} else if v, keep := dropSensitive(v, s[i]); keep {
+				result[i] = v
+			}
+		}
+		return result, true
+	default:
+		return value, true
+	}
+}
@@ -0,0 +1,176 @@
+package tfops
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+var moduleTest = models.Resource{
+	ID:   "aws:module:vpc",
+	Type: "Terraform",
+	Attributes: map[string]interface{}{
+		"cidr": "10.0.0.0/16",
+	},
+	Extensions: map[string]interface{}{
+		ModeExtension:    ModuleMode,
+		SourceExtension:  "terraform-aws-modules/vpc/aws",
+		VersionExtension: "5.0.0",
+		"provider":       "registry.terraform.io/hashicorp/aws/5.0.0",
+		"providerMeta":   map[string]interface{}{"region": "us-east-1"},
+	},
+}
+
+func TestWriteHCL_Module(t *testing.T) {
+	w := NewWorkSpace(fs)
+	w.SetCacheDir(t.TempDir())
+
+	t.Run("Module", func(t *testing.T) {
+		w.SetResource(&moduleTest)
+		assert.Nil(t, w.WriteHCL())
+
+		data, err := fs.ReadFile(filepath.Join(w.tfCacheDir, mainTFFile))
+		assert.Nil(t, err)
+		m := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(data, &m))
+		assert.Equal(t, map[string]interface{}{
+			"vpc": map[string]interface{}{"source": "terraform-aws-modules/vpc/aws", "version": "5.0.0", "cidr": "10.0.0.0/16"},
+		}, m["module"])
+		assert.Equal(t, map[string]interface{}{
+			"vpc": map[string]interface{}{"value": "${module.vpc}", "sensitive": true},
+		}, m["output"])
+		assert.Equal(t, map[string]interface{}{"aws": map[string]interface{}{"region": "us-east-1"}}, m["provider"])
+		assert.NotContains(t, m, "resource")
+	})
+
+	t.Run("ResolvedProviderMeta", func(t *testing.T) {
+		resolved := moduleTest.DeepCopy()
+		resolved.Extensions["providerMeta"] = map[string]interface{}{"region": "us-east-1", "secret_key": "ref+vault://aws/key"}
+		resolved.ResolvedExtensions = map[string]interface{}{
+			"providerMeta": map[string]interface{}{"region": "us-east-1", "secret_key": "secret"},
+		}
+		w.SetResource(resolved)
+		assert.Nil(t, w.WriteHCL())
+
+		data, err := fs.ReadFile(filepath.Join(w.tfCacheDir, mainTFFile))
+		assert.Nil(t, err)
+		assert.NotContains(t, string(data), "secret\"")
+		m := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(data, &m))
+		assert.Equal(t, map[string]interface{}{
+			"aws": map[string]interface{}{"region": "us-east-1", "secret_key": "${var.kusion_provider_meta_0}"},
+		}, m["provider"])
+		assert.Equal(t, map[string]interface{}{
+			"kusion_provider_meta_0": map[string]interface{}{"type": "string", "sensitive": true},
+		}, m["variable"])
+
+		envs, err := w.initEnvs()
+		assert.Nil(t, err)
+		assert.Contains(t, envs, "TF_VAR_kusion_provider_meta_0=secret")
+	})
+
+	t.Run("NoSource", func(t *testing.T) {
+		noSource := moduleTest.DeepCopy()
+		delete(noSource.Extensions, SourceExtension)
+		w.SetResource(noSource)
+		assert.ErrorContains(t, w.WriteHCL(), "source of terraform module aws:module:vpc is empty")
+	})
+}
+
+func TestConvertModuleState(t *testing.T) {
+	data := `{"values":{
+		"outputs":{"vpc":{"sensitive":true,"value":{"vpc_id":"vpc-1"}}},
+		"root_module":{"child_modules":[{"address":"module.vpc",
+			"resources":[{"address":"module.vpc.aws_vpc.this[0]","mode":"managed","type":"aws_vpc","name":"this","values":{"id":"vpc-1"}}],
+			"child_modules":[{"address":"module.vpc.module.flow_log",
+				"resources":[{"address":"module.vpc.module.flow_log.aws_flow_log.this","mode":"managed","type":"aws_flow_log","name":"this","values":{"id":"fl-1"}}]}]}]}}}`
+	tfState := &StateRepresentation{}
+	assert.Nil(t, json.Unmarshal([]byte(data), tfState))
+
+	// attributes computed in the prior state are replaced
+	res := moduleTest.DeepCopy()
+	res.Attributes[ModuleOutputsAttribute] = map[string]interface{}{"vpc_id": "vpc-0"}
+	assert.Equal(t, map[string]interface{}{
+		"cidr":                 "10.0.0.0/16",
+		ModuleOutputsAttribute: map[string]interface{}{"vpc_id": "vpc-1"},
+		ModuleResourcesAttribute: map[string]interface{}{
+			"aws_vpc.this[0]":                   map[string]interface{}{"id": "vpc-1"},
+			"module.flow_log.aws_flow_log.this": map[string]interface{}{"id": "fl-1"},
+		},
+	}, ConvertModuleState(tfState, res, nil))
+
+	// the module is absent after destroy
+	assert.Nil(t, ConvertModuleState(&StateRepresentation{Values: &stateValues{}}, res, nil))
+	assert.Nil(t, ConvertModuleState(nil, res, nil))
+}
+
+func TestConvertModuleState_Sensitive(t *testing.T) {
+	data := `{"values":{
+		"outputs":{"vpc":{"sensitive":true,"value":{"vpc_id":"vpc-1","token":"secret"}}},
+		"root_module":{"child_modules":[{"address":"module.vpc",
+			"resources":[{"address":"module.vpc.aws_db_instance.this","mode":"managed","type":"aws_db_instance","name":"this",
+				"values":{"id":"db-1","password":"secret","users":[{"name":"foo","password":"secret"}],"tokens":["a","secret"]},
+				"sensitive_values":{"password":true,"users":[{"password":true}],"tokens":[false,true]}},
+			{"address":"module.vpc.random_password.this","mode":"managed","type":"random_password","name":"this",
+				"values":{"result":"secret"},"sensitive_values":true}]}]}}}`
+	tfState := &StateRepresentation{}
+	assert.Nil(t, json.Unmarshal([]byte(data), tfState))
+
+	assert.Equal(t, map[string]interface{}{
+		"cidr":                 "10.0.0.0/16",
+		ModuleOutputsAttribute: map[string]interface{}{"vpc_id": "vpc-1"},
+		ModuleResourcesAttribute: map[string]interface{}{
+			"aws_db_instance.this": map[string]interface{}{
+				"id":     "db-1",
+				"users":  []interface{}{map[string]interface{}{"name": "foo"}},
+				"tokens": []interface{}{"a", nil},
+			},
+			"random_password.this": map[string]interface{}{},
+		},
+	}, ConvertModuleState(tfState, &moduleTest, map[string]bool{"token": true}))
+}
+
+func TestSensitiveModuleOutputs(t *testing.T) {
+	w := NewWorkSpace(fs)
+	w.SetCacheDir(t.TempDir())
+	w.SetResource(&moduleTest)
+
+	_, err := w.SensitiveModuleOutputs()
+	assert.NotNil(t, err)
+
+	moduleDir := filepath.Join(w.tfCacheDir, ".terraform", "modules", "vpc")
+	assert.Nil(t, fs.MkdirAll(moduleDir, os.ModePerm))
+	assert.Nil(t, fs.WriteFile(filepath.Join(w.tfCacheDir, modulesManifest),
+		[]byte(`{"Modules":[{"Key":"","Source":"","Dir":"."},{"Key":"vpc","Source":"terraform-aws-modules/vpc/aws","Dir":".terraform/modules/vpc"}]}`), os.ModePerm))
+	assert.Nil(t, fs.WriteFile(filepath.Join(moduleDir, "outputs.tf"), []byte(`
+output "vpc_id" {
+  value = aws_vpc.this.id
+}
+
+output "token" {
+  value     = random_password.this.result
+  sensitive = true
+}
+`), os.ModePerm))
+	assert.Nil(t, fs.WriteFile(filepath.Join(moduleDir, "extra.tf.json"),
+		[]byte(`{"output":{"key":{"value":"${aws_kms_key.this.arn}","sensitive":true},"arn":{"value":"${aws_vpc.this.arn}","sensitive":false}}}`), os.ModePerm))
+
+	sensitive, err := w.SensitiveModuleOutputs()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"token": true, "key": true}, sensitive)
+
+	w.SetResource(&models.Resource{ID: "aws:module:subnet", Extensions: moduleTest.Extensions})
+	_, err = w.SensitiveModuleOutputs()
+	assert.ErrorContains(t, err, "terraform module subnet is not installed")
+}
+
+func TestModuleInputs(t *testing.T) {
+	attributes := map[string]interface{}{"cidr": "10.0.0.0/16", ModuleOutputsAttribute: map[string]interface{}{}, ModuleResourcesAttribute: map[string]interface{}{}}
+	assert.Equal(t, map[string]interface{}{"cidr": "10.0.0.0/16"}, ModuleInputs(attributes))
+	assert.Len(t, attributes, 3)
+}
@@ -0,0 +1,173 @@
+package tfops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Subcommands replayed by ReplayExecutor. RefreshCommand is `apply -refresh-only`, and ShowPlanCommand and
+// ShowStateCommand are `show -json` of the plan file and the state file
+const (
+	InitCommand      = "init"
+	PlanCommand      = "plan"
+	ApplyCommand     = "apply"
+	RefreshCommand   = "refresh"
+	DestroyCommand   = "destroy"
+	ShowPlanCommand  = "show-plan"
+	ShowStateCommand = "show-state"
+	VersionCommand   = "version"
+)
+
+// Fixture is the recorded result of a subcommand
+type Fixture struct {
+	// Output is the combined stdout and stderr
+	Output []byte
+
+	// ExitCode is the exit code. The subcommand fails if it is not zero
+	ExitCode int
+}
+
+var _ Executor = (*ReplayExecutor)(nil)
+
+// ReplayExecutor replays recorded fixtures of subcommands instead of running the Terraform CLI, so workspaces and the
+// Terraform runtime can be tested without the CLI, providers or network. Subcommands without fixtures succeed with
+// empty output. Files written by the Terraform CLI and checked by workspaces are created as well: the lock file on
+// init if it is recorded, the plan file on plan, and the state file on apply and refresh.
+//
+// Its zero value is not usable, and NewReplayExecutor or LoadFixtures must be used
+type ReplayExecutor struct {
+	mu       sync.Mutex
+	fixtures map[string]Fixture
+	lockFile []byte
+	commands []string
+}
+
+func NewReplayExecutor() *ReplayExecutor {
+	return &ReplayExecutor{fixtures: map[string]Fixture{}}
+}
+
+// LoadFixtures returns a ReplayExecutor with fixtures in files of the directory. A file named after a subcommand with
+// the .json extension, such as show-plan.json, is the output of the subcommand which succeeds, and one with the .err
+// extension, such as apply.err, is the output of the subcommand which fails. The .terraform.lock.hcl file is the lock
+// file written on init
+func LoadFixtures(dir string) (*ReplayExecutor, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	e := NewReplayExecutor()
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ext := filepath.Ext(entry.Name())
+		subcommand := strings.TrimSuffix(entry.Name(), ext)
+		switch {
+		case entry.Name() == LockHCLFile:
+			e.SetLockFile(data)
+		case ext == ".json":
+			e.Record(subcommand, Fixture{Output: data})
+		case ext == ".err":
+			e.Record(subcommand, Fixture{Output: data, ExitCode: 1})
+		default:
+			return nil, fmt.Errorf("unknown fixture file %s", entry.Name())
+		}
+	}
+	return e, nil
+}
+
+// Record makes the subcommand return the fixture
+func (e *ReplayExecutor) Record(subcommand string, fixture Fixture) *ReplayExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fixtures[subcommand] = fixture
+	return e
+}
+
+// SetLockFile sets the lock file written on init
+func (e *ReplayExecutor) SetLockFile(content []byte) *ReplayExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lockFile = content
+	return e
+}
+
+// Commands returns replayed subcommands in order
+func (e *ReplayExecutor) Commands() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.commands...)
+}
+
+func (e *ReplayExecutor) Execute(ctx context.Context, cmd *Command) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dir, args := cmd.Dir, cmd.Args
+	if len(args) > 0 && strings.HasPrefix(args[0], "-chdir=") {
+		dir = strings.TrimPrefix(args[0], "-chdir=")
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cmd.Dir, dir)
+		}
+		args = args[1:]
+	}
+	subcommand := subcommandOf(args)
+
+	e.mu.Lock()
+	e.commands = append(e.commands, subcommand)
+	fixture := e.fixtures[subcommand]
+	lockFile := e.lockFile
+	e.mu.Unlock()
+
+	if fixture.ExitCode != 0 {
+		return fixture.Output, &ExitError{Code: fixture.ExitCode}
+	}
+	var err error
+	switch subcommand {
+	case InitCommand:
+		if lockFile != nil {
+			err = os.WriteFile(filepath.Join(dir, LockHCLFile), lockFile, 0o600)
+		}
+	case PlanCommand:
+		err = os.WriteFile(filepath.Join(dir, tfPlanFile), nil, 0o600)
+	case ApplyCommand, RefreshCommand:
+		if _, statErr := os.Stat(filepath.Join(dir, TFStateFile)); os.IsNotExist(statErr) {
+			err = os.WriteFile(filepath.Join(dir, TFStateFile), []byte("{}"), 0o600)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fixture.Output, nil
+}
+
+// subcommandOf returns the replayed subcommand of args without -chdir
+func subcommandOf(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	switch args[0] {
+	case "apply":
+		for _, arg := range args[1:] {
+			if arg == "-refresh-only" || arg == "--refresh-only" {
+				return RefreshCommand
+			}
+		}
+		return ApplyCommand
+	case "show":
+		if args[len(args)-1] == TFStateFile {
+			return ShowStateCommand
+		}
+		return ShowPlanCommand
+	default:
+		return args[0]
+	}
+}
@@ -0,0 +1,82 @@
+package tfops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubcommandOf(t *testing.T) {
+	assert.Equal(t, InitCommand, subcommandOf([]string{"init", "-plugin-dir=/plugins"}))
+	assert.Equal(t, ApplyCommand, subcommandOf([]string{"apply", "-auto-approve", "-json", "-lock=false"}))
+	assert.Equal(t, RefreshCommand, subcommandOf([]string{"apply", "-auto-approve", "-json", "--refresh-only", "-lock=false"}))
+	assert.Equal(t, ShowPlanCommand, subcommandOf([]string{"show", "-json", tfPlanFile}))
+	assert.Equal(t, ShowStateCommand, subcommandOf([]string{"show", "-json", TFStateFile}))
+	assert.Equal(t, "", subcommandOf(nil))
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "show-state.json"), []byte(`{"values":{}}`), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "destroy.err"), []byte("oops"), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, LockHCLFile), []byte("lock"), 0o600))
+	e, err := LoadFixtures(dir)
+	assert.Nil(t, err)
+
+	assert.Equal(t, Fixture{Output: []byte(`{"values":{}}`)}, e.fixtures[ShowStateCommand])
+	assert.Equal(t, Fixture{Output: []byte("oops"), ExitCode: 1}, e.fixtures[DestroyCommand])
+	assert.Equal(t, []byte("lock"), e.lockFile)
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o600))
+	_, err = LoadFixtures(dir)
+	assert.ErrorContains(t, err, "unknown fixture file README.md")
+}
+
+const lockFileTest = `provider "registry.terraform.io/hashicorp/local" {
+  version = "2.2.3"
+}
+`
+
+func TestReplayExecutor(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	t.Setenv("HOME", dir)
+	w := NewWorkSpace(fs)
+	w.SetResource(&resourceTest)
+	w.SetStackDir(dir)
+	w.SetCacheDir(filepath.Join(dir, ".cache"))
+	assert.Nil(t, w.WriteHCL())
+
+	e := NewReplayExecutor().SetLockFile([]byte(lockFileTest))
+	e.Record(ShowPlanCommand, Fixture{Output: []byte(`{"planned_values":{"root_module":{"resources":[{"name":"kusion_example","values":{"content":"kusion"}}]}}}`)})
+	e.Record(ShowStateCommand, Fixture{Output: []byte(`{"values":{"root_module":{"resources":[{"name":"kusion_example","values":{"id":"foo"}}]}}}`)})
+	e.Record(DestroyCommand, Fixture{
+		Output:   []byte(`{"@level":"error","diagnostic":{"severity":"error","summary":"Resource is protected","detail":"Remove the protection first."}}`),
+		ExitCode: 1,
+	})
+	w.SetExecutor(e)
+
+	assert.Nil(t, w.InitWorkSpace(context.TODO()))
+	provider, err := w.GetProvider()
+	assert.Nil(t, err)
+	assert.Equal(t, "registry.terraform.io/hashicorp/local/2.2.3", provider)
+
+	plan, err := w.Plan(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "kusion", plan.PlannedValues.RootModule.Resources[0].AttributeValues["content"])
+
+	state, err := w.Apply(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", state.Values.RootModule.Resources[0].AttributeValues["id"])
+
+	assert.EqualError(t, w.Destroy(context.TODO()), "Resource is protected. Remove the protection first.\n")
+	assert.Equal(t, []string{
+		InitCommand,
+		PlanCommand, ShowPlanCommand,
+		ApplyCommand, RefreshCommand, ShowStateCommand,
+		DestroyCommand,
+	}, e.Commands())
+}
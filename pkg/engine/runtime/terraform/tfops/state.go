@@ -92,6 +92,9 @@ type resource struct {
 	// from absent values.
 	AttributeValues attributeValues `json:"values,omitempty"`
 
+	// SensitiveValues has the structure of AttributeValues, whose leaves are true if the attributes are sensitive
+	SensitiveValues json.RawMessage `json:"sensitive_values,omitempty"`
+
 	// DependsOn contains a list of the resource's dependencies. The entries are
 	// addresses relative to the containing module.
 	DependsOn []string `json:"depends_on,omitempty"`
@@ -109,7 +112,7 @@ type attributeValues map[string]interface{}
 
 // ConvertTFState convert Terraform State to kusion State
 func ConvertTFState(tfState *StateRepresentation, providerAddr string) models.Resource {
-	if tfState == nil || tfState.Values == nil {
+	if tfState == nil || tfState.Values == nil || len(tfState.Values.RootModule.Resources) == 0 {
 		return models.Resource{}
 	}
 	// terraform runtime execute single node
@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
@@ -18,6 +19,7 @@ import (
 
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/util/io"
 	jsonutil "kusionstack.io/kusion/pkg/util/json"
 	"kusionstack.io/kusion/pkg/util/kfile"
@@ -26,24 +28,46 @@ import (
 const (
 	envLog            = "TF_LOG"
 	envPluginCacheDir = "TF_PLUGIN_CACHE_DIR"
+	envCLIConfigFile  = "TF_CLI_CONFIG_FILE"
 	tfDebugLOG        = "DEBUG"
 	envLogPath        = "TF_LOG_PATH"
+	envVarPrefix      = "TF_VAR_"
+	providerVarPrefix = "kusion_provider_meta_"
 	LockHCLFile       = ".terraform.lock.hcl"
 	mainTFFile        = "main.tf.json"
 	tfPlanFile        = "plan.out"
-	tfStateFile       = "terraform.tfstate"
+	TFStateFile       = "terraform.tfstate"
 	tfProviderPrefix  = "terraform-provider"
 	terraformD        = ".terraform.d"
 	pluginCache       = "plugin-cache"
 )
 
+// ModeExtension is the key in Extensions of a Terraform resource telling whether it is a managed resource or a data
+// source. A resource without it is a managed resource
+const ModeExtension = "mode"
+
+// Modes of Terraform resources. A module resource is a Terraform module whose attributes are its inputs
+const (
+	ManagedMode = "managed"
+	DataMode    = "data"
+	ModuleMode  = "module"
+)
+
 var envTFLog = fmt.Sprintf("%s=%s", envLog, tfDebugLOG)
 
+// initMu serializes `terraform init` of all workspaces, since they share the provider plugin cache, which is not
+// safe for concurrent writes
+var initMu sync.Mutex
+
+// WorkSpace runs Terraform commands of one resource in its cache directory. A WorkSpace is not safe for concurrent
+// use, and each resource should have its own
 type WorkSpace struct {
 	resource   *models.Resource
 	fs         afero.Afero
 	stackDir   string
 	tfCacheDir string
+	config     *projectstack.TerraformConfig
+	executor   Executor
 }
 
 // SetResource set workspace resource
@@ -66,6 +90,24 @@ func (w *WorkSpace) SetCacheDir(cacheDir string) {
 	w.tfCacheDir = cacheDir
 }
 
+// SetConfig set Terraform configs of the project. Paths in the configs must be absolute
+func (w *WorkSpace) SetConfig(config *projectstack.TerraformConfig) {
+	w.config = config
+}
+
+// SetExecutor set the executor of Terraform commands
+func (w *WorkSpace) SetExecutor(executor Executor) {
+	w.executor = executor
+}
+
+// getExecutor returns the executor of the workspace. CLIExecutor is used if no executor is set
+func (w *WorkSpace) getExecutor() Executor {
+	if w.executor == nil {
+		return CLIExecutor{}
+	}
+	return w.executor
+}
+
 func NewWorkSpace(fs afero.Afero) *WorkSpace {
 	return &WorkSpace{
 		fs: fs,
@@ -75,14 +117,64 @@ func NewWorkSpace(fs afero.Afero) *WorkSpace {
 // WriteHCL convert kusion Resource to HCL json
 // and write hcl json to main.tf.json
 func (w *WorkSpace) WriteHCL() error {
-	provider := strings.Split(w.resource.Extensions["provider"].(string), "/")
-	resourceType := w.resource.Extensions["resourceType"].(string)
+	var m map[string]interface{}
+	var err error
+	if IsModule(w.resource) {
+		m, err = w.moduleHCL()
+	} else {
+		m, err = w.resourceHCL()
+	}
+	if err != nil {
+		return err
+	}
+	hclMain := jsonutil.Marshal2PrettyString(m)
+
+	_, err = w.fs.Stat(w.tfCacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if err := w.fs.MkdirAll(w.tfCacheDir, os.ModePerm); err != nil {
+				return fmt.Errorf("create workspace error: %v", err)
+			}
+		} else {
+			return err
+		}
+	}
+	err = w.fs.WriteFile(filepath.Join(w.tfCacheDir, mainTFFile), []byte(hclMain), 0o600)
+	if err != nil {
+		return fmt.Errorf("write hcl main.tf.json error: %v", err)
+	}
+
+	return nil
+}
+
+// resourceHCL returns the HCL json of a managed resource or a data source
+func (w *WorkSpace) resourceHCL() (map[string]interface{}, error) {
+	resourceType := w.resource.ResolvedExtension("resourceType").(string)
 	resourceNames := strings.Split(w.resource.ResourceKey(), ":")
 	if len(resourceNames) < 4 {
-		return fmt.Errorf("illegial resource id:%s in Spec. "+
+		return nil, fmt.Errorf("illegial resource id:%s in Spec. "+
 			"Resource id format: providerNamespace:providerName:resourceType:resourceName", w.resource.ResourceKey())
 	}
 
+	block := "resource"
+	if IsDataSource(w.resource) {
+		block = "data"
+	}
+	m := providerHCL(w.resource.ResolvedExtension("provider").(string), w.resource)
+	m[block] = map[string]interface{}{
+		resourceType: map[string]interface{}{
+			resourceNames[len(resourceNames)-1]: w.resource.Attributes,
+		},
+	}
+	return m, nil
+}
+
+// providerHCL returns the HCL json requiring and configuring the provider of the resource, whose address is like
+// registry.terraform.io/hashicorp/local/2.2.3. Resolved values in providerMeta are replaced by sensitive variables,
+// see providerConfig
+func providerHCL(providerAddr string, res *models.Resource) map[string]interface{} {
+	provider := strings.Split(providerAddr, "/")
+	config, vars := providerConfig(res)
 	m := map[string]interface{}{
 		"terraform": map[string]interface{}{
 			"required_providers": map[string]interface{}{
@@ -93,37 +185,88 @@ func (w *WorkSpace) WriteHCL() error {
 			},
 		},
 		"provider": map[string]interface{}{
-			provider[len(provider)-2]: w.resource.Extensions["providerMeta"],
-		},
-		"resource": map[string]interface{}{
-			resourceType: map[string]interface{}{
-				resourceNames[len(resourceNames)-1]: w.resource.Attributes,
-			},
+			provider[len(provider)-2]: config,
 		},
 	}
-	hclMain := jsonutil.Marshal2PrettyString(m)
+	if len(vars) > 0 {
+		variables := map[string]interface{}{}
+		for name := range vars {
+			variables[name] = map[string]interface{}{"type": "string", "sensitive": true}
+		}
+		m["variable"] = variables
+	}
+	return m
+}
 
-	_, err := w.fs.Stat(w.tfCacheDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			if err := w.fs.MkdirAll(w.tfCacheDir, os.ModePerm); err != nil {
-				return fmt.Errorf("create workspace error: %v", err)
+// providerConfig returns providerMeta of the resource written in main.tf.json, and values of variables in it keyed by
+// their names. String values resolved from references, such as secrets read from Vault, are replaced by references
+// to sensitive variables, whose values are passed to Terraform by environment variables instead of being written to
+// files
+func providerConfig(res *models.Resource) (interface{}, map[string]string) {
+	vars := map[string]string{}
+	config := maskResolved(res.Extensions["providerMeta"], res.ResolvedExtension("providerMeta"), vars)
+	return config, vars
+}
+
+// maskResolved replaces strings in resolved, which differ from the ones at the same paths in raw, by variables
+func maskResolved(raw, resolved interface{}, vars map[string]string) interface{} {
+	switch v := resolved.(type) {
+	case map[string]interface{}:
+		rawMap, _ := raw.(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// variables are named in order, so main.tf.json doesn't change between runs
+		sort.Strings(keys)
+		masked := make(map[string]interface{}, len(v))
+		for _, k := range keys {
+			masked[k] = maskResolved(rawMap[k], v[k], vars)
+		}
+		return masked
+	case []interface{}:
+		rawList, _ := raw.([]interface{})
+		masked := make([]interface{}, len(v))
+		for i := range v {
+			var r interface{}
+			if i < len(rawList) {
+				r = rawList[i]
 			}
-		} else {
-			return err
+			masked[i] = maskResolved(r, v[i], vars)
+		}
+		return masked
+	case string:
+		if r, ok := raw.(string); ok && r == v {
+			return v
 		}
+		name := fmt.Sprintf("%s%d", providerVarPrefix, len(vars))
+		vars[name] = v
+		return fmt.Sprintf("${var.%s}", name)
+	default:
+		return resolved
 	}
-	err = w.fs.WriteFile(filepath.Join(w.tfCacheDir, mainTFFile), []byte(hclMain), 0o600)
-	if err != nil {
-		return fmt.Errorf("write hcl main.tf.json error: %v", err)
+}
+
+// IsDataSource returns true if the resource is a Terraform data source, which is only read and never created or
+// deleted
+func IsDataSource(res *models.Resource) bool {
+	if res == nil {
+		return false
 	}
+	mode, _ := res.ResolvedExtension(ModeExtension).(string)
+	return mode == DataMode
+}
 
-	return nil
+func modeOf(res *models.Resource) string {
+	if IsDataSource(res) {
+		return DataMode
+	}
+	return ManagedMode
 }
 
 // WriteTFState writes StateRepresentation to the file, this function is for terraform apply refresh only
 func (w *WorkSpace) WriteTFState(priorState *models.Resource) error {
-	provider := strings.Split(priorState.Extensions["provider"].(string), "/")
+	provider := strings.Split(priorState.ResolvedExtension("provider").(string), "/")
 	resourceNames := strings.Split(w.resource.ResourceKey(), ":")
 	if len(resourceNames) < 4 {
 		return fmt.Errorf("illegial resource id:%s in terraform.tfstate. "+
@@ -133,8 +276,8 @@ func (w *WorkSpace) WriteTFState(priorState *models.Resource) error {
 		"version": 4,
 		"resources": []map[string]interface{}{
 			{
-				"mode":     "managed",
-				"type":     priorState.Extensions["resourceType"].(string),
+				"mode":     modeOf(priorState),
+				"type":     priorState.ResolvedExtension("resourceType").(string),
 				"name":     resourceNames[len(resourceNames)-1],
 				"provider": fmt.Sprintf("provider[\"%s\"]", strings.Join(provider[:len(provider)-1], "/")),
 				"instances": []map[string]interface{}{
@@ -147,7 +290,7 @@ func (w *WorkSpace) WriteTFState(priorState *models.Resource) error {
 	}
 	hclState := jsonutil.Marshal2PrettyString(m)
 
-	err := w.fs.WriteFile(filepath.Join(w.tfCacheDir, tfStateFile), []byte(hclState), os.ModePerm)
+	err := w.fs.WriteFile(filepath.Join(w.tfCacheDir, TFStateFile), []byte(hclState), os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("write hcl error: %v", err)
 	}
@@ -156,23 +299,29 @@ func (w *WorkSpace) WriteTFState(priorState *models.Resource) error {
 
 // InitWorkSpace init terraform runtime workspace
 func (w *WorkSpace) InitWorkSpace(ctx context.Context) error {
-	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
-	cmd := exec.CommandContext(ctx, "terraform", chdir, "init")
-	cmd.Dir = w.stackDir
+	args := []string{"init"}
+	if w.config != nil {
+		for _, dir := range w.config.PluginDirs {
+			args = append(args, "-plugin-dir="+dir)
+		}
+	}
 	envs, err := w.initEnvs()
 	if err != nil {
 		return err
 	}
-	cmd.Env = envs
-	_, err = cmd.Output()
-	if e, ok := err.(*exec.ExitError); ok {
-		return errors.New(string(e.Stderr))
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	out, err := w.execute(ctx, envs, args...)
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return errors.New(string(out))
 	}
 	return nil
 }
 
 func (w *WorkSpace) initEnvs() ([]string, error) {
-	providerCachePath, err := getProviderCachePath()
+	providerCachePath, err := w.getProviderCachePath()
 	if err != nil {
 		return nil, err
 	}
@@ -181,26 +330,44 @@ func (w *WorkSpace) initEnvs() ([]string, error) {
 		return nil, err
 	}
 	result := append(os.Environ(), envTFLog, providerCachePath, logPath)
+	_, vars := providerConfig(w.resource)
+	for name, value := range vars {
+		result = append(result, envVarPrefix+name+"="+value)
+	}
+
+	// providers are installed from the mirror only, which is configured in a CLI config file of the workspace
+	if w.config != nil && w.config.ProviderMirror != "" {
+		cliConfig, err := w.writeCLIConfig()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fmt.Sprintf("%s=%s", envCLIConfigFile, cliConfig))
+	}
 	return result, nil
 }
 
+// execute runs the subcommand with args in the cache directory by the executor of the workspace
+func (w *WorkSpace) execute(ctx context.Context, envs []string, args ...string) ([]byte, error) {
+	return w.getExecutor().Execute(ctx, &Command{
+		Binary: w.binary(),
+		Args:   append([]string{fmt.Sprintf("-chdir=%s", w.tfCacheDir)}, args...),
+		Dir:    w.stackDir,
+		Env:    envs,
+	})
+}
+
 // Apply with the terraform cli apply command
 func (w *WorkSpace) Apply(ctx context.Context) (*StateRepresentation, error) {
-	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
 	err := w.CleanAndInitWorkspace(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, "terraform", chdir, "apply", "-auto-approve", "-json", "-lock=false")
-	cmd.Dir = w.stackDir
 	envs, err := w.initEnvs()
 	if err != nil {
 		return nil, err
 	}
-	cmd.Env = envs
-
-	out, err := cmd.CombinedOutput()
+	out, err := w.execute(ctx, envs, "apply", "-auto-approve", "-json", "-lock=false")
 	if err != nil {
 		return nil, TFError(out)
 	}
@@ -214,24 +381,23 @@ func (w *WorkSpace) Apply(ctx context.Context) (*StateRepresentation, error) {
 
 // Plan with the terraform cli plan command
 func (w *WorkSpace) Plan(ctx context.Context) (*PlanRepresentation, error) {
-	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
 	err := w.CleanAndInitWorkspace(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, "terraform", chdir, "plan", "-out="+tfPlanFile)
-	cmd.Dir = w.stackDir
 	envs, err := w.initEnvs()
 	if err != nil {
 		return nil, err
 	}
-	cmd.Env = envs
-
-	out, err := cmd.CombinedOutput()
+	out, err := w.execute(ctx, envs, "plan", "-out="+tfPlanFile)
 	if err != nil {
 		return nil, TFError(out)
 	}
+	// the plan file contains values of variables, including resolved values in providerMeta
+	defer func() {
+		_ = w.fs.Remove(filepath.Join(w.tfCacheDir, tfPlanFile))
+	}()
 	// convert plan result to PlanRepresentation
 	pr, err := w.ShowPlan(ctx)
 	if err != nil {
@@ -243,7 +409,7 @@ func (w *WorkSpace) Plan(ctx context.Context) (*PlanRepresentation, error) {
 
 // ShowState shows local tfstate with the terraform cli show command
 func (w *WorkSpace) ShowState(ctx context.Context) (*StateRepresentation, error) {
-	fi, err := w.fs.Stat(filepath.Join(w.tfCacheDir, tfStateFile))
+	fi, err := w.fs.Stat(filepath.Join(w.tfCacheDir, TFStateFile))
 	if os.IsNotExist(err) {
 		return nil, nil
 	}
@@ -284,10 +450,7 @@ func (w *WorkSpace) ShowPlan(ctx context.Context) (*PlanRepresentation, error) {
 }
 
 func (w *WorkSpace) show(ctx context.Context, fileName string) ([]byte, error) {
-	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
-	cmd := exec.CommandContext(ctx, "terraform", chdir, "show", "-json", fileName)
-	cmd.Dir = w.stackDir
-	out, err := cmd.CombinedOutput()
+	out, err := w.execute(ctx, nil, "show", "-json", fileName)
 	if err != nil {
 		return nil, TFError(out)
 	}
@@ -296,21 +459,16 @@ func (w *WorkSpace) show(ctx context.Context, fileName string) ([]byte, error) {
 
 // RefreshOnly refresh Terraform State
 func (w *WorkSpace) RefreshOnly(ctx context.Context) (*StateRepresentation, error) {
-	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
 	err := w.CleanAndInitWorkspace(ctx)
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.CommandContext(ctx, "terraform", chdir, "apply", "-auto-approve", "-json", "--refresh-only", "-lock=false")
-	cmd.Dir = w.stackDir
 
 	envs, err := w.initEnvs()
 	if err != nil {
 		return nil, err
 	}
-	cmd.Env = envs
-
-	out, err := cmd.CombinedOutput()
+	out, err := w.execute(ctx, envs, "apply", "-auto-approve", "-json", "--refresh-only", "-lock=false")
 	if err != nil {
 		return nil, TFError(out)
 	}
@@ -323,15 +481,11 @@ func (w *WorkSpace) RefreshOnly(ctx context.Context) (*StateRepresentation, erro
 
 // Destroy make terraform destroy call.
 func (w *WorkSpace) Destroy(ctx context.Context) error {
-	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
-	cmd := exec.CommandContext(ctx, "terraform", chdir, "destroy", "-auto-approve")
-	cmd.Dir = w.stackDir
 	envs, err := w.initEnvs()
 	if err != nil {
 		return err
 	}
-	cmd.Env = envs
-	out, err := cmd.CombinedOutput()
+	out, err := w.execute(ctx, envs, "destroy", "-auto-approve")
 	if err != nil {
 		return TFError(out)
 	}
@@ -359,6 +513,9 @@ func (w *WorkSpace) GetProvider() (string, error) {
 	if diags != nil {
 		return "", errors.New(diags.Error())
 	}
+	if len(content.Blocks) == 0 {
+		return "", fmt.Errorf("no provider found in %s", LockHCLFile)
+	}
 	rawAddr := content.Blocks[0].Labels[0]
 
 	block := content.Blocks[0]
@@ -409,11 +566,15 @@ func (w *WorkSpace) CleanAndInitWorkspace(ctx context.Context) error {
 
 // checkVersionUpdate checks whether the provider version has changed, and returns true if changed
 func (w *WorkSpace) checkVersionUpdate() (bool, error) {
+	// modules may use many providers, and are initialized every time
+	if IsModule(w.resource) {
+		return false, nil
+	}
 	providerAddr, err := w.GetProvider()
 	if err != nil {
 		return false, fmt.Errorf("provider get version failed: %v", err)
 	}
-	return providerAddr != w.resource.Extensions["provider"].(string), nil
+	return providerAddr != w.resource.ResolvedExtension("provider").(string), nil
 }
 
 // getProviderLogPath returns the provider log path environmental variable,
@@ -426,20 +587,28 @@ func (w *WorkSpace) getEnvProviderLogPath() (string, error) {
 	if v := os.Getenv("LOG_DIR"); v != "" {
 		kusionDataDir = v
 	}
-	provider := strings.Split(w.resource.Extensions["provider"].(string), "/")
-	providerLogPath := filepath.Join(kusionDataDir, "logs", fmt.Sprintf("%s-%s.log", tfProviderPrefix, provider[len(provider)-2]))
+	providerName := "module"
+	if providerAddr, ok := w.resource.ResolvedExtension("provider").(string); ok && providerAddr != "" {
+		provider := strings.Split(providerAddr, "/")
+		providerName = provider[len(provider)-2]
+	}
+	providerLogPath := filepath.Join(kusionDataDir, "logs", fmt.Sprintf("%s-%s.log", tfProviderPrefix, providerName))
 	envTFLogPath := fmt.Sprintf("%s=%s", envLogPath, providerLogPath)
 	return envTFLogPath, nil
 }
 
-func getProviderCachePath() (string, error) {
-	curUser, err := user.Current()
-	if err != nil {
-		return "", err
+func (w *WorkSpace) getProviderCachePath() (string, error) {
+	var cachePath string
+	if w.config != nil && w.config.PluginCacheDir != "" {
+		cachePath = w.config.PluginCacheDir
+	} else {
+		curUser, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		cachePath = filepath.Join(curUser.HomeDir, terraformD, pluginCache)
 	}
-
-	cachePath := filepath.Join(curUser.HomeDir, terraformD, pluginCache)
-	err = io.CreateDirIfNotExist(cachePath)
+	err := io.CreateDirIfNotExist(cachePath)
 	if err != nil {
 		return "", err
 	}
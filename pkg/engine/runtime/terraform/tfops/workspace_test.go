@@ -2,6 +2,7 @@ package tfops
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -81,6 +82,38 @@ func TestWriteHCL(t *testing.T) {
 	}
 }
 
+func TestWriteHCL_DataSource(t *testing.T) {
+	dataSource := models.Resource{
+		ID:         "hashicorp:local:local_file:kusion_example",
+		Type:       "Terraform",
+		Attributes: map[string]interface{}{"filename": "test.txt"},
+		Extensions: map[string]interface{}{
+			"provider":     "registry.terraform.io/hashicorp/local/2.2.3",
+			"resourceType": "local_file",
+			ModeExtension:  DataMode,
+		},
+	}
+	w := NewWorkSpace(fs)
+	w.SetResource(&dataSource)
+	w.SetCacheDir(t.TempDir())
+	if err := w.WriteHCL(); err != nil {
+		t.Fatalf("writeHCL error: %v", err)
+	}
+
+	s, _ := fs.ReadFile(filepath.Join(w.tfCacheDir, "main.tf.json"))
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(s, &m); err != nil {
+		t.Fatalf("unmarshal main.tf.json error: %v", err)
+	}
+	if _, ok := m["resource"]; ok {
+		t.Errorf("a data source must not be written as a resource block")
+	}
+	want := map[string]interface{}{"local_file": map[string]interface{}{"kusion_example": map[string]interface{}{"filename": "test.txt"}}}
+	if diff := cmp.Diff(want, m["data"]); diff != "" {
+		t.Errorf("WriteHCL(...): -want data, +got data:\n%s", diff)
+	}
+}
+
 func TestWriteTFState(t *testing.T) {
 	type args struct {
 		w *WorkSpace
@@ -450,3 +483,42 @@ func TestDestroy(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderConfig(t *testing.T) {
+	res := resourceTest.DeepCopy()
+	res.Extensions["providerMeta"] = map[string]interface{}{
+		"region":      "us-east-1",
+		"max_retries": float64(3),
+		"access_key":  "ref+vault://aws/access",
+		"assume_role": []interface{}{map[string]interface{}{"role_arn": "${aws:iam:role.arn}"}},
+	}
+	res.ResolvedExtensions = map[string]interface{}{"providerMeta": map[string]interface{}{
+		"region":      "us-east-1",
+		"max_retries": float64(3),
+		"access_key":  "foo",
+		"assume_role": []interface{}{map[string]interface{}{"role_arn": "arn:aws:iam::1:role/bar"}},
+	}}
+
+	config, vars := providerConfig(res)
+	wantConfig := map[string]interface{}{
+		"region":      "us-east-1",
+		"max_retries": float64(3),
+		"access_key":  "${var.kusion_provider_meta_0}",
+		"assume_role": []interface{}{map[string]interface{}{"role_arn": "${var.kusion_provider_meta_1}"}},
+	}
+	if diff := cmp.Diff(wantConfig, config); diff != "" {
+		t.Errorf("providerConfig(...): -want config, +got config:\n%s", diff)
+	}
+	wantVars := map[string]string{
+		"kusion_provider_meta_0": "foo",
+		"kusion_provider_meta_1": "arn:aws:iam::1:role/bar",
+	}
+	if diff := cmp.Diff(wantVars, vars); diff != "" {
+		t.Errorf("providerConfig(...): -want vars, +got vars:\n%s", diff)
+	}
+
+	config, vars = providerConfig(&resourceTest)
+	if config != nil || len(vars) != 0 {
+		t.Errorf("providerConfig(...) of a resource without providerMeta: got %v and %v", config, vars)
+	}
+}
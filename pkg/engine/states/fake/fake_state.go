@@ -0,0 +1,199 @@
+// Package fake provides an in-memory state storage for engine-level tests. It keeps every applied State as
+// history, and can be programmed to fail or to reject stale writes, so tests can simulate conflicts of concurrent
+// operations. Register it as a backend with backendinit.Register, or pass it as the StateStorage of an operation.
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+// ErrConflict is returned by Apply on an injected conflict or a stale write
+var ErrConflict = errors.New("state conflict: the state has been modified by another operation")
+
+var (
+	_ states.StateStorage = (*StateStorage)(nil)
+	_ states.Backend      = (*Backend)(nil)
+)
+
+// StateStorage is an in-memory states.StateStorage. Its zero value is not usable, and NewStateStorage must be used
+type StateStorage struct {
+	mu          sync.Mutex
+	history     []*states.State
+	nextID      int64
+	checkSerial bool
+	failures    []error
+}
+
+func NewStateStorage() *StateStorage {
+	return &StateStorage{nextID: 1}
+}
+
+// CheckSerial makes Apply reject a State whose Serial is not greater than the latest one with ErrConflict, which
+// happens if two operations start from the same State
+func (s *StateStorage) CheckSerial() *StateStorage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkSerial = true
+	return s
+}
+
+// FailApply makes the next times calls of Apply return err
+func (s *StateStorage) FailApply(err error, times int) *StateStorage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < times; i++ {
+		s.failures = append(s.failures, err)
+	}
+	return s
+}
+
+// InjectConflict makes the next times calls of Apply return ErrConflict
+func (s *StateStorage) InjectConflict(times int) *StateStorage {
+	return s.FailApply(ErrConflict, times)
+}
+
+// BackendFn returns a backend func for backendinit.Register. The backend always returns this StateStorage
+func (s *StateStorage) BackendFn() func() states.Backend {
+	return func() states.Backend {
+		return &Backend{storage: s}
+	}
+}
+
+func (s *StateStorage) GetLatestState(query *states.StateQuery) (*states.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latest := s.latest(query)
+	if latest == nil {
+		return nil, nil
+	}
+	return copyState(latest)
+}
+
+// Apply saves a copy of the State as the latest one. A State without ID gets a new one
+func (s *StateStorage) Apply(state *states.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.failures) > 0 {
+		err := s.failures[0]
+		s.failures = s.failures[1:]
+		return err
+	}
+
+	query := queryOf(state)
+	latest := s.latest(query)
+	if s.checkSerial && latest != nil && state.Serial <= latest.Serial {
+		return fmt.Errorf("%w: serial %d is not greater than the latest %d", ErrConflict, state.Serial, latest.Serial)
+	}
+
+	now := time.Now()
+	if latest == nil || latest.CreateTime.IsZero() {
+		state.CreateTime = now
+	} else {
+		state.CreateTime = latest.CreateTime
+	}
+	state.ModifiedTime = now
+	if state.ID == 0 {
+		state.ID = s.nextID
+		s.nextID++
+	}
+
+	saved, err := copyState(state)
+	if err != nil {
+		return err
+	}
+	s.history = append(s.history, saved)
+	return nil
+}
+
+// Delete removes all States with the ID from the history
+func (s *StateStorage) Delete(id string) error {
+	stateID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid state id %s: %v", id, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.history[:0]
+	for _, state := range s.history {
+		if state.ID != stateID {
+			kept = append(kept, state)
+		}
+	}
+	s.history = kept
+	return nil
+}
+
+// History returns copies of all States matching the query in the order they were applied. All States are returned
+// if query is nil
+func (s *StateStorage) History(query *states.StateQuery) []*states.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*states.State
+	for _, state := range s.history {
+		if query == nil || matches(state, query) {
+			if c, err := copyState(state); err == nil {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+func (s *StateStorage) latest(query *states.StateQuery) *states.State {
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if query == nil || matches(s.history[i], query) {
+			return s.history[i]
+		}
+	}
+	return nil
+}
+
+func queryOf(state *states.State) *states.StateQuery {
+	return &states.StateQuery{Tenant: state.Tenant, Project: state.Project, Stack: state.Stack, Cluster: state.Cluster}
+}
+
+func matches(state *states.State, query *states.StateQuery) bool {
+	return state.Tenant == query.Tenant && state.Project == query.Project &&
+		state.Stack == query.Stack && state.Cluster == query.Cluster
+}
+
+// copyState deep copies the State in the way the local storage saves and loads it, so that numbers keep their
+// types
+func copyState(state *states.State) (*states.State, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	out := &states.State{}
+	if err = yaml.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Backend is a states.Backend returning the StateStorage. It has no configs
+type Backend struct {
+	storage *StateStorage
+}
+
+func (b *Backend) ConfigSchema() cty.Type {
+	return cty.EmptyObject
+}
+
+func (b *Backend) Configure(_ cty.Value) error {
+	return nil
+}
+
+func (b *Backend) StateStorage() states.StateStorage {
+	return b.storage
+}
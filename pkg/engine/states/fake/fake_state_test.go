@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+var query = &states.StateQuery{Project: "foo", Stack: "dev"}
+
+func newState(serial uint64) *states.State {
+	s := states.NewState()
+	s.Project, s.Stack, s.Serial = "foo", "dev", serial
+	s.Resources = models.Resources{{ID: "bar", Attributes: map[string]interface{}{"replicas": 1}}}
+	return s
+}
+
+func TestStateStorage(t *testing.T) {
+	s := NewStateStorage()
+	latest, err := s.GetLatestState(query)
+	assert.Nil(t, err)
+	assert.Nil(t, latest)
+
+	assert.Nil(t, s.Apply(newState(1)))
+	assert.Nil(t, s.Apply(newState(2)))
+	other := newState(1)
+	other.Stack = "prod"
+	assert.Nil(t, s.Apply(other))
+
+	latest, err = s.GetLatestState(query)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	assert.Equal(t, 1, latest.Resources[0].Attributes["replicas"])
+	history := s.History(query)
+	assert.Len(t, history, 2)
+	assert.Equal(t, history[0].CreateTime, history[1].CreateTime)
+	assert.Len(t, s.History(nil), 3)
+
+	assert.Nil(t, s.Delete("2"))
+	latest, _ = s.GetLatestState(query)
+	assert.Equal(t, uint64(1), latest.Serial)
+	assert.NotNil(t, s.Delete("foo"))
+}
+
+func TestStateStorage_Conflicts(t *testing.T) {
+	t.Run("injected", func(t *testing.T) {
+		s := NewStateStorage().InjectConflict(1)
+		assert.ErrorIs(t, s.Apply(newState(1)), ErrConflict)
+		assert.Nil(t, s.Apply(newState(1)))
+
+		boom := errors.New("boom")
+		s.FailApply(boom, 2)
+		assert.ErrorIs(t, s.Apply(newState(2)), boom)
+		assert.ErrorIs(t, s.Apply(newState(2)), boom)
+		assert.Nil(t, s.Apply(newState(2)))
+	})
+
+	t.Run("stale writes", func(t *testing.T) {
+		s := NewStateStorage().CheckSerial()
+		assert.Nil(t, s.Apply(newState(1)))
+		assert.ErrorIs(t, s.Apply(newState(1)), ErrConflict)
+		assert.Nil(t, s.Apply(newState(2)))
+	})
+}
+
+func TestBackend(t *testing.T) {
+	s := NewStateStorage()
+	b := s.BackendFn()()
+	assert.Nil(t, b.Configure(cty.EmptyObjectVal))
+	assert.Same(t, s, b.StateStorage())
+}
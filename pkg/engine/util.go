@@ -1,9 +1,30 @@
 package engine
 
-import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/vals"
+)
 
 const Separator = ":"
 
+// ClusterExtension is the key in resource Extensions which specifies the Kubernetes cluster of this resource,
+// and its value is a context in the kubeconfig of the stack
+const ClusterExtension = "cluster"
+
+// ProviderMetaExtension is the key in resource Extensions which specifies the configuration of the Terraform
+// provider, such as credentials. Secret and implicit references in it are resolved like the ones in attributes
+const ProviderMetaExtension = "providerMeta"
+
+// RefExtensionsExtension is the key in resource Extensions which designates keys of other extensions whose secret
+// and implicit references are resolved, like ["kubeConfig"]
+const RefExtensionsExtension = "refExtensions"
+
 func BuildID(apiVersion, kind, namespace, name string) string {
 	key := apiVersion + Separator + kind + Separator
 	if namespace != "" {
@@ -15,3 +36,138 @@ func BuildID(apiVersion, kind, namespace, name string) string {
 func BuildIDForKubernetes(o *unstructured.Unstructured) string {
 	return BuildID(o.GetAPIVersion(), o.GetKind(), o.GetNamespace(), o.GetName())
 }
+
+// BuildIDWithCluster prefixes the ID with the cluster, like cluster:apiVersion:kind:namespace:name
+func BuildIDWithCluster(cluster, id string) string {
+	if cluster == "" || strings.HasPrefix(id, cluster+Separator) {
+		return id
+	}
+	return cluster + Separator + id
+}
+
+// implicitRefPrefix is the prefix of implicit references like $kusion_path.<id>.<attribute>, the same as the one
+// resolved by resource nodes
+const implicitRefPrefix = "$kusion_path."
+
+// QualifyClusterIDs prefixes IDs of Kubernetes resources with the `cluster` extension with their clusters, so that the
+// same resource deployed to different clusters has different IDs. IDs in DependsOn and implicit references in
+// attributes and extensions are rewritten in the same pass, which refer to the resource in the same cluster first, and
+// then to the only resource with the ID. Clusters must be context names instead of references, because IDs are
+// qualified before references are resolved
+func QualifyClusterIDs(sp *models.Spec) error {
+	if sp == nil {
+		return nil
+	}
+	ids := make(map[string]bool, len(sp.Resources))
+	clusters := make([]string, len(sp.Resources))
+	// qualified are qualified IDs of resources keyed by their IDs written by users
+	qualified := make(map[string][]string)
+	for i := range sp.Resources {
+		res := &sp.Resources[i]
+		if res.Type == runtime.Kubernetes {
+			clusters[i], _ = res.Extensions[ClusterExtension].(string)
+			if _, ok := vals.IsSecured(clusters[i]); ok || strings.HasPrefix(clusters[i], implicitRefPrefix) {
+				return fmt.Errorf("cluster of resource %s must be a context name instead of a reference: %s", res.ID, clusters[i])
+			}
+		}
+		if id := BuildIDWithCluster(clusters[i], res.ID); id != res.ID {
+			qualified[res.ID] = append(qualified[res.ID], id)
+			res.ID = id
+		}
+		ids[res.ID] = true
+	}
+	if len(qualified) == 0 {
+		return nil
+	}
+
+	for i := range sp.Resources {
+		res := &sp.Resources[i]
+		resolve := func(id string) (string, error) {
+			if qid := BuildIDWithCluster(clusters[i], id); ids[qid] {
+				return qid, nil
+			}
+			if ids[id] {
+				return id, nil
+			}
+			switch q := qualified[id]; len(q) {
+			case 0:
+				return id, nil
+			case 1:
+				return q[0], nil
+			default:
+				return "", fmt.Errorf("resource %s refers to %s, which is deployed to multiple clusters. Qualify the "+
+					"reference with the cluster, like %s", res.ID, id, q[0])
+			}
+		}
+		for j, dep := range res.DependsOn {
+			id, err := resolve(dep)
+			if err != nil {
+				return err
+			}
+			res.DependsOn[j] = id
+		}
+		for _, m := range []map[string]interface{}{res.Attributes, res.Extensions} {
+			if _, err := qualifyRefs(m, resolve); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// qualifyRefs rewrites IDs in implicit references in v. Maps and slices are rewritten in place
+func qualifyRefs(v interface{}, resolve func(string) (string, error)) (interface{}, error) {
+	var err error
+	switch value := v.(type) {
+	case string:
+		return qualifyRef(value, resolve)
+	case map[string]interface{}:
+		for k, e := range value {
+			if value[k], err = qualifyRefs(e, resolve); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, e := range value {
+			if value[i], err = qualifyRefs(e, resolve); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// qualifyRef rewrites the ID in the implicit reference like $kusion_path.<id>.<attribute>, and returns other strings
+// as they are
+func qualifyRef(s string, resolve func(string) (string, error)) (string, error) {
+	if !strings.HasPrefix(s, implicitRefPrefix) {
+		return s, nil
+	}
+	ref := strings.TrimPrefix(s, implicitRefPrefix)
+	id, path, _ := strings.Cut(ref, ".")
+	qid, err := resolve(id)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return implicitRefPrefix + qid, nil
+	}
+	return implicitRefPrefix + qid + "." + path, nil
+}
+
+// RefExtensions returns keys of extensions of the resource whose references are resolved, which are the provider
+// configuration and the ones designated by RefExtensionsExtension
+func RefExtensions(res *models.Resource) []string {
+	keys := []string{ProviderMetaExtension}
+	switch designated := res.Extensions[RefExtensionsExtension].(type) {
+	case []string:
+		keys = append(keys, designated...)
+	case []interface{}:
+		for _, k := range designated {
+			if key, ok := k.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
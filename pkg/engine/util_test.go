@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+func TestBuildIDWithCluster(t *testing.T) {
+	assert.Equal(t, "v1:Namespace:foo", BuildIDWithCluster("", "v1:Namespace:foo"))
+	assert.Equal(t, "east:v1:Namespace:foo", BuildIDWithCluster("east", "v1:Namespace:foo"))
+	assert.Equal(t, "east:v1:Namespace:foo", BuildIDWithCluster("east", "east:v1:Namespace:foo"))
+}
+
+func TestQualifyClusterIDs(t *testing.T) {
+	east := map[string]interface{}{ClusterExtension: "east"}
+	sp := &models.Spec{Resources: models.Resources{
+		{ID: "v1:Namespace:foo", Type: runtime.Kubernetes},
+		{ID: "v1:Namespace:foo", Type: runtime.Kubernetes, Extensions: east},
+		{ID: "v1:ConfigMap:foo:bar", Type: runtime.Kubernetes, Extensions: east, DependsOn: []string{"v1:Namespace:foo"}},
+		{ID: "v1:Secret:foo:bar", Type: runtime.Kubernetes, DependsOn: []string{"v1:Namespace:foo"}},
+		{ID: "hashicorp:local:local_file:foo", Type: runtime.Terraform, Extensions: east},
+		{
+			ID:        "hashicorp:local:local_file:bar",
+			Type:      runtime.Terraform,
+			DependsOn: []string{"v1:ConfigMap:foo:bar"},
+			Attributes: map[string]interface{}{
+				"content":  "$kusion_path.v1:ConfigMap:foo:bar.data.key",
+				"list":     []interface{}{"$kusion_path.v1:Namespace:foo.metadata.name", "v1:ConfigMap:foo:bar"},
+				"filename": "foo",
+			},
+		},
+	}}
+	assert.Nil(t, QualifyClusterIDs(sp))
+
+	assert.Equal(t, "v1:Namespace:foo", sp.Resources[0].ID)
+	assert.Equal(t, "east:v1:Namespace:foo", sp.Resources[1].ID)
+	assert.Equal(t, "east:v1:ConfigMap:foo:bar", sp.Resources[2].ID)
+	assert.Equal(t, []string{"east:v1:Namespace:foo"}, sp.Resources[2].DependsOn)
+	assert.Equal(t, []string{"v1:Namespace:foo"}, sp.Resources[3].DependsOn)
+	assert.Equal(t, "hashicorp:local:local_file:foo", sp.Resources[4].ID)
+	// references to the only resource with the ID are qualified, and others are kept
+	assert.Equal(t, []string{"east:v1:ConfigMap:foo:bar"}, sp.Resources[5].DependsOn)
+	assert.Equal(t, map[string]interface{}{
+		"content":  "$kusion_path.east:v1:ConfigMap:foo:bar.data.key",
+		"list":     []interface{}{"$kusion_path.v1:Namespace:foo.metadata.name", "v1:ConfigMap:foo:bar"},
+		"filename": "foo",
+	}, sp.Resources[5].Attributes)
+
+	t.Run("ambiguous reference", func(t *testing.T) {
+		sp := &models.Spec{Resources: models.Resources{
+			{ID: "v1:Namespace:foo", Type: runtime.Kubernetes, Extensions: east},
+			{ID: "v1:Namespace:foo", Type: runtime.Kubernetes, Extensions: map[string]interface{}{ClusterExtension: "west"}},
+			{ID: "bar", Type: runtime.Terraform, Attributes: map[string]interface{}{"name": "$kusion_path.v1:Namespace:foo.metadata.name"}},
+		}}
+		assert.ErrorContains(t, QualifyClusterIDs(sp), "deployed to multiple clusters")
+	})
+
+	t.Run("cluster reference", func(t *testing.T) {
+		sp := &models.Spec{Resources: models.Resources{
+			{ID: "v1:Namespace:foo", Type: runtime.Kubernetes, Extensions: map[string]interface{}{ClusterExtension: "$kusion_path.bar.name"}},
+		}}
+		assert.ErrorContains(t, QualifyClusterIDs(sp), "must be a context name instead of a reference")
+	})
+}
+
+func TestRefExtensions(t *testing.T) {
+	assert.Equal(t, []string{ProviderMetaExtension}, RefExtensions(&models.Resource{}))
+	assert.Equal(t, []string{ProviderMetaExtension, "kubeConfig"}, RefExtensions(&models.Resource{Extensions: map[string]interface{}{
+		RefExtensionsExtension: []interface{}{"kubeConfig"},
+	}}))
+}
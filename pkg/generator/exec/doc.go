@@ -0,0 +1,48 @@
+// Package exec implements the Exec generator, which delegates Spec generation to an external executable, so that
+// any config source can feed Kusion without forking it.
+//
+// The generator is configured in project.yaml:
+//
+//	generator:
+//	  type: Exec
+//	  configs:
+//	    command: ./bin/catalog-generator  # required, a relative path with a separator is relative to the stack directory
+//	    args: ["--env", "prod"]           # optional
+//	    timeout: 2m                       # optional, default is 1m
+//
+// The executable runs in the stack directory. It receives a Request as one JSON document on stdin:
+//
+//	{
+//	  "version": "v1",
+//	  "options": {
+//	    "workDir": "/path/to/stack",
+//	    "filenames": ["main.k"],
+//	    "settings": ["kcl.yaml"],
+//	    "arguments": ["key=value"],
+//	    "overrides": ["app.image=nginx"]
+//	  },
+//	  "stack": {
+//	    "name": "prod",
+//	    "path": "/path/to/stack",
+//	    "kubeconfig": "/path/to/kubeconfig",
+//	    "context": "prod-cluster"
+//	  }
+//	}
+//
+// Empty fields are omitted. The executable writes the Spec as one JSON document on stdout, and exits with 0:
+//
+//	{
+//	  "resources": [
+//	    {
+//	      "id": "v1:Namespace:foo",
+//	      "type": "Kubernetes",
+//	      "attributes": {"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "foo"}},
+//	      "dependsOn": [],
+//	      "extensions": {}
+//	    }
+//	  ]
+//	}
+//
+// Every line written to stderr is a diagnostic. Diagnostics are logged as warnings if the executable succeeds, and
+// are returned in the error if it exits with a non-zero code, writes an invalid Spec, or exceeds the timeout.
+package exec
@@ -0,0 +1,135 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// ProtocolVersion is the version of the request schema
+const ProtocolVersion = "v1"
+
+// DefaultTimeout is the max duration of the executable if the timeout is not configured
+const DefaultTimeout = time.Minute
+
+// Keys in generator configs of the Exec generator
+const (
+	CommandConfig = "command"
+	ArgsConfig    = "args"
+	TimeoutConfig = "timeout"
+)
+
+// Request is the JSON document written to stdin of the executable
+type Request struct {
+	Version string              `json:"version"`
+	Options *generator.Options  `json:"options"`
+	Stack   *projectstack.Stack `json:"stack"`
+}
+
+// Generator runs an external executable to generate the Spec. See the package doc for the protocol
+type Generator struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+var _ generator.Generator = (*Generator)(nil)
+
+// NewGenerator creates an Exec generator with generator configs in project.yaml
+func NewGenerator(configs map[string]interface{}) (*Generator, error) {
+	command, _ := configs[CommandConfig].(string)
+	if command == "" {
+		return nil, fmt.Errorf("%s of the Exec generator is required", CommandConfig)
+	}
+	g := &Generator{command: command, timeout: DefaultTimeout}
+
+	if v, ok := configs[ArgsConfig]; ok {
+		args, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s of the Exec generator: %v", ArgsConfig, v)
+		}
+		for _, arg := range args {
+			g.args = append(g.args, fmt.Sprint(arg))
+		}
+	}
+	if v, ok := configs[TimeoutConfig]; ok {
+		timeout, err := time.ParseDuration(fmt.Sprint(v))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid %s of the Exec generator: %v", TimeoutConfig, v)
+		}
+		g.timeout = timeout
+	}
+	return g, nil
+}
+
+func (g *Generator) GenerateSpec(o *generator.Options, stack *projectstack.Stack) (*models.Spec, error) {
+	dir := o.WorkDir
+	if dir == "" {
+		dir = stack.GetPath()
+	}
+	command := g.command
+	if !filepath.IsAbs(command) && strings.ContainsRune(command, filepath.Separator) {
+		command = filepath.Join(dir, command)
+	}
+
+	request, err := json.Marshal(&Request{Version: ProtocolVersion, Options: o, Stack: stack})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+	cmd := osexec.CommandContext(ctx, command, g.args...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(request)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	log.Debugf("Run generator: %s %s", command, strings.Join(g.args, " "))
+	err = cmd.Run()
+	diagnostics := diagnosticsOf(stderr.String())
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, generatorError(fmt.Sprintf("generator %s timed out after %s", g.command, g.timeout), diagnostics)
+	}
+	if err != nil {
+		return nil, generatorError(fmt.Sprintf("generator %s failed: %v", g.command, err), diagnostics)
+	}
+
+	spec := &models.Spec{}
+	if err = json.Unmarshal(stdout.Bytes(), spec); err != nil {
+		return nil, generatorError(fmt.Sprintf("generator %s returned an invalid spec: %v", g.command, err), diagnostics)
+	}
+	for _, d := range diagnostics {
+		log.Warnf("generator %s: %s", g.command, d)
+	}
+	return spec, nil
+}
+
+// diagnosticsOf splits stderr into lines, and empty lines are dropped
+func diagnosticsOf(stderr string) []string {
+	var diagnostics []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			diagnostics = append(diagnostics, line)
+		}
+	}
+	return diagnostics
+}
+
+func generatorError(msg string, diagnostics []string) error {
+	if len(diagnostics) == 0 {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s\n%s", msg, strings.Join(diagnostics, "\n"))
+}
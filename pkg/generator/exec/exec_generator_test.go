@@ -0,0 +1,90 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// writeScript writes an executable shell script into the directory
+func writeScript(t *testing.T, dir, script string) {
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "generator.sh"), []byte("#!/bin/sh\n"+script), 0o755))
+}
+
+func TestNewGenerator(t *testing.T) {
+	g, err := NewGenerator(map[string]interface{}{
+		CommandConfig: "./generator.sh",
+		ArgsConfig:    []interface{}{"--env", "prod"},
+		TimeoutConfig: "10s",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &Generator{command: "./generator.sh", args: []string{"--env", "prod"}, timeout: 10 * time.Second}, g)
+
+	_, err = NewGenerator(nil)
+	assert.NotNil(t, err)
+
+	_, err = NewGenerator(map[string]interface{}{CommandConfig: "foo", TimeoutConfig: "foo"})
+	assert.NotNil(t, err)
+
+	_, err = NewGenerator(map[string]interface{}{CommandConfig: "foo", ArgsConfig: "foo"})
+	assert.NotNil(t, err)
+}
+
+func TestGenerator_GenerateSpec(t *testing.T) {
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev"}}
+
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		// the script fails unless the request carries the protocol version, arguments and stack
+		writeScript(t, dir, `request=$(cat)
+case "$request" in
+  *'"version":"v1"'*'"arguments":["foo"]'*'"name":"dev"'*) ;;
+  *) echo "unexpected request $request" >&2; exit 1 ;;
+esac
+echo "deprecated config" >&2
+echo '{"resources": [{"id": "v1:Namespace:dev", "type": "Kubernetes", "attributes": {"kind": "Namespace"}}]}'
+`)
+		g := &Generator{command: "./generator.sh", timeout: DefaultTimeout}
+		sp, err := g.GenerateSpec(&generator.Options{WorkDir: dir, Arguments: []string{"foo"}}, stack)
+		assert.Nil(t, err)
+		assert.Equal(t, &models.Spec{Resources: models.Resources{{
+			ID:         "v1:Namespace:dev",
+			Type:       runtime.Kubernetes,
+			Attributes: map[string]interface{}{"kind": "Namespace"},
+		}}}, sp)
+	})
+
+	t.Run("failed with diagnostics", func(t *testing.T) {
+		dir := t.TempDir()
+		writeScript(t, dir, "echo 'catalog is unavailable' >&2\nexit 2\n")
+		g := &Generator{command: "./generator.sh", timeout: DefaultTimeout}
+		_, err := g.GenerateSpec(&generator.Options{WorkDir: dir}, stack)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "catalog is unavailable")
+	})
+
+	t.Run("invalid spec", func(t *testing.T) {
+		dir := t.TempDir()
+		writeScript(t, dir, "echo foo\n")
+		g := &Generator{command: "./generator.sh", timeout: DefaultTimeout}
+		_, err := g.GenerateSpec(&generator.Options{WorkDir: dir}, stack)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		dir := t.TempDir()
+		writeScript(t, dir, "exec sleep 10\n")
+		g := &Generator{command: "./generator.sh", timeout: 100 * time.Millisecond}
+		_, err := g.GenerateSpec(&generator.Options{WorkDir: dir}, stack)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "timed out")
+	})
+}
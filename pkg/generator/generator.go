@@ -17,31 +17,31 @@ const (
 
 type Options struct {
 	// WorkDir represent the filesystem path where the operation is invoked
-	WorkDir string
+	WorkDir string `json:"workDir,omitempty"`
 
 	// Filenames represent all file names included in this operation
-	Filenames []string
+	Filenames []string `json:"filenames,omitempty"`
 
 	// Settings are setting args stored in the setting.yaml
-	Settings []string
+	Settings []string `json:"settings,omitempty"`
 
 	// Arguments are args used for a specified Generator. All Generator related args should be passed through this field
-	Arguments []string
+	Arguments []string `json:"arguments,omitempty"`
 
 	// Overrides contains all override args of this operation
-	Overrides []string
+	Overrides []string `json:"overrides,omitempty"`
 
 	// todo move this field to args
 	// DisableNone is the kclvm option. It is not appropriate to put it here
-	DisableNone bool
+	DisableNone bool `json:"disableNone,omitempty"`
 
 	// todo move this field to args
 	// OverrideAST is the kclvm option. It is not appropriate to put it here
-	OverrideAST bool
+	OverrideAST bool `json:"overrideAST,omitempty"`
 
 	// NoStyle represents whether to turn on the spinner output style
-	NoStyle bool
+	NoStyle bool `json:"noStyle,omitempty"`
 
 	// NoPrompt represents whether to print prompt or not
-	NoPrompt bool
+	NoPrompt bool `json:"noPrompt,omitempty"`
 }
@@ -0,0 +1,78 @@
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// Generator builds the kustomization in the stack directory, and each rendered object becomes a Kubernetes resource
+type Generator struct{}
+
+var _ generator.Generator = (*Generator)(nil)
+
+func (g *Generator) GenerateSpec(o *generator.Options, stack *projectstack.Stack) (*models.Spec, error) {
+	dir := o.WorkDir
+	if dir == "" {
+		dir = stack.GetPath()
+	}
+
+	manifests, err := build(dir)
+	if err != nil {
+		return nil, err
+	}
+	return manifests2Spec(manifests)
+}
+
+// build builds the kustomization in the directory with the kustomize library, the same as kustomize build
+func build(dir string) ([]byte, error) {
+	log.Debugf("Build kustomization in %s", dir)
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("build kustomization in %s failed: %v", dir, err)
+	}
+	return resMap.AsYaml()
+}
+
+// manifests2Spec converts rendered objects to Kubernetes resources. Objects with the same ID are not allowed
+func manifests2Spec(manifests []byte) (*models.Spec, error) {
+	var resources models.Resources
+	ids := map[string]bool{}
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifests), 4096)
+	for {
+		data := make(map[string]interface{})
+		if err := decoder.Decode(&data); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("error parsing kustomize output: %v", err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		id := engine.BuildIDForKubernetes(&unstructured.Unstructured{Object: data})
+		if ids[id] {
+			return nil, fmt.Errorf("duplicate resource %s in kustomize output", id)
+		}
+		ids[id] = true
+		resources = append(resources, models.Resource{
+			ID:         id,
+			Type:       runtime.Kubernetes,
+			Attributes: data,
+		})
+	}
+	return &models.Spec{Resources: resources}, nil
+}
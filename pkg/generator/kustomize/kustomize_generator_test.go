@@ -0,0 +1,75 @@
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+const output = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: bar
+  namespace: foo
+data:
+  key: value
+`
+
+func TestGenerator_GenerateSpec(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kustomization.yaml": "namespace: foo\nresources:\n- namespace.yaml\n- configmap.yaml\n",
+		"namespace.yaml":     "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: foo\n",
+		"configmap.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\ndata:\n  key: value\n",
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	sp, err := (&Generator{}).GenerateSpec(&generator.Options{WorkDir: dir}, &projectstack.Stack{})
+	assert.Nil(t, err)
+	assert.Equal(t, &models.Spec{Resources: models.Resources{
+		{
+			ID:   "v1:Namespace:foo",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]interface{}{"name": "foo"},
+			},
+		},
+		{
+			ID:   "v1:ConfigMap:foo:bar",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "bar", "namespace": "foo"},
+				"data":       map[string]interface{}{"key": "value"},
+			},
+		},
+	}}, sp)
+}
+
+func TestGenerator_GenerateSpecFailed(t *testing.T) {
+	// no kustomization in the directory
+	_, err := (&Generator{}).GenerateSpec(&generator.Options{WorkDir: t.TempDir()}, &projectstack.Stack{})
+	assert.NotNil(t, err)
+}
+
+func TestManifests2Spec_Duplicate(t *testing.T) {
+	_, err := manifests2Spec([]byte(output + "---\n" + output))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,106 @@
+package yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/resources"
+)
+
+// PathsConfig is the key in generator configs of the glob patterns of files to read, relative to the stack directory
+const PathsConfig = "paths"
+
+// Generator reads Kubernetes manifests and raw resource documents from YAML and JSON files. Documents with
+// apiVersion and kind are Kubernetes manifests, and documents with id and type are resources of any runtime,
+// such as Terraform
+type Generator struct {
+	paths []string
+}
+
+var _ generator.Generator = (*Generator)(nil)
+
+// NewGenerator creates a YAML generator with generator configs in project.yaml
+func NewGenerator(configs map[string]interface{}) (*Generator, error) {
+	paths, ok := configs[PathsConfig].([]interface{})
+	if !ok || len(paths) == 0 {
+		return nil, fmt.Errorf("%s of the YAML generator is required", PathsConfig)
+	}
+	g := &Generator{}
+	for _, p := range paths {
+		path, ok := p.(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid %s of the YAML generator: %v", PathsConfig, p)
+		}
+		g.paths = append(g.paths, path)
+	}
+	return g, nil
+}
+
+func (g *Generator) GenerateSpec(o *generator.Options, stack *projectstack.Stack) (*models.Spec, error) {
+	dir := o.WorkDir
+	if dir == "" {
+		dir = stack.GetPath()
+	}
+	patterns := make([]string, 0, len(g.paths))
+	for _, p := range g.paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		patterns = append(patterns, p)
+	}
+
+	docs, err := resources.NewFileVisitor(resources.FileExtensions, patterns...).Visit()
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make(models.Resources, 0, len(docs))
+	ids := map[string]bool{}
+	for _, doc := range docs {
+		res, err := doc2Resource(doc.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		if ids[res.ID] {
+			return nil, fmt.Errorf("duplicate resource %s", res.ID)
+		}
+		ids[res.ID] = true
+		rs = append(rs, *res)
+	}
+	return &models.Spec{Resources: rs}, nil
+}
+
+// doc2Resource converts a Kubernetes manifest or a raw resource document to a resource
+func doc2Resource(doc map[string]interface{}) (*models.Resource, error) {
+	if _, ok := doc["apiVersion"]; ok {
+		if _, ok = doc["kind"]; ok {
+			return &models.Resource{
+				ID:         engine.BuildIDForKubernetes(&unstructured.Unstructured{Object: doc}),
+				Type:       runtime.Kubernetes,
+				Attributes: doc,
+			}, nil
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.Resource{}
+	if err = json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	if res.ID == "" || res.Type == "" {
+		return nil, errors.New("document is neither a Kubernetes manifest nor a resource with id and type")
+	}
+	return res, nil
+}
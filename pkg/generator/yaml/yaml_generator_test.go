@@ -0,0 +1,82 @@
+package yaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func TestNewGenerator(t *testing.T) {
+	g, err := NewGenerator(map[string]interface{}{PathsConfig: []interface{}{"manifests", "terraform/*.json"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"manifests", "terraform/*.json"}, g.paths)
+
+	_, err = NewGenerator(nil)
+	assert.NotNil(t, err)
+
+	_, err = NewGenerator(map[string]interface{}{PathsConfig: []interface{}{1}})
+	assert.NotNil(t, err)
+}
+
+func TestGenerator_GenerateSpec(t *testing.T) {
+	stack := &projectstack.Stack{}
+
+	t.Run("manifests and resources", func(t *testing.T) {
+		g := &Generator{paths: []string{"manifests", "terraform/*.json"}}
+		sp, err := g.GenerateSpec(&generator.Options{WorkDir: "testdata"}, stack)
+		assert.Nil(t, err)
+		assert.Equal(t, models.Resources{
+			{
+				ID:   "v1:Namespace:foo",
+				Type: runtime.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Namespace",
+					"metadata":   map[string]interface{}{"name": "foo"},
+				},
+			},
+			{
+				ID:   "v1:ConfigMap:foo:bar",
+				Type: runtime.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata":   map[string]interface{}{"name": "bar", "namespace": "foo"},
+					"data":       map[string]interface{}{"key": "value"},
+				},
+			},
+			{
+				ID:         "hashicorp:random:random_password:example",
+				Type:       runtime.Terraform,
+				Attributes: map[string]interface{}{"length": float64(10)},
+				Extensions: map[string]interface{}{
+					"provider":     "registry.terraform.io/hashicorp/random/3.1.0",
+					"resourceType": "random_password",
+				},
+			},
+		}, sp.Resources)
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		g := &Generator{paths: []string{"invalid.yaml"}}
+		_, err := g.GenerateSpec(&generator.Options{WorkDir: "testdata"}, stack)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("duplicate resources", func(t *testing.T) {
+		g := &Generator{paths: []string{"manifests", "manifests/namespace.yaml"}}
+		_, err := g.GenerateSpec(&generator.Options{WorkDir: "testdata"}, stack)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("no files matched", func(t *testing.T) {
+		g := &Generator{paths: []string{"foo/*.yaml"}}
+		_, err := g.GenerateSpec(&generator.Options{WorkDir: "testdata"}, stack)
+		assert.NotNil(t, err)
+	})
+}
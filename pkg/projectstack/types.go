@@ -2,11 +2,13 @@ package projectstack
 
 import (
 	"errors"
+	"path/filepath"
 	"strings"
 
 	"github.com/pterm/pterm"
 
 	"kusionstack.io/kusion/pkg/engine/backend"
+	"kusionstack.io/kusion/pkg/engine/notification"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/vals"
 )
@@ -19,17 +21,95 @@ var (
 )
 
 const (
-	StackFile                      = "stack.yaml"
-	ProjectFile                    = "project.yaml"
-	CiTestDir                      = "ci-test"
-	SettingsFile                   = "settings.yaml"
-	StdoutGoldenFile               = "stdout.golden.yaml"
-	KclFile                        = "kcl.yaml"
-	KCLGenerator     GeneratorType = "KCL"
+	StackFile                        = "stack.yaml"
+	ProjectFile                      = "project.yaml"
+	CiTestDir                        = "ci-test"
+	SettingsFile                     = "settings.yaml"
+	StdoutGoldenFile                 = "stdout.golden.yaml"
+	KclFile                          = "kcl.yaml"
+	KCLGenerator       GeneratorType = "KCL"
+	KustomizeGenerator GeneratorType = "Kustomize"
+	YAMLGenerator      GeneratorType = "YAML"
+	ExecGenerator      GeneratorType = "Exec"
 )
 
 type GeneratorType string
 
+// ApplyMode is how the Kubernetes runtime applies resources
+type ApplyMode string
+
+const (
+	// ClientSideApply patches resources with a three-way JSON merge patch computed by Kusion. It is the default mode
+	ClientSideApply ApplyMode = "ClientSide"
+
+	// ServerSideApply sends the whole resource to the API server, which merges fields and tracks their managers
+	ServerSideApply ApplyMode = "ServerSide"
+)
+
+// KubernetesConfig represents configs of the Kubernetes runtime saved in project.yaml
+type KubernetesConfig struct {
+	// ApplyMode is ClientSide or ServerSide. Default is ClientSide
+	ApplyMode ApplyMode `json:"applyMode,omitempty" yaml:"applyMode,omitempty"`
+
+	// FieldManager is the name of the field manager in server-side apply. Default is kusion
+	FieldManager string `json:"fieldManager,omitempty" yaml:"fieldManager,omitempty"`
+
+	// ForceConflicts makes server-side apply take over fields managed by other managers
+	ForceConflicts bool `json:"forceConflicts,omitempty" yaml:"forceConflicts,omitempty"`
+
+	// KindOrder is groups of kinds applied in order if they have no explicit dependencies, and kinds not in any
+	// group are applied at last. Deletes are in the reverse order. A default order is used if it is empty
+	KindOrder [][]string `json:"kindOrder,omitempty" yaml:"kindOrder,omitempty"`
+
+	// DisableKindOrder applies resources without explicit dependencies in parallel regardless of their kinds
+	DisableKindOrder bool `json:"disableKindOrder,omitempty" yaml:"disableKindOrder,omitempty"`
+
+	// PropagationPolicy is Foreground, Background or Orphan, which decides how dependents of deleted objects are
+	// deleted. The default policy of each kind is used if it is empty
+	PropagationPolicy string `json:"propagationPolicy,omitempty" yaml:"propagationPolicy,omitempty"`
+
+	// WaitForDeletion makes deletes wait until objects are gone from the cluster
+	WaitForDeletion bool `json:"waitForDeletion,omitempty" yaml:"waitForDeletion,omitempty"`
+
+	// DeletionTimeout is the max duration to wait for deletion, such as 2m. Default is 5m
+	DeletionTimeout string `json:"deletionTimeout,omitempty" yaml:"deletionTimeout,omitempty"`
+}
+
+// TerraformConfig represents configs of the Terraform runtime saved in project.yaml
+type TerraformConfig struct {
+	// Parallelism is the max number of Terraform commands running at the same time. Default is 10
+	Parallelism int `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+
+	// Binary is the name or path of the Terraform CLI, such as tofu for OpenTofu. Default is terraform on PATH. A
+	// relative path is relative to the project directory
+	Binary string `json:"binary,omitempty" yaml:"binary,omitempty"`
+
+	// RequiredVersion is the version constraint of the binary, such as ">= 1.3.0, < 2.0.0", which is checked before
+	// running any command
+	RequiredVersion string `json:"requiredVersion,omitempty" yaml:"requiredVersion,omitempty"`
+
+	// PluginCacheDir is the provider plugin cache shared by all resources. Default is ~/.terraform.d/plugin-cache
+	PluginCacheDir string `json:"pluginCacheDir,omitempty" yaml:"pluginCacheDir,omitempty"`
+
+	// ProviderMirror is a directory in the layout of a Terraform filesystem mirror, from which providers are installed
+	// instead of registries
+	ProviderMirror string `json:"providerMirror,omitempty" yaml:"providerMirror,omitempty"`
+
+	// PluginDirs are directories of providers passed to terraform init with -plugin-dir. Setting them enables the
+	// offline mode, in which providers are never downloaded
+	PluginDirs []string `json:"pluginDirs,omitempty" yaml:"pluginDirs,omitempty"`
+}
+
+// RuntimePluginConfig represents a runtime plugin saved in project.yaml, which manages resources of a type not built
+// in Kusion
+type RuntimePluginConfig struct {
+	// Type is the resource type managed by the plugin
+	Type string `json:"type" yaml:"type"`
+
+	// Path is the plugin executable. A relative path is relative to the project directory
+	Path string `json:"path" yaml:"path"`
+}
+
 // GeneratorConfig represent Generator configs saved in project.yaml
 type GeneratorConfig struct {
 	Type    GeneratorType          `json:"type"`
@@ -52,6 +132,18 @@ type ProjectConfiguration struct {
 
 	// Secret stores
 	SecretStores *vals.SecretStores `json:"secret_stores,omitempty" yaml:"secret_stores,omitempty"`
+
+	// Notification configs of operation events
+	Notification *notification.Config `json:"notification,omitempty" yaml:"notification,omitempty"`
+
+	// Kubernetes runtime configs
+	Kubernetes *KubernetesConfig `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+
+	// Terraform runtime configs
+	Terraform *TerraformConfig `json:"terraform,omitempty" yaml:"terraform,omitempty"`
+
+	// Runtime plugins of resource types not built in. Plugins on PATH are used for types not configured
+	RuntimePlugins []*RuntimePluginConfig `json:"runtimePlugins,omitempty" yaml:"runtimePlugins,omitempty"`
 }
 
 type Project struct {
@@ -117,6 +209,14 @@ func (p *Project) TableReport() string {
 // StackConfiguration is the stack configuration
 type StackConfiguration struct {
 	Name string `json:"name" yaml:"name"` // Stack name
+
+	// KubeConfig is the kubeconfig file of Kubernetes resources in this stack. A relative path is relative to the
+	// stack directory. $KUBECONFIG or ~/.kube/config is used if it is empty
+	KubeConfig string `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+
+	// Context is the default kubeconfig context of Kubernetes resources in this stack. Resources can override it
+	// with the `cluster` extension. The current context is used if it is empty
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
 }
 
 type Stack struct {
@@ -142,6 +242,15 @@ func (s *Stack) GetPath() string {
 	return s.Path
 }
 
+// GetKubeConfig returns the absolute path of the kubeconfig file configured in stack.yaml,
+// or an empty string if it is not configured
+func (s *Stack) GetKubeConfig() string {
+	if s.KubeConfig == "" || filepath.IsAbs(s.KubeConfig) {
+		return s.KubeConfig
+	}
+	return filepath.Join(s.Path, s.KubeConfig)
+}
+
 // TableReport returns the report string of table format
 func (s *Stack) TableReport() string {
 	// Fill table header
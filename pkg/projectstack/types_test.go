@@ -4,6 +4,7 @@
 package projectstack
 
 import (
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -267,6 +268,41 @@ func TestStack_GetPath(t *testing.T) {
 	}
 }
 
+func TestStack_GetKubeConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		kubeConfig string
+		want       string
+	}{
+		{
+			name:       "empty",
+			kubeConfig: "",
+			want:       "",
+		},
+		{
+			name:       "absolute",
+			kubeConfig: "/etc/kube/config",
+			want:       "/etc/kube/config",
+		},
+		{
+			name:       "relative",
+			kubeConfig: "kubeconfig",
+			want:       filepath.Join(TestStackPathAA, "kubeconfig"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Stack{
+				StackConfiguration: StackConfiguration{Name: TestStackA, KubeConfig: tt.kubeConfig},
+				Path:               TestStackPathAA,
+			}
+			if got := s.GetKubeConfig(); got != tt.want {
+				t.Errorf("Stack.GetKubeConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestStack_TableReport(t *testing.T) {
 	type fields struct {
 		StackConfiguration StackConfiguration
@@ -1,6 +1,8 @@
 package crd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,26 +26,16 @@ func TestCrdVisitor(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, 3, len(objs))
 	})
-}
-
-func Test_ignoreFile(t *testing.T) {
-	t.Run("not ignore .YAML file", func(t *testing.T) {
-		flag := ignoreFile("foo.YAML", FileExtensions)
-		assert.False(t, flag)
-	})
-
-	t.Run("not ignore .yaml file", func(t *testing.T) {
-		flag := ignoreFile("foo.yaml", FileExtensions)
-		assert.False(t, flag)
-	})
 
-	t.Run("not ignore .yml file", func(t *testing.T) {
-		flag := ignoreFile("foo.yml", FileExtensions)
-		assert.False(t, flag)
-	})
+	t.Run("read path with glob characters", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "stack[dev]")
+		assert.Nil(t, os.MkdirAll(dir, 0o755))
+		data, err := os.ReadFile("./testdata/one.yaml")
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, "one.yaml"), data, 0o644))
 
-	t.Run("ignore .go file", func(t *testing.T) {
-		flag := ignoreFile("bar.go", FileExtensions)
-		assert.True(t, flag)
+		objs, err := (&crdVisitor{Path: dir}).Visit()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(objs))
 	})
 }
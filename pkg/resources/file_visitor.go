@@ -0,0 +1,128 @@
+package resources
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// FileExtensions are extensions of YAML and JSON files
+var FileExtensions = []string{".yaml", ".yml", ".json"}
+
+type fileVisitor struct {
+	Patterns   []string
+	Extensions []string
+
+	// literal means patterns are paths which are walked as they are instead of globs
+	literal bool
+}
+
+// NewFileVisitor returns a Visitor reading all documents in files matching the glob patterns. Directories are walked
+// recursively, and only files with the extensions are read in them
+func NewFileVisitor(extensions []string, patterns ...string) Visitor {
+	return &fileVisitor{Patterns: patterns, Extensions: extensions}
+}
+
+// NewPathVisitor returns a Visitor reading all documents in files of the paths like NewFileVisitor, but the paths
+// are not matched as globs, so that paths containing characters like '[', '*' and '?' are read as they are
+func NewPathVisitor(extensions []string, paths ...string) Visitor {
+	return &fileVisitor{Patterns: paths, Extensions: extensions, literal: true}
+}
+
+// Visit reads documents of files in the order of patterns, and each pattern must match at least one file
+func (v *fileVisitor) Visit() (objs []interface{}, err error) {
+	for _, pattern := range v.Patterns {
+		matches := []string{pattern}
+		if !v.literal {
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", pattern)
+			}
+		}
+		for _, match := range matches {
+			docs, err := v.walk(match)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, docs...)
+		}
+	}
+	return objs, nil
+}
+
+// walk reads documents of the file, or files in the directory recursively
+func (v *fileVisitor) walk(root string) (objs []interface{}, err error) {
+	err = filepath.WalkDir(root, func(filePath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// the root file is always read, and files in directories are filtered by extensions
+		if d.IsDir() || (filePath != root && IgnoreFile(filePath, v.Extensions)) {
+			return nil
+		}
+
+		docs, err := readDocuments(filePath)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			objs = append(objs, doc)
+		}
+		return nil
+	})
+	return objs, err
+}
+
+func readDocuments(filePath string) ([]map[string]interface{}, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	docs, err := DecodeDocuments(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", filePath, err)
+	}
+	return docs, nil
+}
+
+// DecodeDocuments decodes a stream of YAML or JSON documents, and empty documents are skipped
+func DecodeDocuments(r io.Reader) ([]map[string]interface{}, error) {
+	var docs []map[string]interface{}
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		data := make(map[string]interface{})
+		if err := decoder.Decode(&data); err != nil {
+			if err == io.EOF {
+				return docs, nil
+			}
+			return nil, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		docs = append(docs, data)
+	}
+}
+
+// IgnoreFile indicates a filename is ended with specified extension or not
+func IgnoreFile(path string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return false
+	}
+	ext := filepath.Ext(path)
+	for _, s := range extensions {
+		if strings.EqualFold(s, ext) {
+			return false
+		}
+	}
+	return true
+}
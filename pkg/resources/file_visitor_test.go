@@ -0,0 +1,53 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileVisitor(t *testing.T) {
+	t.Run("read files matching patterns", func(t *testing.T) {
+		visitor := NewFileVisitor(FileExtensions, "./crd/testdata/one.*", "./crd/testdata/multi.yaml")
+		objs, err := visitor.Visit()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(objs))
+	})
+
+	t.Run("no files matched", func(t *testing.T) {
+		_, err := NewFileVisitor(FileExtensions, "./crd/testdata/*.json").Visit()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDecodeDocuments(t *testing.T) {
+	docs, err := DecodeDocuments(strings.NewReader("foo: bar\n---\n---\n{\"foo\": \"baz\"}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{{"foo": "bar"}, {"foo": "baz"}}, docs)
+
+	_, err = DecodeDocuments(strings.NewReader("foo: [bar"))
+	assert.NotNil(t, err)
+}
+
+func TestIgnoreFile(t *testing.T) {
+	t.Run("not ignore .YAML file", func(t *testing.T) {
+		flag := IgnoreFile("foo.YAML", FileExtensions)
+		assert.False(t, flag)
+	})
+
+	t.Run("not ignore .yaml file", func(t *testing.T) {
+		flag := IgnoreFile("foo.yaml", FileExtensions)
+		assert.False(t, flag)
+	})
+
+	t.Run("not ignore .yml file", func(t *testing.T) {
+		flag := IgnoreFile("foo.yml", FileExtensions)
+		assert.False(t, flag)
+	})
+
+	t.Run("ignore .go file", func(t *testing.T) {
+		flag := IgnoreFile("bar.go", FileExtensions)
+		assert.True(t, flag)
+	})
+}
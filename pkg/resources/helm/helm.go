@@ -0,0 +1,383 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+)
+
+// Type is the type of Helm chart resources in the spec, which are rendered into Kubernetes resources during the
+// spec generation and never reach any runtime
+const Type models.Type = "Helm"
+
+// Keys in Extensions of rendered resources, which record the chart they come from. Values of the chart are not
+// recorded, since they often contain secrets and extensions are saved in the state
+const (
+	ChartExtension   = "helmChart"
+	VersionExtension = "helmChartVersion"
+	ReleaseExtension = "helmRelease"
+)
+
+var crdGroupKind = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+// clusterScopedGroupKinds are built-in kinds whose objects have no namespace. Other kinds known by the client-go
+// scheme are namespaced, so that scopes of built-in kinds are resolved without clusters
+var clusterScopedGroupKinds = map[schema.GroupKind]bool{
+	{Kind: "Namespace"}:        true,
+	{Kind: "Node"}:             true,
+	{Kind: "PersistentVolume"}: true,
+	{Kind: "ComponentStatus"}:  true,
+	crdGroupKind:               true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                             true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                         true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                  true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                   true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                        true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                               true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                               true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                      true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                                true,
+	{Group: "networking.k8s.io", Kind: "ClusterCIDR"}:                                 true,
+	{Group: "networking.k8s.io", Kind: "IPAddress"}:                                   true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:     true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}:        true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}: true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:                 true,
+	{Group: "certificates.k8s.io", Kind: "ClusterTrustBundle"}:                        true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                       true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:       true,
+	{Group: "internal.apiserver.k8s.io", Kind: "StorageVersion"}:                      true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                      true,
+	{Group: "resource.k8s.io", Kind: "ResourceClass"}:                                 true,
+	{Group: "authentication.k8s.io", Kind: "TokenReview"}:                             true,
+	{Group: "authentication.k8s.io", Kind: "SelfSubjectReview"}:                       true,
+	{Group: "authorization.k8s.io", Kind: "SubjectAccessReview"}:                      true,
+	{Group: "authorization.k8s.io", Kind: "SelfSubjectAccessReview"}:                  true,
+	{Group: "authorization.k8s.io", Kind: "SelfSubjectRulesReview"}:                   true,
+}
+
+// BinaryEnv is the environment variable to specify the helm binary, and helm in PATH is used if it is not set
+const BinaryEnv = "KUSION_HELM_BINARY"
+
+// RepositoryCacheEnv is the environment variable of the helm repository cache, the same as helm
+const RepositoryCacheEnv = "HELM_REPOSITORY_CACHE"
+
+// NamespacedFunc tells whether objects of the kind are namespaced in the cluster, which is the context named by the
+// `cluster` extension of the chart resource, or the cluster of the stack if the name is empty
+type NamespacedFunc func(cluster string, gvk schema.GroupVersionKind) (bool, error)
+
+// Chart is attributes of a Helm chart resource
+type Chart struct {
+	// Chart is a local chart directory or archive, or the name of a chart in the repository cache
+	Chart string `json:"chart"`
+
+	// Version is the version of the chart in the repository cache
+	Version string `json:"version,omitempty"`
+
+	// RepositoryCache is the directory of chart archives named like name-version.tgz. The helm repository cache
+	// is used if it is empty
+	RepositoryCache string `json:"repositoryCache,omitempty"`
+
+	// ReleaseName is the name of the release. Default is the name of the chart
+	ReleaseName string `json:"releaseName,omitempty"`
+
+	// Namespace is the namespace of the release, which is filled into namespaced objects without one. Scopes of
+	// built-in kinds and kinds defined by CRDs in the chart are resolved offline, and others are resolved in the cluster
+	Namespace string `json:"namespace,omitempty"`
+
+	// Values overrides values in the chart and value files
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// ValueFiles are value files applied in order
+	ValueFiles []string `json:"valueFiles,omitempty"`
+}
+
+// Render replaces Helm chart resources in the spec with Kubernetes resources rendered from their charts.
+// Relative paths are resolved against dir, and namespaced resolves scopes of kinds when the namespace of a chart is
+// filled. Rendered resources inherit DependsOn and Extensions of the chart resource, and resources depending on the
+// chart resource depend on all rendered resources instead
+func Render(sp *models.Spec, dir string, namespaced NamespacedFunc) error {
+	if sp == nil {
+		return nil
+	}
+
+	var result models.Resources
+	rendered := map[string][]string{}
+	for _, res := range sp.Resources {
+		if res.Type != Type {
+			result = append(result, res)
+			continue
+		}
+		resources, err := renderResource(&res, dir, namespaced)
+		if err != nil {
+			return fmt.Errorf("render Helm chart resource %s failed: %v", res.ID, err)
+		}
+		ids := make([]string, 0, len(resources))
+		for _, r := range resources {
+			ids = append(ids, r.ID)
+		}
+		rendered[res.ID] = ids
+		result = append(result, resources...)
+	}
+	if len(rendered) == 0 {
+		return nil
+	}
+
+	for i := range result {
+		var dependsOn []string
+		for _, dep := range result[i].DependsOn {
+			if ids, ok := rendered[dep]; ok {
+				dependsOn = append(dependsOn, ids...)
+			} else {
+				dependsOn = append(dependsOn, dep)
+			}
+		}
+		result[i].DependsOn = dependsOn
+	}
+	sp.Resources = result
+	return nil
+}
+
+func renderResource(res *models.Resource, dir string, namespaced NamespacedFunc) (models.Resources, error) {
+	chart, err := parseChart(res.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	chartPath, err := chart.locate(dir)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("render Helm chart %s of resource %s", chartPath, res.ID)
+
+	manifests, err := chart.template(chartPath, dir)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	crds := crdScopes(objs)
+	cluster, _ := res.Extensions[engine.ClusterExtension].(string)
+	resources := make(models.Resources, 0, len(objs))
+	for _, obj := range objs {
+		if chart.Namespace != "" && obj.GetNamespace() == "" {
+			ns, err := isNamespaced(obj, crds, cluster, namespaced)
+			if err != nil {
+				return nil, err
+			}
+			if ns {
+				obj.SetNamespace(chart.Namespace)
+			}
+		}
+		extensions := map[string]interface{}{
+			ChartExtension:   chart.Chart,
+			ReleaseExtension: chart.releaseName(),
+		}
+		if chart.Version != "" {
+			extensions[VersionExtension] = chart.Version
+		}
+		for k, v := range res.Extensions {
+			extensions[k] = v
+		}
+		resources = append(resources, models.Resource{
+			ID:         engine.BuildIDForKubernetes(obj),
+			Type:       runtime.Kubernetes,
+			Attributes: obj.Object,
+			DependsOn:  res.DependsOn,
+			Extensions: extensions,
+		})
+	}
+	return resources, nil
+}
+
+// crdScopes returns whether kinds defined by CRDs in objs are namespaced. They are not known by the cluster before
+// the CRDs are applied
+func crdScopes(objs []*unstructured.Unstructured) map[schema.GroupKind]bool {
+	scopes := map[schema.GroupKind]bool{}
+	for _, obj := range objs {
+		if obj.GroupVersionKind().GroupKind() != crdGroupKind {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		scope, _, _ := unstructured.NestedString(obj.Object, "spec", "scope")
+		scopes[schema.GroupKind{Group: group, Kind: kind}] = scope == "Namespaced"
+	}
+	return scopes
+}
+
+// isNamespaced returns whether the object is namespaced by CRDs in the chart or built-in kinds, or by the cluster
+// otherwise
+func isNamespaced(obj *unstructured.Unstructured, crds map[schema.GroupKind]bool, cluster string, namespaced NamespacedFunc) (bool, error) {
+	gvk := obj.GroupVersionKind()
+	if ns, ok := crds[gvk.GroupKind()]; ok {
+		return ns, nil
+	}
+	if clusterScopedGroupKinds[gvk.GroupKind()] {
+		return false, nil
+	}
+	if scheme.Scheme.Recognizes(gvk) {
+		return true, nil
+	}
+	if namespaced == nil {
+		return false, fmt.Errorf("scope of kind %s is unknown", gvk.GroupKind())
+	}
+	ns, err := namespaced(cluster, gvk)
+	if err != nil {
+		return false, fmt.Errorf("resolve scope of kind %s failed: %v", gvk.GroupKind(), err)
+	}
+	return ns, nil
+}
+
+func parseChart(attributes map[string]interface{}) (*Chart, error) {
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	chart := &Chart{}
+	if err = json.Unmarshal(data, chart); err != nil {
+		return nil, err
+	}
+	if chart.Chart == "" {
+		return nil, errors.New("chart is required")
+	}
+	return chart, nil
+}
+
+func (c *Chart) releaseName() string {
+	if c.ReleaseName != "" {
+		return c.ReleaseName
+	}
+	return strings.TrimSuffix(filepath.Base(c.Chart), filepath.Ext(c.Chart))
+}
+
+// locate returns the path of the chart on disk. Local charts are used as is, and chart names are resolved to
+// archives in the repository cache, so that rendering never downloads charts
+func (c *Chart) locate(dir string) (string, error) {
+	if local := resolvePath(dir, c.Chart); exists(local) {
+		return local, nil
+	}
+	if c.Version == "" {
+		return "", fmt.Errorf("chart %s not found on disk, and the version to find it in the repository cache is empty", c.Chart)
+	}
+
+	cache := c.RepositoryCache
+	if cache == "" {
+		cache = defaultRepositoryCache()
+	}
+	// Repository prefixes like bitnami/nginx are not part of archive names
+	name := c.Chart[strings.LastIndex(c.Chart, "/")+1:]
+	archive := filepath.Join(resolvePath(dir, cache), fmt.Sprintf("%s-%s.tgz", name, c.Version))
+	if !exists(archive) {
+		return "", fmt.Errorf("chart %s-%s not found in the repository cache %s", name, c.Version, cache)
+	}
+	return archive, nil
+}
+
+// template renders the chart with helm template, which works offline with local charts. Hooks and tests are not
+// rendered, since they would be applied as ordinary resources on every apply
+func (c *Chart) template(chartPath, dir string) ([]byte, error) {
+	args := []string{"template", c.releaseName(), chartPath, "--include-crds", "--no-hooks", "--skip-tests"}
+	if c.Namespace != "" {
+		args = append(args, "--namespace", c.Namespace)
+	}
+	for _, f := range c.ValueFiles {
+		args = append(args, "--values", resolvePath(dir, f))
+	}
+	if len(c.Values) > 0 {
+		values, err := os.CreateTemp("", "kusion-helm-values-*.yaml")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(values.Name())
+		data, err := yamlv2.Marshal(c.Values)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = values.Write(data); err != nil {
+			return nil, err
+		}
+		if err = values.Close(); err != nil {
+			return nil, err
+		}
+		args = append(args, "--values", values.Name())
+	}
+	return runHelm(dir, args...)
+}
+
+func runHelm(dir string, args ...string) ([]byte, error) {
+	binary := os.Getenv(BinaryEnv)
+	if binary == "" {
+		binary = "helm"
+	}
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %v, %s", binary, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func decodeManifests(manifests []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifests), 4096)
+	for {
+		data := make(map[string]interface{})
+		if err := decoder.Decode(&data); err != nil {
+			if err == io.EOF {
+				return objs, nil
+			}
+			return nil, fmt.Errorf("error parsing rendered manifests: %v", err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		objs = append(objs, &unstructured.Unstructured{Object: data})
+	}
+}
+
+func defaultRepositoryCache() string {
+	if cache := os.Getenv(RepositoryCacheEnv); cache != "" {
+		return cache
+	}
+	if cache := os.Getenv("XDG_CACHE_HOME"); cache != "" {
+		return filepath.Join(cache, "helm", "repository")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "helm", "repository")
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) || dir == "" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,164 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+const manifests = `---
+# Source: foo/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  key: value
+---
+# Source: foo/templates/clusterrole.yaml
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: foo
+---
+# Source: foo/crds/widget.yaml
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  scope: Namespaced
+---
+# Source: foo/templates/widget.yaml
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: foo
+---
+# Source: foo/templates/gadget.yaml
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: foo
+`
+
+// fakeHelm installs a fake helm binary which records its args and prints the manifests
+func fakeHelm(t *testing.T) (dir, argsFile string) {
+	dir = t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat <<'EOF'\n" + manifests + "EOF\n"
+	binary := filepath.Join(dir, "helm")
+	assert.Nil(t, os.WriteFile(binary, []byte(script), 0o755))
+	t.Setenv(BinaryEnv, binary)
+	return dir, argsFile
+}
+
+func TestRender(t *testing.T) {
+	dir, argsFile := fakeHelm(t)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "charts", "foo"), 0o755))
+
+	sp := &models.Spec{Resources: models.Resources{
+		{
+			ID:   "foo",
+			Type: Type,
+			Attributes: map[string]interface{}{
+				"chart":       "charts/foo",
+				"releaseName": "bar",
+				"namespace":   "default",
+				"values":      map[string]interface{}{"replicas": 2},
+			},
+			DependsOn:  []string{"v1:Namespace:default"},
+			Extensions: map[string]interface{}{engine.ClusterExtension: "kind"},
+		},
+		{ID: "v1:Namespace:default", Type: runtime.Kubernetes},
+		{ID: "app", Type: runtime.Kubernetes, DependsOn: []string{"foo"}},
+	}}
+	// only scopes of kinds which are neither built-in nor defined by CRDs in the chart are resolved in the cluster
+	namespaced := func(cluster string, gvk schema.GroupVersionKind) (bool, error) {
+		assert.Equal(t, "kind", cluster)
+		if gvk.Kind != "Gadget" {
+			return false, fmt.Errorf("unexpected kind %s", gvk.Kind)
+		}
+		return true, nil
+	}
+	assert.Nil(t, Render(sp, dir, namespaced))
+
+	args, err := os.ReadFile(argsFile)
+	assert.Nil(t, err)
+	assert.Contains(t, string(args), "template bar "+filepath.Join(dir, "charts", "foo")+" --include-crds --no-hooks --skip-tests --namespace default --values")
+
+	assert.Len(t, sp.Resources, 7)
+	cm, role, crd, widget, gadget := sp.Resources[0], sp.Resources[1], sp.Resources[2], sp.Resources[3], sp.Resources[4]
+	assert.Equal(t, "v1:ConfigMap:default:foo", cm.ID)
+	assert.Equal(t, runtime.Kubernetes, cm.Type)
+	assert.Equal(t, []string{"v1:Namespace:default"}, cm.DependsOn)
+	assert.Equal(t, map[string]interface{}{
+		ChartExtension:          "charts/foo",
+		ReleaseExtension:        "bar",
+		engine.ClusterExtension: "kind",
+	}, cm.Extensions)
+	// cluster-scoped objects have no namespace
+	assert.Equal(t, "rbac.authorization.k8s.io/v1:ClusterRole:foo", role.ID)
+	assert.Equal(t, "apiextensions.k8s.io/v1:CustomResourceDefinition:widgets.example.com", crd.ID)
+	assert.Equal(t, "example.com/v1:Widget:default:foo", widget.ID)
+	assert.Equal(t, "example.com/v1:Gadget:default:foo", gadget.ID)
+	assert.Equal(t, []string{cm.ID, role.ID, crd.ID, widget.ID, gadget.ID}, sp.Resources[6].DependsOn)
+
+	t.Run("unknown scope", func(t *testing.T) {
+		sp := &models.Spec{Resources: models.Resources{
+			{ID: "foo", Type: Type, Attributes: map[string]interface{}{"chart": "charts/foo", "namespace": "default"}},
+		}}
+		assert.ErrorContains(t, Render(sp, dir, nil), "scope of kind Gadget.example.com is unknown")
+		err := Render(sp, dir, func(string, schema.GroupVersionKind) (bool, error) { return false, errors.New("no cluster") })
+		assert.ErrorContains(t, err, "resolve scope of kind Gadget.example.com failed: no cluster")
+	})
+
+	t.Run("no namespace", func(t *testing.T) {
+		sp := &models.Spec{Resources: models.Resources{
+			{ID: "foo", Type: Type, Attributes: map[string]interface{}{"chart": "charts/foo"}},
+		}}
+		assert.Nil(t, Render(sp, dir, nil))
+		assert.Equal(t, "v1:ConfigMap:foo", sp.Resources[0].ID)
+	})
+}
+
+func TestChart_Locate(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache")
+	assert.Nil(t, os.MkdirAll(cache, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(cache, "nginx-1.0.0.tgz"), nil, 0o644))
+
+	got, err := (&Chart{Chart: "bitnami/nginx", Version: "1.0.0", RepositoryCache: "cache"}).locate(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(cache, "nginx-1.0.0.tgz"), got)
+
+	_, err = (&Chart{Chart: "bitnami/nginx", Version: "2.0.0", RepositoryCache: "cache"}).locate(dir)
+	assert.NotNil(t, err)
+
+	_, err = (&Chart{Chart: "bitnami/nginx"}).locate(dir)
+	assert.NotNil(t, err)
+}
+
+func TestRender_Failed(t *testing.T) {
+	t.Setenv(BinaryEnv, "false")
+	dir := t.TempDir()
+	sp := &models.Spec{Resources: models.Resources{
+		{ID: "foo", Type: Type, Attributes: map[string]interface{}{"chart": "."}},
+	}}
+	assert.NotNil(t, Render(sp, dir, nil))
+
+	sp.Resources[0].Attributes = map[string]interface{}{}
+	assert.NotNil(t, Render(sp, dir, nil))
+}
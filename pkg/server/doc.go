@@ -0,0 +1,3 @@
+// Package server contains code of the Kusion server which exposes operations like Compile, Preview, Apply and Destroy
+// as a versioned HTTP/JSON API, so that other systems can integrate Kusion without scraping the terminal output.
+package server
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/notification"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/version"
+)
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if _, err := s.findProjects(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// handleAPI authenticates and routes all versioned API requests. Supported routes are:
+//
+//	GET  /api/v1/version
+//	GET  /api/v1/projects
+//	GET  /api/v1/projects/{project}/stacks
+//	POST /api/v1/projects/{project}/stacks/{stack}/compile
+//	POST /api/v1/projects/{project}/stacks/{stack}/preview
+//	POST /api/v1/projects/{project}/stacks/{stack}/apply
+//	POST /api/v1/projects/{project}/stacks/{stack}/destroy
+//	GET  /api/v1/projects/{project}/stacks/{stack}/state
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if err := s.authenticate(r); err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	// segments[0:2] is ["api", "v1"]
+	segments := splitPath(r.URL.Path)[2:]
+
+	switch {
+	case len(segments) == 1 && segments[0] == "version":
+		s.route(w, r, http.MethodGet, s.handleVersion)
+	case len(segments) == 1 && segments[0] == "projects":
+		s.route(w, r, http.MethodGet, s.handleListProjects)
+	case len(segments) == 3 && segments[0] == "projects" && segments[2] == "stacks":
+		s.route(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			s.handleListStacks(w, r, segments[1])
+		})
+	case len(segments) == 5 && segments[0] == "projects" && segments[2] == "stacks":
+		project, stack, err := s.findProjectAndStack(segments[1], segments[3])
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		switch segments[4] {
+		case "compile":
+			s.route(w, r, http.MethodPost, s.withStack(project, stack, s.handleCompile))
+		case "preview":
+			s.route(w, r, http.MethodPost, s.withStack(project, stack, s.handlePreview))
+		case "apply":
+			s.route(w, r, http.MethodPost, s.withStack(project, stack, s.handleApply))
+		case "destroy":
+			s.route(w, r, http.MethodPost, s.withStack(project, stack, s.handleDestroy))
+		case "state":
+			s.route(w, r, http.MethodGet, s.withStack(project, stack, s.handleState))
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type stackHandlerFunc func(http.ResponseWriter, *http.Request, *OperationRequest, *projectstack.Project, *projectstack.Stack)
+
+// withStack decodes the OperationRequest and waits for a free operation slot before calling h
+func (s *Server) withStack(project *projectstack.Project, stack *projectstack.Stack, h stackHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &OperationRequest{}
+		if r.Body != nil && r.Method != http.MethodGet {
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if r.Method == http.MethodGet {
+			req.Arguments = r.URL.Query()["argument"]
+		}
+
+		if err := s.acquire(r.Context()); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		defer s.release()
+
+		h(w, r, req, project, stack)
+	}
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, method string, h http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	h(w, r)
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(version.JSON()))
+}
+
+func (s *Server) handleListProjects(w http.ResponseWriter, _ *http.Request) {
+	projects, err := s.findProjects()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) handleListStacks(w http.ResponseWriter, _ *http.Request, projectName string) {
+	project, _, err := s.findProjectAndStack(projectName, "")
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, project.Stacks)
+}
+
+func (s *Server) handleCompile(
+	w http.ResponseWriter, r *http.Request, req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack,
+) {
+	sp, err := compile(r.Context(), req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &CompileResponse{Spec: sp})
+}
+
+func (s *Server) handlePreview(
+	w http.ResponseWriter, r *http.Request, req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack,
+) {
+	sp, err := compile(r.Context(), req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	storage, err := stateStorage(req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	changes, err := preview(r.Context(), req, opsmodels.ApplyPreview, storage, sp, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &PreviewResponse{Changes: changes})
+}
+
+func (s *Server) handleApply(
+	w http.ResponseWriter, r *http.Request, req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack,
+) {
+	// notifications are flushed after the stack is unlocked, so that slow webhooks don't block next operations
+	notifier := notification.NewNotifier(project.Notification)
+	defer notifier.Flush(notification.DefaultFlushTimeout)
+	unlock, err := s.tryLockStack(stack)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	defer unlock()
+
+	sp, err := compile(r.Context(), req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	storage, err := stateStorage(req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	changes, err := preview(r.Context(), req, opsmodels.ApplyPreview, storage, sp, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	stream := newEventStream(w)
+	stream.send(&Event{Type: ChangesEvent, Changes: changes})
+
+	ctx, done := s.startOperation()
+	defer done()
+	notify(notifier, notification.ApplyStarted, req, changes, nil)
+	state, err := apply(ctx, req, storage, sp, changes, func(p *Progress) {
+		stream.send(&Event{Type: ProgressEvent, Progress: p})
+	})
+	if err != nil {
+		notify(notifier, notification.ApplyFailed, req, changes, err)
+		stream.send(&Event{Type: ErrorEvent, Error: err.Error()})
+		return
+	}
+	notify(notifier, notification.ApplySucceeded, req, changes, nil)
+	stream.send(&Event{Type: ResultEvent, State: state})
+}
+
+// handleDestroy deletes resources recorded in the latest state, the same as `kusion destroy`. The stack is not
+// compiled, so that resources removed from the configuration are deleted too, and a stack whose configuration
+// can't be compiled anymore can still be destroyed
+func (s *Server) handleDestroy(
+	w http.ResponseWriter, r *http.Request, req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack,
+) {
+	// notifications are flushed after the stack is unlocked, the same as apply
+	notifier := notification.NewNotifier(project.Notification)
+	defer notifier.Flush(notification.DefaultFlushTimeout)
+	unlock, err := s.tryLockStack(stack)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	defer unlock()
+
+	state, err := latestState(req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if state == nil || len(state.Resources) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("no managed resources to destroy"))
+		return
+	}
+	storage, err := stateStorage(req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sp := &models.Spec{Resources: state.Resources}
+	changes, err := preview(r.Context(), req, opsmodels.DestroyPreview, storage, sp, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	stream := newEventStream(w)
+	stream.send(&Event{Type: ChangesEvent, Changes: changes})
+
+	ctx, done := s.startOperation()
+	defer done()
+	notify(notifier, notification.DestroyStarted, req, changes, nil)
+	err = destroy(ctx, req, storage, sp, changes, func(p *Progress) {
+		stream.send(&Event{Type: ProgressEvent, Progress: p})
+	})
+	if err != nil {
+		notify(notifier, notification.DestroyFailed, req, changes, err)
+		stream.send(&Event{Type: ErrorEvent, Error: err.Error()})
+		return
+	}
+	notify(notifier, notification.DestroySucceeded, req, changes, nil)
+	stream.send(&Event{Type: ResultEvent})
+}
+
+func (s *Server) handleState(
+	w http.ResponseWriter, r *http.Request, req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack,
+) {
+	state, err := latestState(req, project, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if state == nil {
+		writeError(w, http.StatusNotFound, errors.New("state not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, &StateResponse{State: state})
+}
+
+// eventStream writes events as newline-delimited JSON and flushes each of them to the client immediately
+type eventStream struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &eventStream{encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+func (e *eventStream) send(event *Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.encoder.Encode(event); err != nil {
+		log.Errorf("send event failed: %v", err)
+		return
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("write response failed: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &ErrorResponse{Error: err.Error()})
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrProjectNotFound), errors.Is(err, ErrStackNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrStackLocked):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+
+	"kusionstack.io/kusion/pkg/api"
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/backend"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/notification"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/generator"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// compile generates the Spec of the stack
+func compile(ctx context.Context, req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack) (*models.Spec, error) {
+	return api.Compile(ctx, project, stack, &generator.Options{
+		WorkDir:   stack.GetPath(),
+		Settings:  req.Settings,
+		Arguments: req.Arguments,
+		Overrides: req.Overrides,
+		NoStyle:   true,
+		NoPrompt:  true,
+	})
+}
+
+// stateStorage returns the StateStorage configured by project.yaml and overridden by the request
+func stateStorage(req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack) (states.StateStorage, error) {
+	return backend.BackendFromConfig(project.Backend, backend.BackendOps{
+		Type:   req.BackendType,
+		Config: req.BackendConfig,
+	}, stack.GetPath())
+}
+
+// latestState returns the latest State of the stack, or nil if not exists
+func latestState(req *OperationRequest, project *projectstack.Project, stack *projectstack.Stack) (*states.State, error) {
+	storage, err := stateStorage(req, project, stack)
+	if err != nil {
+		return nil, err
+	}
+	return storage.GetLatestState(&states.StateQuery{
+		Tenant:  project.Tenant,
+		Project: project.Name,
+		Stack:   stack.Name,
+		Cluster: util.ParseClusterArgument(req.Arguments),
+	})
+}
+
+// preview computes changes of sp with the given operation type
+func preview(
+	ctx context.Context,
+	req *OperationRequest,
+	opType opsmodels.OperationType,
+	storage states.StateStorage,
+	sp *models.Spec,
+	project *projectstack.Project,
+	stack *projectstack.Stack,
+) (*opsmodels.Changes, error) {
+	return api.Preview(ctx, project, stack, sp, &api.PreviewOptions{
+		Dependencies:   api.Dependencies{StateStorage: storage},
+		OperationType:  opType,
+		Operator:       req.Operator,
+		Cluster:        util.ParseClusterArgument(req.Arguments),
+		IgnoreFields:   req.IgnoreFields,
+		ForceConflicts: req.ForceConflicts,
+	})
+}
+
+// apply applies sp and reports the progress of each resource by the progress func
+func apply(
+	ctx context.Context,
+	req *OperationRequest,
+	storage states.StateStorage,
+	sp *models.Spec,
+	changes *opsmodels.Changes,
+	progress func(*Progress),
+) (*states.State, error) {
+	return api.Apply(ctx, sp, changes, &api.ApplyOptions{
+		Dependencies:   api.Dependencies{StateStorage: storage},
+		Operator:       req.Operator,
+		Cluster:        util.ParseClusterArgument(req.Arguments),
+		ForceConflicts: req.ForceConflicts,
+		Progress:       progressFunc(changes, progress),
+	})
+}
+
+// destroy deletes all resources in sp and reports the progress of each resource by the progress func
+func destroy(
+	ctx context.Context,
+	req *OperationRequest,
+	storage states.StateStorage,
+	sp *models.Spec,
+	changes *opsmodels.Changes,
+	progress func(*Progress),
+) error {
+	return api.Destroy(ctx, sp, changes, &api.DestroyOptions{
+		Dependencies: api.Dependencies{StateStorage: storage},
+		Operator:     req.Operator,
+		Progress:     progressFunc(changes, progress),
+	})
+}
+
+// progressFunc converts engine messages to Progress
+func progressFunc(changes *opsmodels.Changes, progress func(*Progress)) api.ProgressFunc {
+	return func(msg opsmodels.Message) {
+		p := &Progress{ResourceID: msg.ResourceID, Result: msg.OpResult}
+		if step := changes.Get(msg.ResourceID); step != nil {
+			p.Action = step.Action.String()
+		}
+		if msg.OpErr != nil {
+			p.Error = msg.OpErr.Error()
+		}
+		progress(p)
+	}
+}
+
+// notify sends the notification event of this operation in the background and never returns errors
+func notify(
+	notifier *notification.Notifier, t notification.EventType, req *OperationRequest, changes *opsmodels.Changes, err error,
+) {
+	notifier.Send(util.NewNotificationEvent(t, changes, req.Operator, err))
+}
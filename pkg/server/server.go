@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+const (
+	DefaultAddress        = "127.0.0.1:8080"
+	DefaultMaxConcurrency = 4
+	shutdownTimeout       = 30 * time.Second
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrStackNotFound   = errors.New("stack not found")
+	ErrStackLocked     = errors.New("another operation is running on this stack")
+	ErrUnauthorized    = errors.New("unauthorized")
+)
+
+// Options are configs of the Kusion server
+type Options struct {
+	// Address is the TCP address to listen on, such as "127.0.0.1:8080"
+	Address string
+
+	// Token is the bearer token that clients must send in the Authorization header
+	Token string
+
+	// TLSCertFile and TLSKeyFile are the certificate and the key to serve HTTPS with
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ClientCAFile is the CA bundle to verify client certificates with. If it is set, clients must present a
+	// certificate signed by one of these CAs (mTLS)
+	ClientCAFile string
+
+	// WorkDir is the root directory where all projects are found
+	WorkDir string
+
+	// MaxConcurrency is the max number of operations running at the same time
+	MaxConcurrency int
+
+	// OperationTimeout is the max duration of an apply or destroy, which is not interrupted by disconnected clients.
+	// Zero means no limit
+	OperationTimeout time.Duration
+}
+
+// Server serves the Kusion HTTP API. All API requests must be authenticated by Options.Token or a verified client
+// certificate, operations that modify the infrastructure are protected by per-stack locks, and the number of
+// operations running concurrently is limited by Options.MaxConcurrency.
+type Server struct {
+	opts *Options
+
+	// sem limits the number of running operations
+	sem chan struct{}
+
+	// locks contains all per-stack locks. The key is the absolute path of the stack
+	locks   map[string]*sync.Mutex
+	locksMu sync.Mutex
+
+	// ctx is the parent context of apply and destroy operations instead of contexts of requests, so that operations
+	// are not interrupted when clients disconnect. It is canceled if operations are not finished when shutting down
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// operations are apply and destroy operations running, which are waited for before the server stops
+	operations sync.WaitGroup
+}
+
+// NewServer creates a new Server with the given options
+func NewServer(opts *Options) *Server {
+	if opts.Address == "" {
+		opts.Address = DefaultAddress
+	}
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = DefaultMaxConcurrency
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		opts:   opts,
+		sem:    make(chan struct{}, opts.MaxConcurrency),
+		locks:  map[string]*sync.Mutex{},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Handler returns the http.Handler of all API routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/api/"+APIVersion+"/", s.handleAPI)
+	return mux
+}
+
+// Run serves the API until ctx is done, and then shuts down the server gracefully
+func (s *Server) Run(ctx context.Context) error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              s.opts.Address,
+		Handler:           s.Handler(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Infof("kusion server is listening on %s", s.opts.Address)
+		if tlsConfig != nil {
+			errCh <- srv.ListenAndServeTLS(s.opts.TLSCertFile, s.opts.TLSKeyFile)
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Warnf("operations are not finished in %s, cancel them", shutdownTimeout)
+		}
+		// operations are canceled only if they are not finished in the shutdown timeout, and the server waits for
+		// them to stop, so that states of canceled operations are still saved
+		s.cancel()
+		s.operations.Wait()
+		return err
+	}
+}
+
+// startOperation returns the context to run an apply or destroy on, which is detached from the request and bounded
+// by the shutdown of the server and Options.OperationTimeout. The returned func must be called when it ends
+func (s *Server) startOperation() (context.Context, func()) {
+	s.operations.Add(1)
+	ctx, cancel := s.ctx, context.CancelFunc(func() {})
+	if s.opts.OperationTimeout > 0 {
+		ctx, cancel = context.WithTimeout(s.ctx, s.opts.OperationTimeout)
+	}
+	return ctx, func() {
+		cancel()
+		s.operations.Done()
+	}
+}
+
+// tlsConfig returns the TLS config of the server, or nil if the server serves plain HTTP
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if s.opts.TLSCertFile == "" && s.opts.TLSKeyFile == "" {
+		if s.opts.ClientCAFile != "" {
+			return nil, errors.New("client certificates can only be verified when serving HTTPS")
+		}
+		return nil, nil
+	}
+	if s.opts.TLSCertFile == "" || s.opts.TLSKeyFile == "" {
+		return nil, errors.New("both the TLS certificate and the TLS key are required")
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(s.opts.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA file failed: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", s.opts.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// authenticate checks the bearer token or the verified client certificate of the request. Requests are always
+// rejected if neither the token nor the client CA is configured
+func (s *Server) authenticate(r *http.Request) error {
+	if s.opts.ClientCAFile != "" && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return nil
+	}
+	auth := r.Header.Get("Authorization")
+	if s.opts.Token != "" && strings.HasPrefix(auth, "Bearer ") {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1 {
+			return nil
+		}
+	}
+	return ErrUnauthorized
+}
+
+// findProjects finds all projects under the work directory
+func (s *Server) findProjects() ([]*projectstack.Project, error) {
+	return projectstack.FindAllProjectsFrom(s.opts.WorkDir)
+}
+
+// findProjectAndStack finds the project and the stack by their names
+func (s *Server) findProjectAndStack(projectName, stackName string) (*projectstack.Project, *projectstack.Stack, error) {
+	projects, err := s.findProjects()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range projects {
+		if p.Name != projectName {
+			continue
+		}
+		if stackName == "" {
+			return p, nil, nil
+		}
+		for _, st := range p.Stacks {
+			if st.Name == stackName {
+				return p, st, nil
+			}
+		}
+		return p, nil, ErrStackNotFound
+	}
+	return nil, nil, ErrProjectNotFound
+}
+
+// acquire waits for a free operation slot until ctx is done
+func (s *Server) acquire(ctx context.Context) error {
+	select {
+	case s.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) release() {
+	<-s.sem
+}
+
+// tryLockStack locks the stack and returns the unlock func, or ErrStackLocked if the stack is already locked
+func (s *Server) tryLockStack(stack *projectstack.Stack) (func(), error) {
+	key := filepath.Clean(stack.GetPath())
+
+	s.locksMu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[key] = l
+	}
+	s.locksMu.Unlock()
+
+	if !l.TryLock() {
+		return nil, ErrStackLocked
+	}
+	return l.Unlock, nil
+}
+
+// splitPath splits the url path into non-empty segments
+func splitPath(path string) []string {
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/notification"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func newTestServer(t *testing.T) (*Server, string) {
+	dir := t.TempDir()
+	stackDir := filepath.Join(dir, "p", "dev")
+	assert.Nil(t, os.MkdirAll(stackDir, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "p", projectstack.ProjectFile), []byte("name: p\n"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(stackDir, projectstack.StackFile), []byte("name: dev\n"), 0o644))
+	return NewServer(&Options{WorkDir: dir, Token: testToken}), stackDir
+}
+
+const testToken = "test-token"
+
+func do(s *Server, method, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func TestServer_Authenticate(t *testing.T) {
+	s, _ := newTestServer(t)
+	request := func(auth string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("Bearer "+testToken))
+	assert.Equal(t, http.StatusUnauthorized, request(""))
+	assert.Equal(t, http.StatusUnauthorized, request(testToken))
+	assert.Equal(t, http.StatusUnauthorized, request("Bearer wrong"))
+
+	// health checks are not authenticated
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// requests are rejected if no authentication is configured
+	s.opts.Token = ""
+	assert.Equal(t, http.StatusUnauthorized, request("Bearer "))
+	assert.Equal(t, http.StatusUnauthorized, do(s, http.MethodPost, "/api/v1/projects/p/stacks/dev/apply").Code)
+
+	// verified client certificates are accepted if the client CA is configured
+	s.opts.ClientCAFile = "ca.crt"
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil)
+	assert.ErrorIs(t, s.authenticate(r), ErrUnauthorized)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	assert.Nil(t, s.authenticate(r))
+}
+
+func TestServer_TLSConfig(t *testing.T) {
+	s := NewServer(&Options{})
+	config, err := s.tlsConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, config)
+	assert.Equal(t, DefaultAddress, s.opts.Address)
+
+	s = NewServer(&Options{ClientCAFile: "ca.crt"})
+	_, err = s.tlsConfig()
+	assert.NotNil(t, err)
+
+	s = NewServer(&Options{TLSCertFile: "server.crt"})
+	_, err = s.tlsConfig()
+	assert.NotNil(t, err)
+}
+
+func TestServer_Health(t *testing.T) {
+	s, _ := newTestServer(t)
+	assert.Equal(t, http.StatusOK, do(s, http.MethodGet, "/healthz").Code)
+	assert.Equal(t, http.StatusOK, do(s, http.MethodGet, "/readyz").Code)
+}
+
+func TestServer_ListProjectsAndStacks(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := do(s, http.MethodGet, "/api/v1/projects")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var projects []*projectstack.Project
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &projects))
+	assert.Len(t, projects, 1)
+	assert.Equal(t, "p", projects[0].Name)
+
+	w = do(s, http.MethodGet, "/api/v1/projects/p/stacks")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stacks []*projectstack.Stack
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &stacks))
+	assert.Len(t, stacks, 1)
+	assert.Equal(t, "dev", stacks[0].Name)
+
+	assert.Equal(t, http.StatusNotFound, do(s, http.MethodGet, "/api/v1/projects/none/stacks").Code)
+	assert.Equal(t, http.StatusNotFound, do(s, http.MethodGet, "/api/v1/projects/p/stacks/none/state").Code)
+	assert.Equal(t, http.StatusNotFound, do(s, http.MethodGet, "/api/v1/unknown").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(s, http.MethodPost, "/api/v1/projects").Code)
+}
+
+func TestServer_State(t *testing.T) {
+	s, stackDir := newTestServer(t)
+	assert.Equal(t, http.StatusNotFound, do(s, http.MethodGet, "/api/v1/projects/p/stacks/dev/state").Code)
+
+	state := states.NewState()
+	state.Project = "p"
+	state.Stack = "dev"
+	state.Resources = models.Resources{{ID: "a", Type: "Kubernetes"}}
+	storage := &local.FileSystemState{Path: filepath.Join(stackDir, local.KusionState)}
+	assert.Nil(t, storage.Apply(state))
+
+	w := do(s, http.MethodGet, "/api/v1/projects/p/stacks/dev/state")
+	assert.Equal(t, http.StatusOK, w.Code)
+	rsp := &StateResponse{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), rsp))
+	assert.Equal(t, "a", rsp.State.Resources[0].ID)
+}
+
+func TestServer_DestroyFromState(t *testing.T) {
+	defer monkey.UnpatchAll()
+	s, stackDir := newTestServer(t)
+
+	state := states.NewState()
+	state.Project = "p"
+	state.Stack = "dev"
+	state.Resources = models.Resources{{ID: "a", Type: "Kubernetes"}}
+	storage := &local.FileSystemState{Path: filepath.Join(stackDir, local.KusionState)}
+	assert.Nil(t, storage.Apply(state))
+
+	// like kusion destroy, resources in the state are destroyed without compiling the stack
+	monkey.Patch(compile, func(context.Context, *OperationRequest, *projectstack.Project, *projectstack.Stack) (*models.Spec, error) {
+		t.Fatal("the stack must not be compiled on destroy")
+		return nil, nil
+	})
+	var previewed, destroyed *models.Spec
+	monkey.Patch(preview, func(_ context.Context, _ *OperationRequest, opType opsmodels.OperationType, _ states.StateStorage,
+		sp *models.Spec, project *projectstack.Project, stack *projectstack.Stack,
+	) (*opsmodels.Changes, error) {
+		assert.Equal(t, opsmodels.DestroyPreview, opType)
+		previewed = sp
+		return opsmodels.NewChanges(project, stack, &opsmodels.ChangeOrder{
+			StepKeys:    []string{"a"},
+			ChangeSteps: map[string]*opsmodels.ChangeStep{"a": {ID: "a", Action: opsmodels.Delete, From: &sp.Resources[0]}},
+		}), nil
+	})
+	monkey.Patch(destroy, func(_ context.Context, _ *OperationRequest, _ states.StateStorage, sp *models.Spec,
+		_ *opsmodels.Changes, _ func(*Progress),
+	) error {
+		destroyed = sp
+		return nil
+	})
+
+	w := do(s, http.MethodPost, "/api/v1/projects/p/stacks/dev/destroy")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"type":"`+string(ResultEvent)+`"`)
+	assert.Equal(t, state.Resources, previewed.Resources)
+	assert.Equal(t, state.Resources, destroyed.Resources)
+}
+
+func TestServer_DestroyDetachedFromRequest(t *testing.T) {
+	defer monkey.UnpatchAll()
+	s, stackDir := newTestServer(t)
+
+	var mu sync.Mutex
+	var events []notification.EventType
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notification.Event
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e.Type)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	projectYAML := "name: p\nnotification:\n  webhooks:\n    - url: " + hook.URL + "\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(filepath.Dir(stackDir), projectstack.ProjectFile), []byte(projectYAML), 0o644))
+
+	state := states.NewState()
+	state.Project = "p"
+	state.Stack = "dev"
+	state.Resources = models.Resources{{ID: "a", Type: "Kubernetes"}}
+	storage := &local.FileSystemState{Path: filepath.Join(stackDir, local.KusionState)}
+	assert.Nil(t, storage.Apply(state))
+
+	monkey.Patch(preview, func(_ context.Context, _ *OperationRequest, _ opsmodels.OperationType, _ states.StateStorage,
+		sp *models.Spec, project *projectstack.Project, stack *projectstack.Stack,
+	) (*opsmodels.Changes, error) {
+		return opsmodels.NewChanges(project, stack, &opsmodels.ChangeOrder{}), nil
+	})
+	// the client disconnects while destroying, which must not cancel the operation
+	reqCtx, disconnect := context.WithCancel(context.Background())
+	var opErr error
+	monkey.Patch(destroy, func(ctx context.Context, _ *OperationRequest, _ states.StateStorage, _ *models.Spec,
+		_ *opsmodels.Changes, _ func(*Progress),
+	) error {
+		disconnect()
+		opErr = ctx.Err()
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/projects/p/stacks/dev/destroy", nil).WithContext(reqCtx)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, opErr)
+	assert.Equal(t, []notification.EventType{notification.DestroyStarted, notification.DestroySucceeded}, events)
+}
+
+func TestServer_Lock(t *testing.T) {
+	s, stackDir := newTestServer(t)
+	stack := &projectstack.Stack{Path: stackDir}
+
+	unlock, err := s.tryLockStack(stack)
+	assert.Nil(t, err)
+	_, err = s.tryLockStack(stack)
+	assert.ErrorIs(t, err, ErrStackLocked)
+	assert.Equal(t, http.StatusConflict, do(s, http.MethodPost, "/api/v1/projects/p/stacks/dev/destroy").Code)
+
+	unlock()
+	unlock, err = s.tryLockStack(stack)
+	assert.Nil(t, err)
+	unlock()
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+const (
+	// APIVersion is the version prefix of all API paths
+	APIVersion = "v1"
+
+	ndjsonContentType = "application/x-ndjson"
+	jsonContentType   = "application/json"
+)
+
+// OperationRequest is the request body of compile, preview, apply and destroy
+type OperationRequest struct {
+	// Settings are setting files of the stack
+	Settings []string `json:"settings,omitempty"`
+
+	// Arguments are generator args, such as ["cluster=default"]
+	Arguments []string `json:"arguments,omitempty"`
+
+	// Overrides contains all override args of the generator
+	Overrides []string `json:"overrides,omitempty"`
+
+	// Operator represents the person who triggered this operation
+	Operator string `json:"operator,omitempty"`
+
+	// IgnoreFields will be ignored in preview stage
+	IgnoreFields []string `json:"ignoreFields,omitempty"`
+
+	// ForceConflicts takes over fields managed by others when conflicts occur in server-side apply
+	ForceConflicts bool `json:"forceConflicts,omitempty"`
+
+	// BackendType overrides the backend type in project.yaml
+	BackendType string `json:"backendType,omitempty"`
+
+	// BackendConfig overrides the backend config in project.yaml, such as ["path=kusion_state.json"]
+	BackendConfig []string `json:"backendConfig,omitempty"`
+}
+
+// CompileResponse is the response of compile
+type CompileResponse struct {
+	Spec *models.Spec `json:"spec"`
+}
+
+// PreviewResponse is the response of preview
+type PreviewResponse struct {
+	Changes *opsmodels.Changes `json:"changes"`
+}
+
+// StateResponse is the response of state queries
+type StateResponse struct {
+	State *states.State `json:"state"`
+}
+
+// ErrorResponse is the response of all failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type EventType string
+
+// EventType values
+const (
+	ChangesEvent  EventType = "changes"
+	ProgressEvent EventType = "progress"
+	ResultEvent   EventType = "result"
+	ErrorEvent    EventType = "error"
+)
+
+// Event is one line of the streaming response of apply and destroy. The stream always starts with a changes event,
+// followed by progress events of each resource, and ends with a result or error event.
+type Event struct {
+	Type     EventType          `json:"type"`
+	Changes  *opsmodels.Changes `json:"changes,omitempty"`
+	Progress *Progress          `json:"progress,omitempty"`
+	State    *states.State      `json:"state,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// Progress is the progress of one resource during apply or destroy
+type Progress struct {
+	ResourceID string             `json:"resourceID"`
+	Action     string             `json:"action,omitempty"`
+	Result     opsmodels.OpResult `json:"result,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}